@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// CanChat 的拒绝原因
+var (
+	ErrUserMuted  = errors.New("user is muted")
+	ErrUserBanned = errors.New("user is banned")
+)
+
+type BanRequest struct {
+	Reason string `json:"reason"`
+}
+
+// RequireAdmin 仅允许管理员访问，需放在 AuthMiddleware 之后。
+// 角色以仓库中的当前值为准，撤销管理员或封禁后立即生效，不必等待令牌过期。
+func (a *AuthService) RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := a.userRepo.GetUserByID(r.Header.Get("X-User-ID"))
+		if err != nil || user.Role != RoleAdmin || user.Banned {
+			http.Error(w, "Admin access required", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// BanHandler 封禁（PUT）或解封（DELETE）用户，封禁时吊销其全部刷新令牌
+func (a *AuthService) BanHandler(w http.ResponseWriter, r *http.Request) {
+	banned := r.Method != http.MethodDelete
+
+	user, err := a.userRepo.GetUserByID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if banned && user.Role == RoleAdmin {
+		http.Error(w, "Cannot ban an admin", http.StatusForbidden)
+		return
+	}
+
+	var req BanRequest
+	if banned && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := a.userRepo.SetBanned(user.ID, banned, req.Reason); err != nil {
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+	if banned {
+		a.tokenRepo.RevokeUserRefreshTokens(user.ID)
+	}
+
+	slog.Info("user ban updated", "user", user.ID, "banned", banned, "admin", r.Header.Get("X-User-ID"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MuteHandler 禁言（PUT）或解除禁言（DELETE）用户，禁言后无法发送聊天消息
+func (a *AuthService) MuteHandler(w http.ResponseWriter, r *http.Request) {
+	muted := r.Method != http.MethodDelete
+
+	user, err := a.userRepo.GetUserByID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if muted && user.Role == RoleAdmin {
+		http.Error(w, "Cannot mute an admin", http.StatusForbidden)
+		return
+	}
+
+	if err := a.userRepo.SetMuted(user.ID, muted); err != nil {
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("user mute updated", "user", user.ID, "muted", muted, "admin", r.Header.Get("X-User-ID"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CanChat 检查用户能否发送聊天消息。以仓库中的当前状态为准，连接建立后的禁言与封禁立即生效
+func (a *AuthService) CanChat(userID string) error {
+	user, err := a.userRepo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.Banned {
+		return ErrUserBanned
+	}
+	if user.Muted {
+		return ErrUserMuted
+	}
+	return nil
+}
+
+// rejectBanned 拒绝被封禁或已删除的账户，用于 WebSocket 连接
+func (a *AuthService) rejectBanned(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := a.userRepo.GetUserByID(r.Header.Get("X-User-ID"))
+		if err != nil {
+			recordAuthFailure("invalid_token")
+			http.Error(w, "User not found", http.StatusUnauthorized)
+			return
+		}
+		if user.Banned {
+			recordAuthFailure("banned")
+			http.Error(w, "Account is banned", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// bootstrapAdmin 将 AuthConfig.AdminUsers 中的用户名提升为管理员
+func (a *AuthService) bootstrapAdmin(user *User) {
+	if user.IsGuest || user.Role == RoleAdmin || !slices.Contains(a.adminUsers, user.Username) {
+		return
+	}
+	if err := a.userRepo.SetRole(user.ID, RoleAdmin); err != nil {
+		slog.Error("failed to grant admin role", "error", err, "user", user.ID)
+		return
+	}
+	user.Role = RoleAdmin
+}
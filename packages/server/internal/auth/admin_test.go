@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAdmin_BootstrapAndRequireAdmin(t *testing.T) {
+	service, _ := newTestAuthService(time.Minute)
+	user := registerTestUser(t, service)
+
+	rec := postJSON(service.RegisterHandler, "/api/auth/register", "",
+		`{"username": "root", "email": "root@example.com", "password": "secret123"}`)
+	var admin AuthResponse
+	json.NewDecoder(rec.Body).Decode(&admin)
+	if admin.User == nil || admin.User.Role != RoleAdmin {
+		t.Fatalf("Expected configured admin to get admin role, got %+v", admin.User)
+	}
+
+	protected := service.AuthMiddleware(service.RequireAdmin(echoUser))
+	for token, want := range map[string]int{user.Token: http.StatusForbidden, admin.Token: http.StatusOK} {
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/games", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		protected(rec, req)
+		if rec.Code != want {
+			t.Errorf("Expected %d, got %d", want, rec.Code)
+		}
+	}
+}
+
+func TestAdmin_BanEnforcedAtLoginAndWS(t *testing.T) {
+	service, _ := newTestAuthService(time.Minute)
+	user := registerTestUser(t, service)
+
+	ban := func(method string) int {
+		req := httptest.NewRequest(method, "/api/admin/users/"+user.User.ID+"/ban", nil)
+		req.SetPathValue("id", user.User.ID)
+		rec := httptest.NewRecorder()
+		service.BanHandler(rec, req)
+		return rec.Code
+	}
+	wsConnect := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/api/game/ws", nil)
+		req.Header.Set("Authorization", "Bearer "+user.Token)
+		rec := httptest.NewRecorder()
+		service.WSAuthMiddleware(echoUser)(rec, req)
+		return rec.Code
+	}
+
+	if code := ban(http.MethodPut); code != http.StatusNoContent {
+		t.Fatalf("Expected 204 on ban, got %d", code)
+	}
+
+	rec := postJSON(service.LoginHandler, "/api/auth/login", "", `{"username": "alice", "password": "secret123"}`)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected banned login to be rejected, got %d", rec.Code)
+	}
+	if code := wsConnect(); code != http.StatusForbidden {
+		t.Errorf("Expected banned ws connect to be rejected, got %d", code)
+	}
+	rec = postJSON(service.RefreshHandler, "/api/auth/refresh", "", `{"refreshToken": "`+user.RefreshToken+`"}`)
+	if rec.Code == http.StatusOK {
+		t.Error("Expected refresh to fail after ban")
+	}
+
+	if code := ban(http.MethodDelete); code != http.StatusNoContent {
+		t.Fatalf("Expected 204 on unban, got %d", code)
+	}
+	rec = postJSON(service.LoginHandler, "/api/auth/login", "", `{"username": "alice", "password": "secret123"}`)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected login after unban, got %d", rec.Code)
+	}
+	if code := wsConnect(); code != http.StatusOK {
+		t.Errorf("Expected ws connect after unban, got %d", code)
+	}
+}
+
+func TestAdmin_MuteBlocksChat(t *testing.T) {
+	service, _ := newTestAuthService(time.Minute)
+	user := registerTestUser(t, service)
+
+	mute := func(method string) int {
+		req := httptest.NewRequest(method, "/api/admin/users/"+user.User.ID+"/mute", nil)
+		req.SetPathValue("id", user.User.ID)
+		rec := httptest.NewRecorder()
+		service.MuteHandler(rec, req)
+		return rec.Code
+	}
+
+	if err := service.CanChat(user.User.ID); err != nil {
+		t.Fatalf("Expected user to be able to chat, got %v", err)
+	}
+	if code := mute(http.MethodPut); code != http.StatusNoContent {
+		t.Fatalf("Expected 204 on mute, got %d", code)
+	}
+	if err := service.CanChat(user.User.ID); !errors.Is(err, ErrUserMuted) {
+		t.Errorf("Expected ErrUserMuted, got %v", err)
+	}
+	if code := mute(http.MethodDelete); code != http.StatusNoContent {
+		t.Fatalf("Expected 204 on unmute, got %d", code)
+	}
+	if err := service.CanChat(user.User.ID); err != nil {
+		t.Errorf("Expected chat after unmute, got %v", err)
+	}
+
+	service.userRepo.SetBanned(user.User.ID, true, "spam")
+	if err := service.CanChat(user.User.ID); !errors.Is(err, ErrUserBanned) {
+		t.Errorf("Expected ErrUserBanned, got %v", err)
+	}
+}
@@ -3,28 +3,47 @@ package auth
 import (
 	"crypto/rand"
 	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"server/internal/cache"
+	"server/internal/metrics"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/argon2"
 )
 
+// wsBearerProtocol 浏览器通过 new WebSocket(url, ["bearer", token]) 传递令牌时使用的子协议名
+const wsBearerProtocol = "bearer"
+
 type AuthService struct {
 	userRepo    UserRepository
 	tokenSvc    TokenService
 	passwordSvc PasswordService
+	tokenRepo   TokenRepository
+	limiter     *LoginLimiter
+	cache       *cache.CacheService
+	refreshTTL  time.Duration
+	wsTicketTTL time.Duration
+	adminUsers  []string
 }
 
 type User struct {
 	ID           string    `json:"id"`
 	Username     string    `json:"username"`
 	Email        string    `json:"email"`
+	DisplayName  string    `json:"displayName"`
 	PasswordHash []byte    `json:"-"`
 	Salt         []byte    `json:"-"`
+	IsGuest      bool      `json:"isGuest"`
+	Role         string    `json:"role"`
+	Banned       bool      `json:"banned"`
+	BanReason    string    `json:"banReason,omitempty"`
+	Muted        bool      `json:"muted"`
 	CreatedAt    time.Time `json:"createdAt"`
 	LastLoginAt  time.Time `json:"lastLoginAt"`
 }
@@ -41,14 +60,32 @@ type LoginRequest struct {
 }
 
 type AuthResponse struct {
-	Token     string `json:"token"`
-	User      *User  `json:"user"`
+	Token            string `json:"token"`
+	User             *User  `json:"user"`
+	ExpiresAt        int64  `json:"expiresAt"`
+	RefreshToken     string `json:"refreshToken"`
+	RefreshExpiresAt int64  `json:"refreshExpiresAt"`
+}
+
+// WSTicket 一次性 WebSocket 连接票据
+type WSTicket struct {
+	UserID   string `json:"userId"`
+	Username string `json:"username"`
+	Scope    string `json:"scope"`
+	Role     string `json:"role"`
+}
+
+type WSTicketResponse struct {
+	Ticket    string `json:"ticket"`
 	ExpiresAt int64  `json:"expiresAt"`
 }
 
 type Claims struct {
 	UserID   string `json:"userId"`
 	Username string `json:"username"`
+	// Scope 为空表示完整账户，ScopeGuest 表示游客令牌
+	Scope string `json:"scope,omitempty"`
+	Role  string `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -57,11 +94,21 @@ type UserRepository interface {
 	GetUserByUsername(username string) (*User, error)
 	UpdateLastLogin(userID string) error
 	UserExists(username string) bool
+	CreateGuestUser(username string) (*User, error)
+	UpgradeGuest(userID, username, email string, passwordHash, salt []byte) (*User, error)
+	GetUserByID(userID string) (*User, error)
+	UpdateProfile(userID, email, displayName string) (*User, error)
+	UpdatePassword(userID string, passwordHash, salt []byte) error
+	DeleteUser(userID string) error
+	SetRole(userID, role string) error
+	SetBanned(userID string, banned bool, reason string) error
+	SetMuted(userID string, muted bool) error
 }
 
 type TokenService interface {
 	GenerateToken(user *User) (string, int64, error)
 	ValidateToken(tokenString string) (*Claims, error)
+	RevokeToken(claims *Claims) error
 }
 
 type PasswordService interface {
@@ -69,11 +116,17 @@ type PasswordService interface {
 	VerifyPassword(password string, passwordHash, salt []byte) bool
 }
 
-func NewAuthService(userRepo UserRepository, tokenSvc TokenService, passwordSvc PasswordService) *AuthService {
+func NewAuthService(userRepo UserRepository, tokenSvc TokenService, passwordSvc PasswordService, tokenRepo TokenRepository, limiter *LoginLimiter, cacheSvc *cache.CacheService, refreshTTL, wsTicketTTL time.Duration, adminUsers []string) *AuthService {
 	return &AuthService{
 		userRepo:    userRepo,
 		tokenSvc:    tokenSvc,
 		passwordSvc: passwordSvc,
+		tokenRepo:   tokenRepo,
+		limiter:     limiter,
+		cache:       cacheSvc,
+		refreshTTL:  refreshTTL,
+		wsTicketTTL: wsTicketTTL,
+		adminUsers:  adminUsers,
 	}
 }
 
@@ -83,12 +136,20 @@ func (a *AuthService) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !a.allowRequest(w, "ip:"+a.limiter.ClientIP(r)) {
+		return
+	}
+
 	var req RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
+	if !a.allowRequest(w, "user:"+req.Username) {
+		return
+	}
+
 	if err := a.validateRegistration(req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -111,18 +172,12 @@ func (a *AuthService) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, expiresAt, err := a.tokenSvc.GenerateToken(user)
+	response, err := a.issueTokens(user)
 	if err != nil {
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
 
-	response := AuthResponse{
-		Token:     token,
-		User:      user,
-		ExpiresAt: expiresAt,
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
@@ -133,35 +188,86 @@ func (a *AuthService) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !a.allowRequest(w, "ip:"+a.limiter.ClientIP(r)) {
+		return
+	}
+
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
+	if !a.allowRequest(w, "user:"+req.Username) {
+		return
+	}
+	if wait := a.limiter.LockedFor(req.Username); wait > 0 {
+		tooManyRequests(w, wait)
+		return
+	}
+
 	user, err := a.userRepo.GetUserByUsername(req.Username)
 	if err != nil {
+		a.limiter.RecordFailure(req.Username)
+		recordAuthFailure("invalid_credentials")
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
 	if !a.passwordSvc.VerifyPassword(req.Password, user.PasswordHash, user.Salt) {
+		a.limiter.RecordFailure(req.Username)
+		recordAuthFailure("invalid_credentials")
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
+	a.limiter.RecordSuccess(req.Username)
+
+	if user.Banned {
+		recordAuthFailure("banned")
+		http.Error(w, "Account is banned", http.StatusForbidden)
+		return
+	}
+
 	a.userRepo.UpdateLastLogin(user.ID)
 
-	token, expiresAt, err := a.tokenSvc.GenerateToken(user)
+	response, err := a.issueTokens(user)
 	if err != nil {
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
 
-	response := AuthResponse{
-		Token:     token,
-		User:      user,
-		ExpiresAt: expiresAt,
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// WSTicketHandler 为已登录用户签发短期、一次性的 WebSocket 连接票据
+func (a *AuthService) WSTicketHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ticket, err := generateTicket()
+	if err != nil {
+		http.Error(w, "Failed to generate ticket", http.StatusInternalServerError)
+		return
+	}
+
+	data := WSTicket{
+		UserID:   r.Header.Get("X-User-ID"),
+		Username: r.Header.Get("X-Username"),
+		Scope:    r.Header.Get("X-User-Scope"),
+		Role:     r.Header.Get("X-User-Role"),
+	}
+	if err := a.cache.SetWSTicket(ticket, data, a.wsTicketTTL); err != nil {
+		http.Error(w, "Failed to store ticket", http.StatusInternalServerError)
+		return
+	}
+
+	response := WSTicketResponse{
+		Ticket:    ticket,
+		ExpiresAt: time.Now().Add(a.wsTicketTTL).Unix(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -172,33 +278,120 @@ func (a *AuthService) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
+			recordAuthFailure("missing_token")
 			http.Error(w, "Authorization header required", http.StatusUnauthorized)
 			return
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		if tokenString == authHeader {
+			recordAuthFailure("missing_token")
 			http.Error(w, "Bearer token required", http.StatusUnauthorized)
 			return
 		}
 
-		claims, err := a.tokenSvc.ValidateToken(tokenString)
-		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		a.serveWithToken(w, r, tokenString, next)
+	}
+}
+
+// WSAuthMiddleware 仅用于 WebSocket 路由。浏览器无法设置 Authorization 头，
+// 因此额外接受 ?ticket= 一次性票据，或 Sec-WebSocket-Protocol 中的 "bearer, <token>"。
+// 被封禁的账户无法建立连接。
+func (a *AuthService) WSAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	next = a.rejectBanned(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ticket := r.URL.Query().Get("ticket"); ticket != "" {
+			var data WSTicket
+			found, err := a.cache.ConsumeWSTicket(ticket, &data)
+			if err != nil || !found {
+				recordAuthFailure("invalid_ticket")
+				http.Error(w, "Invalid or expired ticket", http.StatusUnauthorized)
+				return
+			}
+
+			r.Header.Set("X-User-ID", data.UserID)
+			r.Header.Set("X-Username", data.Username)
+			r.Header.Set("X-User-Scope", data.Scope)
+			r.Header.Set("X-User-Role", data.Role)
+			next(w, r)
+			return
+		}
+
+		if tokenString := protocolToken(r); tokenString != "" {
+			a.serveWithToken(w, r, tokenString, next)
 			return
 		}
 
-		r.Header.Set("X-User-ID", claims.UserID)
-		r.Header.Set("X-Username", claims.Username)
+		a.AuthMiddleware(next)(w, r)
+	}
+}
 
-		next(w, r)
+func (a *AuthService) serveWithToken(w http.ResponseWriter, r *http.Request, tokenString string, next http.HandlerFunc) {
+	claims, err := a.tokenSvc.ValidateToken(tokenString)
+	if err != nil {
+		recordAuthFailure("invalid_token")
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
 	}
+
+	r.Header.Set("X-User-ID", claims.UserID)
+	r.Header.Set("X-Username", claims.Username)
+	r.Header.Set("X-User-Scope", claims.Scope)
+	r.Header.Set("X-User-Role", claims.Role)
+
+	next(w, r)
+}
+
+// requestClaims 解析 Authorization 头中的令牌，供需要 jti 等完整声明的处理器使用
+func (a *AuthService) requestClaims(w http.ResponseWriter, r *http.Request) (*Claims, bool) {
+	tokenString, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		recordAuthFailure("missing_token")
+		http.Error(w, "Bearer token required", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	claims, err := a.tokenSvc.ValidateToken(tokenString)
+	if err != nil {
+		recordAuthFailure("invalid_token")
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return nil, false
+	}
+	return claims, true
+}
+
+// protocolToken 从 Sec-WebSocket-Protocol 中取出紧跟在 "bearer" 之后的令牌
+func protocolToken(r *http.Request) string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			protocols = append(protocols, strings.TrimSpace(protocol))
+		}
+	}
+
+	for i := 0; i+1 < len(protocols); i++ {
+		if protocols[i] == wsBearerProtocol {
+			return protocols[i+1]
+		}
+	}
+	return ""
+}
+
+func (a *AuthService) allowRequest(w http.ResponseWriter, key string) bool {
+	allowed, wait := a.limiter.Allow(key)
+	if !allowed {
+		tooManyRequests(w, wait)
+	}
+	return allowed
 }
 
 func (a *AuthService) validateRegistration(req RegisterRequest) error {
 	if len(req.Username) < 3 || len(req.Username) > 20 {
 		return fmt.Errorf("username must be between 3 and 20 characters")
 	}
+	if strings.HasPrefix(req.Username, "guest_") {
+		return fmt.Errorf("username prefix guest_ is reserved")
+	}
 	if len(req.Password) < 6 {
 		return fmt.Errorf("password must be at least 6 characters")
 	}
@@ -209,6 +402,7 @@ func (a *AuthService) validateRegistration(req RegisterRequest) error {
 }
 
 type InMemoryUserRepository struct {
+	mutex sync.RWMutex
 	users map[string]*User
 }
 
@@ -219,12 +413,16 @@ func NewInMemoryUserRepository() *InMemoryUserRepository {
 }
 
 func (r *InMemoryUserRepository) CreateUser(username, email string, passwordHash, salt []byte) (*User, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
 	user := &User{
 		ID:           generateUserID(),
 		Username:     username,
 		Email:        email,
 		PasswordHash: passwordHash,
 		Salt:         salt,
+		Role:         RoleUser,
 		CreatedAt:    time.Now(),
 		LastLoginAt:  time.Now(),
 	}
@@ -233,6 +431,9 @@ func (r *InMemoryUserRepository) CreateUser(username, email string, passwordHash
 }
 
 func (r *InMemoryUserRepository) GetUserByUsername(username string) (*User, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
 	user, exists := r.users[username]
 	if !exists {
 		return nil, fmt.Errorf("user not found")
@@ -241,36 +442,194 @@ func (r *InMemoryUserRepository) GetUserByUsername(username string) (*User, erro
 }
 
 func (r *InMemoryUserRepository) UpdateLastLogin(userID string) error {
-	for _, user := range r.users {
-		if user.ID == userID {
-			user.LastLoginAt = time.Now()
-			return nil
-		}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	user := r.findByID(userID)
+	if user == nil {
+		return fmt.Errorf("user not found")
 	}
-	return fmt.Errorf("user not found")
+	user.LastLoginAt = time.Now()
+	return nil
 }
 
 func (r *InMemoryUserRepository) UserExists(username string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
 	_, exists := r.users[username]
 	return exists
 }
 
+func (r *InMemoryUserRepository) CreateGuestUser(username string) (*User, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.users[username]; exists {
+		return nil, fmt.Errorf("username already exists")
+	}
+
+	user := &User{
+		ID:          generateUserID(),
+		Username:    username,
+		IsGuest:     true,
+		Role:        RoleUser,
+		CreatedAt:   time.Now(),
+		LastLoginAt: time.Now(),
+	}
+	r.users[username] = user
+	return user, nil
+}
+
+func (r *InMemoryUserRepository) UpgradeGuest(userID, username, email string, passwordHash, salt []byte) (*User, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	guest := r.findByID(userID)
+	if guest == nil || !guest.IsGuest {
+		return nil, fmt.Errorf("guest not found")
+	}
+	if existing, exists := r.users[username]; exists && existing != guest {
+		return nil, fmt.Errorf("username already exists")
+	}
+
+	delete(r.users, guest.Username)
+	guest.Username = username
+	guest.Email = email
+	guest.PasswordHash = passwordHash
+	guest.Salt = salt
+	guest.IsGuest = false
+	r.users[username] = guest
+	return guest, nil
+}
+
+func (r *InMemoryUserRepository) GetUserByID(userID string) (*User, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	user := r.findByID(userID)
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	return user, nil
+}
+
+func (r *InMemoryUserRepository) UpdateProfile(userID, email, displayName string) (*User, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	user := r.findByID(userID)
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	user.Email = email
+	user.DisplayName = displayName
+	return user, nil
+}
+
+func (r *InMemoryUserRepository) UpdatePassword(userID string, passwordHash, salt []byte) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	user := r.findByID(userID)
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+	user.PasswordHash = passwordHash
+	user.Salt = salt
+	return nil
+}
+
+func (r *InMemoryUserRepository) DeleteUser(userID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	user := r.findByID(userID)
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+	delete(r.users, user.Username)
+	return nil
+}
+
+func (r *InMemoryUserRepository) SetRole(userID, role string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	user := r.findByID(userID)
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+	user.Role = role
+	return nil
+}
+
+func (r *InMemoryUserRepository) SetBanned(userID string, banned bool, reason string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	user := r.findByID(userID)
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+	user.Banned = banned
+	user.BanReason = ""
+	if banned {
+		user.BanReason = reason
+	}
+	return nil
+}
+
+func (r *InMemoryUserRepository) SetMuted(userID string, muted bool) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	user := r.findByID(userID)
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+	user.Muted = muted
+	return nil
+}
+
+// findByID 调用方需持有锁
+func (r *InMemoryUserRepository) findByID(userID string) *User {
+	for _, user := range r.users {
+		if user.ID == userID {
+			return user
+		}
+	}
+	return nil
+}
+
 type JWTTokenService struct {
 	jwtSecret []byte
+	expiry    time.Duration
+	tokenRepo TokenRepository
 }
 
-func NewJWTTokenService(jwtSecret string) *JWTTokenService {
+func NewJWTTokenService(jwtSecret string, expiry time.Duration, tokenRepo TokenRepository) *JWTTokenService {
 	return &JWTTokenService{
 		jwtSecret: []byte(jwtSecret),
+		expiry:    expiry,
+		tokenRepo: tokenRepo,
 	}
 }
 
 func (s *JWTTokenService) GenerateToken(user *User) (string, int64, error) {
-	expiresAt := time.Now().Add(24 * time.Hour)
+	jti, err := generateTicket()
+	if err != nil {
+		return "", 0, err
+	}
+
+	expiresAt := time.Now().Add(s.expiry)
 	claims := &Claims{
 		UserID:   user.ID,
 		Username: user.Username,
+		Scope:    userScope(user),
+		Role:     user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
@@ -297,11 +656,25 @@ func (s *JWTTokenService) ValidateToken(tokenString string) (*Claims, error) {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
 	}
 
-	return nil, fmt.Errorf("invalid token")
+	if claims.ID == "" || s.tokenRepo.IsAccessTokenRevoked(claims.ID) {
+		return nil, fmt.Errorf("token has been revoked")
+	}
+
+	return claims, nil
+}
+
+// RevokeToken 将访问令牌加入吊销列表，直到其自然过期
+func (s *JWTTokenService) RevokeToken(claims *Claims) error {
+	expiresAt := time.Now().Add(s.expiry)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	return s.tokenRepo.RevokeAccessToken(claims.ID, expiresAt)
 }
 
 type Argon2PasswordService struct {
@@ -337,6 +710,19 @@ func (s *Argon2PasswordService) VerifyPassword(password string, passwordHash, sa
 	return subtle.ConstantTimeCompare(passwordHash, computedHash) == 1
 }
 
+func generateTicket() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
 func generateUserID() string {
 	return fmt.Sprintf("user_%d", time.Now().UnixNano())
 }
+
+// recordAuthFailure 记录被拒绝的认证请求，reason 为固定的标签值
+func recordAuthFailure(reason string) {
+	metrics.AuthFailures.WithLabelValues(reason).Inc()
+}
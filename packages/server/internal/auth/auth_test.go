@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"server/internal/cache"
+	"testing"
+	"time"
+)
+
+func newTestAuthService(ticketTTL time.Duration) (*AuthService, string) {
+	tokenRepo := NewInMemoryTokenRepository()
+	tokenSvc := NewJWTTokenService("test-secret", 15*time.Minute, tokenRepo)
+	userRepo := NewInMemoryUserRepository()
+	service := NewAuthService(
+		userRepo,
+		tokenSvc,
+		NewArgon2PasswordService(),
+		tokenRepo,
+		NewLoginLimiter(0, 0, nil),
+		cache.NewCacheService(cache.NewInMemoryCache(time.Minute), cache.DefaultTTLs()),
+		time.Hour,
+		ticketTTL,
+		[]string{"root"},
+	)
+
+	user := &User{ID: "user_1", Username: "tester", Role: RoleUser}
+	userRepo.users[user.Username] = user
+	token, _, _ := tokenSvc.GenerateToken(user)
+	return service, token
+}
+
+func echoUser(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(r.Header.Get("X-User-ID")))
+}
+
+func issueTicket(t *testing.T, service *AuthService, token string) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/ws-ticket", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	service.AuthMiddleware(service.WSTicketHandler)(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for ticket, got %d", rec.Code)
+	}
+
+	var response WSTicketResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode ticket response: %v", err)
+	}
+	return response.Ticket
+}
+
+func TestWSAuth_Ticket(t *testing.T) {
+	service, token := newTestAuthService(time.Minute)
+	ticket := issueTicket(t, service, token)
+	handler := service.WSAuthMiddleware(echoUser)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/game/ws?ticket="+ticket, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "user_1" {
+		t.Fatalf("Expected ticket to authenticate user_1, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/game/ws?ticket="+ticket, nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected reused ticket to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestWSAuth_TicketExpires(t *testing.T) {
+	service, token := newTestAuthService(10 * time.Millisecond)
+	ticket := issueTicket(t, service, token)
+	time.Sleep(20 * time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	service.WSAuthMiddleware(echoUser)(rec, httptest.NewRequest(http.MethodGet, "/api/game/ws?ticket="+ticket, nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected expired ticket to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestWSAuth_Subprotocol(t *testing.T) {
+	service, token := newTestAuthService(time.Minute)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/game/ws", nil)
+	req.Header.Set("Sec-WebSocket-Protocol", "bearer, "+token)
+	rec := httptest.NewRecorder()
+	service.WSAuthMiddleware(echoUser)(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "user_1" {
+		t.Fatalf("Expected subprotocol token to authenticate user_1, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/cache/stats", nil)
+	req.Header.Set("Sec-WebSocket-Protocol", "bearer, "+token)
+	rec = httptest.NewRecorder()
+	service.AuthMiddleware(echoUser)(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected subprotocol token to be rejected outside the ws route, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/game/ws", nil)
+	req.Header.Set("Sec-WebSocket-Protocol", "bearer, not-a-token")
+	rec = httptest.NewRecorder()
+	service.WSAuthMiddleware(echoUser)(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected invalid subprotocol token to be rejected, got %d", rec.Code)
+	}
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"time"
 )
@@ -25,6 +26,7 @@ func (r *DatabaseUserRepository) CreateUser(username, email string, passwordHash
 		Email:        email,
 		PasswordHash: passwordHash,
 		Salt:         salt,
+		Role:         RoleUser,
 		CreatedAt:    time.Now(),
 		LastLoginAt:  time.Now(),
 	}
@@ -56,3 +58,134 @@ func (r *DatabaseUserRepository) UserExists(username string) bool {
 
 	return false
 }
+
+func (r *DatabaseUserRepository) CreateGuestUser(username string) (*User, error) {
+	// query := "INSERT INTO users (id, username, email, is_guest, created_at, last_login_at) VALUES (?, ?, '', 1, ?, ?)"
+
+	user := &User{
+		ID:          generateUserID(),
+		Username:    username,
+		IsGuest:     true,
+		Role:        RoleUser,
+		CreatedAt:   time.Now(),
+		LastLoginAt: time.Now(),
+	}
+
+	return user, nil
+}
+
+func (r *DatabaseUserRepository) UpgradeGuest(userID, username, email string, passwordHash, salt []byte) (*User, error) {
+	// query := "UPDATE users SET username = ?, email = ?, password_hash = ?, salt = ?, is_guest = 0 WHERE id = ? AND is_guest = 1"
+	// 受影响行数为 0 时说明用户不存在或已是完整账户
+
+	return nil, fmt.Errorf("guest not found (database implementation)")
+}
+
+func (r *DatabaseUserRepository) GetUserByID(userID string) (*User, error) {
+	// query := "SELECT id, username, email, display_name, password_hash, salt, is_guest, role, banned, ban_reason, muted, created_at, last_login_at FROM users WHERE id = ?"
+	// row := r.db.QueryRow(query, userID)
+
+	return nil, fmt.Errorf("user not found (database implementation)")
+}
+
+func (r *DatabaseUserRepository) UpdateProfile(userID, email, displayName string) (*User, error) {
+	// query := "UPDATE users SET email = ?, display_name = ? WHERE id = ?"
+	// _, err := r.db.Exec(query, email, displayName, userID)
+	// return r.GetUserByID(userID)
+
+	return nil, fmt.Errorf("user not found (database implementation)")
+}
+
+func (r *DatabaseUserRepository) UpdatePassword(userID string, passwordHash, salt []byte) error {
+	// query := "UPDATE users SET password_hash = ?, salt = ? WHERE id = ?"
+	// _, err := r.db.Exec(query, passwordHash, salt, userID)
+
+	return nil
+}
+
+func (r *DatabaseUserRepository) DeleteUser(userID string) error {
+	// 刷新令牌随用户一并删除，对局记录中的 user_id 保留
+	// query := "DELETE FROM users WHERE id = ?"
+	// cleanup := "DELETE FROM refresh_tokens WHERE user_id = ?"
+
+	return nil
+}
+
+func (r *DatabaseUserRepository) SetRole(userID, role string) error {
+	// query := "UPDATE users SET role = ? WHERE id = ?"
+
+	return nil
+}
+
+func (r *DatabaseUserRepository) SetBanned(userID string, banned bool, reason string) error {
+	// query := "UPDATE users SET banned = ?, ban_reason = ? WHERE id = ?"
+	// _, err := r.db.Exec(query, banned, reason, userID)
+
+	return nil
+}
+
+func (r *DatabaseUserRepository) SetMuted(userID string, muted bool) error {
+	// query := "UPDATE users SET muted = ? WHERE id = ?"
+	// _, err := r.db.Exec(query, muted, userID)
+
+	return nil
+}
+
+// Tables:
+//   refresh_tokens (token_hash, user_id, username, expires_at, revoked, created_at)
+//   revoked_tokens (jti, expires_at)
+
+// errTokenDatabaseUnavailable 数据库令牌仓库尚未接入数据库，所有操作都返回错误而不是假装成功
+var errTokenDatabaseUnavailable = errors.New("database token repository is not implemented")
+
+// DatabaseTokenRepository 尚未接入数据库，需要持久化时使用 CacheTokenRepository 并配置 Redis 缓存
+type DatabaseTokenRepository struct {
+	// db *sql.DB
+}
+
+func NewDatabaseTokenRepository( /* db *sql.DB */ ) *DatabaseTokenRepository {
+	return &DatabaseTokenRepository{
+		// db: db,
+	}
+}
+
+func (r *DatabaseTokenRepository) SaveRefreshToken(token *RefreshToken) error {
+	// query := "INSERT INTO refresh_tokens (token_hash, user_id, username, expires_at, revoked, created_at) VALUES (?, ?, ?, ?, 0, ?)"
+	// _, err := r.db.Exec(query, token.TokenHash, token.UserID, token.Username, token.ExpiresAt, token.CreatedAt)
+
+	return errTokenDatabaseUnavailable
+}
+
+func (r *DatabaseTokenRepository) GetRefreshToken(tokenHash string) (*RefreshToken, error) {
+	// query := "SELECT token_hash, user_id, username, expires_at, revoked, created_at FROM refresh_tokens WHERE token_hash = ?"
+
+	return nil, errTokenDatabaseUnavailable
+}
+
+func (r *DatabaseTokenRepository) RevokeRefreshToken(tokenHash string) error {
+	// query := "UPDATE refresh_tokens SET revoked = 1 WHERE token_hash = ? AND revoked = 0"
+	// result, err := r.db.Exec(query, tokenHash)
+	// 受影响行数为 0 时说明令牌不存在或已被吊销
+
+	return errTokenDatabaseUnavailable
+}
+
+func (r *DatabaseTokenRepository) RevokeUserRefreshTokens(userID string) error {
+	// query := "UPDATE refresh_tokens SET revoked = 1 WHERE user_id = ?"
+
+	return errTokenDatabaseUnavailable
+}
+
+func (r *DatabaseTokenRepository) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	// query := "INSERT INTO revoked_tokens (jti, expires_at) VALUES (?, ?) ON CONFLICT DO NOTHING"
+	// cleanup := "DELETE FROM revoked_tokens WHERE expires_at < ?"
+
+	return errTokenDatabaseUnavailable
+}
+
+// IsAccessTokenRevoked 无法查询吊销记录时视为已吊销，拒绝令牌而不是静默放行
+func (r *DatabaseTokenRepository) IsAccessTokenRevoked(jti string) bool {
+	// query := "SELECT COUNT(*) FROM revoked_tokens WHERE jti = ? AND expires_at > ?"
+
+	return true
+}
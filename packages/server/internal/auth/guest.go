@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// ScopeGuest 游客令牌的权限范围，不能参与排位等需要完整账户的功能
+const ScopeGuest = "guest"
+
+const guestNameAttempts = 5
+
+func userScope(user *User) string {
+	if user.IsGuest {
+		return ScopeGuest
+	}
+	return ""
+}
+
+// GuestHandler 创建游客账户并直接登录，无需用户名、邮箱和密码
+func (a *AuthService) GuestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !a.allowRequest(w, "ip:"+a.limiter.ClientIP(r)) {
+		return
+	}
+
+	var user *User
+	for attempt := 0; attempt < guestNameAttempts && user == nil; attempt++ {
+		username, err := generateGuestName()
+		if err != nil {
+			http.Error(w, "Failed to create guest", http.StatusInternalServerError)
+			return
+		}
+		if a.userRepo.UserExists(username) {
+			continue
+		}
+		user, _ = a.userRepo.CreateGuestUser(username)
+	}
+	if user == nil {
+		http.Error(w, "Failed to create guest", http.StatusInternalServerError)
+		return
+	}
+
+	response, err := a.issueTokens(user)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpgradeHandler 将游客升级为完整账户，用户 ID 不变因此保留对局记录。
+// 升级后游客的访问令牌与刷新令牌全部作废，返回新的令牌。
+func (a *AuthService) UpgradeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := a.requestClaims(w, r)
+	if !ok {
+		return
+	}
+	if claims.Scope != ScopeGuest {
+		http.Error(w, "Only guest accounts can be upgraded", http.StatusBadRequest)
+		return
+	}
+
+	var req RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.validateRegistration(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Username != claims.Username && a.userRepo.UserExists(req.Username) {
+		http.Error(w, "Username already exists", http.StatusConflict)
+		return
+	}
+
+	passwordHash, salt, err := a.passwordSvc.HashPassword(req.Password)
+	if err != nil {
+		http.Error(w, "Failed to upgrade account", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := a.userRepo.UpgradeGuest(claims.UserID, req.Username, req.Email, passwordHash, salt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	a.tokenSvc.RevokeToken(claims)
+	a.tokenRepo.RevokeUserRefreshTokens(user.ID)
+
+	response, err := a.issueTokens(user)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RequireFullAccount 拒绝游客令牌，需放在 AuthMiddleware 之后
+func (a *AuthService) RequireFullAccount(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-User-Scope") == ScopeGuest {
+			http.Error(w, "A registered account is required", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func generateGuestName() (string, error) {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("guest_%s", hex.EncodeToString(buf)), nil
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGuest_CreateAndUpgrade(t *testing.T) {
+	service, _ := newTestAuthService(time.Minute)
+
+	rec := postJSON(service.GuestHandler, "/api/auth/guest", "", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for guest, got %d", rec.Code)
+	}
+	var guest AuthResponse
+	json.NewDecoder(rec.Body).Decode(&guest)
+	if !guest.User.IsGuest || !strings.HasPrefix(guest.User.Username, "guest_") {
+		t.Fatalf("Expected guest user, got %+v", guest.User)
+	}
+
+	claims, err := service.tokenSvc.ValidateToken(guest.Token)
+	if err != nil || claims.Scope != ScopeGuest {
+		t.Fatalf("Expected guest scope, got %+v (%v)", claims, err)
+	}
+
+	restricted := service.AuthMiddleware(service.RequireFullAccount(echoUser))
+	req := httptest.NewRequest(http.MethodPost, "/api/maps/library", nil)
+	req.Header.Set("Authorization", "Bearer "+guest.Token)
+	rec = httptest.NewRecorder()
+	restricted(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected guest to be forbidden, got %d", rec.Code)
+	}
+
+	rec = postJSON(service.UpgradeHandler, "/api/auth/upgrade", guest.Token,
+		`{"username": "bob", "email": "bob@example.com", "password": "secret123"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 on upgrade, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var upgraded AuthResponse
+	json.NewDecoder(rec.Body).Decode(&upgraded)
+	if upgraded.User.ID != guest.User.ID || upgraded.User.IsGuest || upgraded.User.Username != "bob" {
+		t.Errorf("Expected same user id as a full account, got %+v", upgraded.User)
+	}
+
+	if _, err := service.tokenSvc.ValidateToken(guest.Token); err == nil {
+		t.Error("Expected guest token to be revoked after upgrade")
+	}
+
+	req.Header.Set("Authorization", "Bearer "+upgraded.Token)
+	rec = httptest.NewRecorder()
+	restricted(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected upgraded account to pass, got %d", rec.Code)
+	}
+
+	rec = postJSON(service.LoginHandler, "/api/auth/login", "", `{"username": "bob", "password": "secret123"}`)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected login with upgraded credentials, got %d", rec.Code)
+	}
+
+	rec = postJSON(service.UpgradeHandler, "/api/auth/upgrade", upgraded.Token,
+		`{"username": "carol", "email": "carol@example.com", "password": "secret123"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected full account upgrade to be rejected, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const maxDisplayNameLength = 32
+
+// PublicProfile 公开资料，不包含邮箱等私人信息
+type PublicProfile struct {
+	ID          string    `json:"id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"displayName"`
+	IsGuest     bool      `json:"isGuest"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// UpdateProfileRequest 字段为 nil 时保持原值
+type UpdateProfileRequest struct {
+	Email       *string `json:"email"`
+	DisplayName *string `json:"displayName"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// MeHandler 查看（GET）或修改（PATCH）当前用户资料，需放在 AuthMiddleware 之后
+func (a *AuthService) MeHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := a.userRepo.GetUserByID(r.Header.Get("X-User-ID"))
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPatch:
+		var req UpdateProfileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		email, displayName := user.Email, user.DisplayName
+		if req.Email != nil {
+			if user.IsGuest {
+				http.Error(w, "A registered account is required", http.StatusForbidden)
+				return
+			}
+			email = strings.TrimSpace(*req.Email)
+			if !validEmail(email) {
+				http.Error(w, "a valid email is required", http.StatusBadRequest)
+				return
+			}
+		}
+		if req.DisplayName != nil {
+			displayName = strings.TrimSpace(*req.DisplayName)
+		}
+		if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+			http.Error(w, fmt.Sprintf("display name must be at most %d characters", maxDisplayNameLength), http.StatusBadRequest)
+			return
+		}
+
+		user, err = a.userRepo.UpdateProfile(user.ID, email, displayName)
+		if err != nil {
+			http.Error(w, "Failed to update profile", http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// ChangePasswordHandler 校验当前密码后修改密码。
+// 该用户的其他会话（刷新令牌与当前访问令牌）全部失效，并返回新的令牌。
+func (a *AuthService) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := a.requestClaims(w, r)
+	if !ok {
+		return
+	}
+	if !a.allowRequest(w, "user:"+claims.Username) {
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := a.userRepo.GetUserByID(claims.UserID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.IsGuest {
+		http.Error(w, "A registered account is required", http.StatusForbidden)
+		return
+	}
+	if !a.verifyCurrentPassword(w, user, req.CurrentPassword) {
+		return
+	}
+	if len(req.NewPassword) < 6 {
+		http.Error(w, "password must be at least 6 characters", http.StatusBadRequest)
+		return
+	}
+
+	passwordHash, salt, err := a.passwordSvc.HashPassword(req.NewPassword)
+	if err != nil {
+		http.Error(w, "Failed to change password", http.StatusInternalServerError)
+		return
+	}
+	if err := a.userRepo.UpdatePassword(user.ID, passwordHash, salt); err != nil {
+		http.Error(w, "Failed to change password", http.StatusInternalServerError)
+		return
+	}
+
+	a.tokenSvc.RevokeToken(claims)
+	a.tokenRepo.RevokeUserRefreshTokens(user.ID)
+
+	response, err := a.issueTokens(user)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteAccountHandler 删除当前账户，完整账户需要再次提供密码；游客可直接删除
+func (a *AuthService) DeleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := a.requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := a.userRepo.GetUserByID(claims.UserID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if !user.IsGuest {
+		if !a.allowRequest(w, "user:"+user.Username) {
+			return
+		}
+
+		var req DeleteAccountRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if !a.verifyCurrentPassword(w, user, req.Password) {
+			return
+		}
+	}
+
+	if err := a.userRepo.DeleteUser(user.ID); err != nil {
+		http.Error(w, "Failed to delete account", http.StatusInternalServerError)
+		return
+	}
+
+	a.tokenSvc.RevokeToken(claims)
+	a.tokenRepo.RevokeUserRefreshTokens(user.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verifyCurrentPassword 校验当前密码，与登录共用同一用户名的失败计数与锁定，
+// 持有访问令牌也不能绕过登录限流无限次猜测密码。校验失败时已写入响应
+func (a *AuthService) verifyCurrentPassword(w http.ResponseWriter, user *User, password string) bool {
+	if wait := a.limiter.LockedFor(user.Username); wait > 0 {
+		tooManyRequests(w, wait)
+		return false
+	}
+	if !a.passwordSvc.VerifyPassword(password, user.PasswordHash, user.Salt) {
+		a.limiter.RecordFailure(user.Username)
+		recordAuthFailure("invalid_credentials")
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return false
+	}
+	a.limiter.RecordSuccess(user.Username)
+	return true
+}
+
+// validEmail 要求整个输入是一个不带显示名的邮箱地址
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
+// PublicProfileHandler 按用户 ID 查看公开资料
+func (a *AuthService) PublicProfileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := a.userRepo.GetUserByID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	profile := PublicProfile{
+		ID:          user.ID,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		IsGuest:     user.IsGuest,
+		CreatedAt:   user.CreatedAt,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProfile_UpdateAndPublicLookup(t *testing.T) {
+	service, _ := newTestAuthService(time.Minute)
+	auth := registerTestUser(t, service)
+	me := service.AuthMiddleware(service.MeHandler)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/users/me", bytes.NewBufferString(`{"displayName": "Alice W."}`))
+	req.Header.Set("Authorization", "Bearer "+auth.Token)
+	rec := httptest.NewRecorder()
+	me(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 on patch, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var user User
+	json.NewDecoder(rec.Body).Decode(&user)
+	if user.DisplayName != "Alice W." || user.Email != "alice@example.com" {
+		t.Errorf("Expected display name updated and email unchanged, got %+v", user)
+	}
+
+	req = httptest.NewRequest(http.MethodPatch, "/api/users/me", bytes.NewBufferString(`{"displayName": "`+strings.Repeat("x", 33)+`"}`))
+	req.Header.Set("Authorization", "Bearer "+auth.Token)
+	rec = httptest.NewRecorder()
+	me(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for long display name, got %d", rec.Code)
+	}
+
+	for _, email := range []string{"@", "a@", "Alice <alice@example.com>"} {
+		body, _ := json.Marshal(map[string]string{"email": email})
+		req = httptest.NewRequest(http.MethodPatch, "/api/users/me", bytes.NewBuffer(body))
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+		rec = httptest.NewRecorder()
+		me(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for email %q, got %d", email, rec.Code)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/users/"+auth.User.ID, nil)
+	req.SetPathValue("id", auth.User.ID)
+	rec = httptest.NewRecorder()
+	service.PublicProfileHandler(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 on public profile, got %d", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "alice@example.com") {
+		t.Error("Expected public profile to omit email")
+	}
+	var profile PublicProfile
+	json.NewDecoder(rec.Body).Decode(&profile)
+	if profile.Username != "alice" || profile.DisplayName != "Alice W." {
+		t.Errorf("Unexpected public profile %+v", profile)
+	}
+}
+
+func TestProfile_ChangePassword(t *testing.T) {
+	service, _ := newTestAuthService(time.Minute)
+	auth := registerTestUser(t, service)
+
+	rec := postJSON(service.ChangePasswordHandler, "/api/users/me/password", auth.Token,
+		`{"currentPassword": "wrong", "newPassword": "newsecret"}`)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for wrong current password, got %d", rec.Code)
+	}
+
+	rec = postJSON(service.ChangePasswordHandler, "/api/users/me/password", auth.Token,
+		`{"currentPassword": "secret123", "newPassword": "newsecret"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 on password change, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if _, err := service.tokenSvc.ValidateToken(auth.Token); err == nil {
+		t.Error("Expected old access token to be revoked")
+	}
+	rec = postJSON(service.RefreshHandler, "/api/auth/refresh", "", `{"refreshToken": "`+auth.RefreshToken+`"}`)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected old refresh token to be revoked, got %d", rec.Code)
+	}
+
+	rec = postJSON(service.LoginHandler, "/api/auth/login", "", `{"username": "alice", "password": "newsecret"}`)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected login with new password, got %d", rec.Code)
+	}
+}
+
+func TestProfile_DeleteAccount(t *testing.T) {
+	service, _ := newTestAuthService(time.Minute)
+	auth := registerTestUser(t, service)
+
+	deleteAccount := func(body string) int {
+		req := httptest.NewRequest(http.MethodDelete, "/api/users/me", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+		rec := httptest.NewRecorder()
+		service.DeleteAccountHandler(rec, req)
+		return rec.Code
+	}
+
+	if code := deleteAccount(`{"password": "wrong"}`); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for wrong password, got %d", code)
+	}
+	if code := deleteAccount(`{"password": "secret123"}`); code != http.StatusNoContent {
+		t.Fatalf("Expected 204 on delete, got %d", code)
+	}
+
+	if service.userRepo.UserExists("alice") {
+		t.Error("Expected user to be removed")
+	}
+	if _, err := service.tokenSvc.ValidateToken(auth.Token); err == nil {
+		t.Error("Expected access token to be revoked")
+	}
+
+	rec := postJSON(service.LoginHandler, "/api/auth/login", "", `{"username": "alice", "password": "secret123"}`)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected login to fail after deletion, got %d", rec.Code)
+	}
+}
+
+// 修改密码与删除账户的密码校验与登录共用失败计数，持有访问令牌也不能无限次猜测
+func TestProfile_PasswordChecksUseLoginLockout(t *testing.T) {
+	service, _ := newTestAuthService(time.Minute)
+	auth := registerTestUser(t, service)
+
+	for i := 0; i < loginFailureThreshold; i++ {
+		rec := postJSON(service.ChangePasswordHandler, "/api/users/me/password", auth.Token,
+			`{"currentPassword": "wrong", "newPassword": "newsecret"}`)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected 401 for wrong password, got %d", rec.Code)
+		}
+	}
+
+	rec := postJSON(service.ChangePasswordHandler, "/api/users/me/password", auth.Token,
+		`{"currentPassword": "secret123", "newPassword": "newsecret"}`)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 while locked out, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/users/me", bytes.NewBufferString(`{"password": "secret123"}`))
+	req.Header.Set("Authorization", "Bearer "+auth.Token)
+	rec = httptest.NewRecorder()
+	service.DeleteAccountHandler(rec, req)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 on delete while locked out, got %d", rec.Code)
+	}
+
+	rec = postJSON(service.LoginHandler, "/api/auth/login", "", `{"username": "alice", "password": "secret123"}`)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected login to share the lockout, got %d", rec.Code)
+	}
+}
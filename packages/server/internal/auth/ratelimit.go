@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 连续登录失败达到该次数后开始退避
+	loginFailureThreshold = 3
+	loginBackoffBase      = time.Second
+	loginBackoffMax       = 15 * time.Minute
+
+	// 超过该数量的限流桶时清理已回满的桶
+	limiterPruneSize = 10000
+)
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+type loginFailure struct {
+	count       int
+	lockedUntil time.Time
+}
+
+// LoginLimiter 登录与注册限流器
+//
+// 按客户端 IP 与用户名分别维护令牌桶，速率与容量来自 AuthConfig.RateLimitRPS/Burst；
+// 同一用户名连续登录失败后按指数退避锁定。rps <= 0 时不限流。
+type LoginLimiter struct {
+	mutex    sync.Mutex
+	rps      float64
+	burst    float64
+	buckets  map[string]*tokenBucket
+	failures map[string]*loginFailure
+	trusted  []*net.IPNet
+	now      func() time.Time
+}
+
+func NewLoginLimiter(rps, burst int, trustedProxies []string) *LoginLimiter {
+	limiter := &LoginLimiter{
+		rps:      float64(rps),
+		burst:    float64(max(burst, 1)),
+		buckets:  make(map[string]*tokenBucket),
+		failures: make(map[string]*loginFailure),
+		now:      time.Now,
+	}
+
+	for _, cidr := range trustedProxies {
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			slog.Warn("ignoring invalid trusted proxy", "value", cidr, "error", err)
+			continue
+		}
+		limiter.trusted = append(limiter.trusted, network)
+	}
+
+	return limiter
+}
+
+// Allow 消耗一个令牌，不足时返回需要等待的时间
+func (l *LoginLimiter) Allow(key string) (bool, time.Duration) {
+	if l.rps <= 0 {
+		return true, 0
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	if len(l.buckets) > limiterPruneSize {
+		l.prune(now)
+	}
+
+	bucket, exists := l.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: l.burst, lastSeen: now}
+		l.buckets[key] = bucket
+	}
+
+	elapsed := now.Sub(bucket.lastSeen).Seconds()
+	bucket.tokens = math.Min(l.burst, bucket.tokens+elapsed*l.rps)
+	bucket.lastSeen = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - bucket.tokens) / l.rps * float64(time.Second))
+	return false, wait
+}
+
+// LockedFor 返回用户名因连续登录失败仍需等待的时间
+func (l *LoginLimiter) LockedFor(username string) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	failure, exists := l.failures[username]
+	if !exists {
+		return 0
+	}
+	return max(0, failure.lockedUntil.Sub(l.now()))
+}
+
+// RecordFailure 记录一次登录失败，超过阈值后锁定时间按 2 的幂增长
+func (l *LoginLimiter) RecordFailure(username string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	failure, exists := l.failures[username]
+	if !exists {
+		failure = &loginFailure{}
+		l.failures[username] = failure
+	}
+
+	failure.count++
+	if failure.count >= loginFailureThreshold {
+		backoff := loginBackoffBase << min(failure.count-loginFailureThreshold, 20)
+		failure.lockedUntil = l.now().Add(min(backoff, loginBackoffMax))
+	}
+}
+
+func (l *LoginLimiter) RecordSuccess(username string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.failures, username)
+}
+
+// ClientIP 获取客户端 IP。仅当直连地址属于受信任代理（如前置的 Caddy）时才采信
+// X-Forwarded-For，并从右向左取第一个不受信任的地址，防止客户端伪造。
+func (l *LoginLimiter) ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !l.isTrusted(host) {
+		return host
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(forwarded[i])
+		if ip == "" {
+			continue
+		}
+		if !l.isTrusted(ip) {
+			return ip
+		}
+		host = ip
+	}
+	return host
+}
+
+func (l *LoginLimiter) isTrusted(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range l.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *LoginLimiter) prune(now time.Time) {
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.lastSeen).Seconds()*l.rps >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+	for username, failure := range l.failures {
+		if now.After(failure.lockedUntil.Add(loginBackoffMax)) {
+			delete(l.failures, username)
+		}
+	}
+}
+
+// tooManyRequests 返回 429，Retry-After 向上取整到秒
+func tooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	recordAuthFailure("rate_limited")
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+	http.Error(w, fmt.Sprintf("Too many requests, retry after %ds", max(seconds, 1)), http.StatusTooManyRequests)
+}
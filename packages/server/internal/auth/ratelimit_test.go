@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLoginLimiter_TokenBucket(t *testing.T) {
+	limiter := NewLoginLimiter(1, 2, nil)
+	now := time.Unix(0, 0)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if allowed, _ := limiter.Allow("ip:1.2.3.4"); !allowed {
+			t.Fatalf("Expected request %d within burst to be allowed", i)
+		}
+	}
+
+	allowed, wait := limiter.Allow("ip:1.2.3.4")
+	if allowed || wait != time.Second {
+		t.Fatalf("Expected third request to wait 1s, got allowed=%v wait=%v", allowed, wait)
+	}
+
+	if allowed, _ := limiter.Allow("ip:5.6.7.8"); !allowed {
+		t.Error("Expected other keys to have their own bucket")
+	}
+
+	now = now.Add(time.Second)
+	if allowed, _ := limiter.Allow("ip:1.2.3.4"); !allowed {
+		t.Error("Expected bucket to refill after 1s")
+	}
+}
+
+func TestLoginLimiter_FailureBackoff(t *testing.T) {
+	limiter := NewLoginLimiter(0, 0, nil)
+	now := time.Unix(0, 0)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < loginFailureThreshold-1; i++ {
+		limiter.RecordFailure("alice")
+	}
+	if wait := limiter.LockedFor("alice"); wait != 0 {
+		t.Fatalf("Expected no lock below threshold, got %v", wait)
+	}
+
+	limiter.RecordFailure("alice")
+	first := limiter.LockedFor("alice")
+	limiter.RecordFailure("alice")
+	second := limiter.LockedFor("alice")
+	if first <= 0 || second != 2*first {
+		t.Fatalf("Expected doubling backoff, got %v then %v", first, second)
+	}
+
+	limiter.RecordSuccess("alice")
+	if wait := limiter.LockedFor("alice"); wait != 0 {
+		t.Errorf("Expected success to clear backoff, got %v", wait)
+	}
+}
+
+func TestLoginLimiter_ClientIP(t *testing.T) {
+	limiter := NewLoginLimiter(1, 1, []string{"10.0.0.0/8", "127.0.0.1"})
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded string
+		expected  string
+	}{
+		{"direct", "203.0.113.5:4000", "", "203.0.113.5"},
+		{"untrusted_proxy_ignored", "203.0.113.5:4000", "198.51.100.1", "203.0.113.5"},
+		{"trusted_proxy", "127.0.0.1:4000", "198.51.100.1", "198.51.100.1"},
+		{"spoofed_prefix", "127.0.0.1:4000", "1.1.1.1, 198.51.100.1, 10.0.0.2", "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+			req.RemoteAddr = tt.remote
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if ip := limiter.ClientIP(req); ip != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, ip)
+			}
+		})
+	}
+}
+
+func TestLoginHandler_RateLimited(t *testing.T) {
+	service, _ := newTestAuthService(time.Minute)
+	registerTestUser(t, service)
+	service.limiter = NewLoginLimiter(0, 0, nil)
+
+	body := `{"username": "alice", "password": "wrong-password"}`
+	for i := 0; i < loginFailureThreshold; i++ {
+		rec := postJSON(service.LoginHandler, "/api/auth/login", "", body)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected 401 for wrong password, got %d", rec.Code)
+		}
+	}
+
+	rec := postJSON(service.LoginHandler, "/api/auth/login", "", `{"username": "alice", "password": "secret123"}`)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("Expected 429 with Retry-After during backoff, got %d", rec.Code)
+	}
+
+	service.limiter = NewLoginLimiter(1, 1, nil)
+	postJSON(service.LoginHandler, "/api/auth/login", "", body)
+	rec = postJSON(service.LoginHandler, "/api/auth/login", "", body)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected 429 with Retry-After 1 once the bucket is empty, got %d %q",
+			rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
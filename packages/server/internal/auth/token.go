@@ -0,0 +1,269 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RefreshToken 持久化的刷新令牌，只保存令牌的哈希
+type RefreshToken struct {
+	TokenHash string
+	UserID    string
+	Username  string
+	ExpiresAt time.Time
+	Revoked   bool
+	CreatedAt time.Time
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken"`
+	// All 为 true 时注销该用户的所有刷新令牌
+	All bool `json:"all"`
+}
+
+// TokenRepository 保存刷新令牌与已吊销的访问令牌 jti
+type TokenRepository interface {
+	SaveRefreshToken(token *RefreshToken) error
+	GetRefreshToken(tokenHash string) (*RefreshToken, error)
+	RevokeRefreshToken(tokenHash string) error
+	RevokeUserRefreshTokens(userID string) error
+	RevokeAccessToken(jti string, expiresAt time.Time) error
+	IsAccessTokenRevoked(jti string) bool
+}
+
+// RefreshHandler 用刷新令牌换取新的访问令牌，旧刷新令牌随即失效（轮换）
+func (a *AuthService) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tokenHash := hashRefreshToken(req.RefreshToken)
+	stored, err := a.tokenRepo.GetRefreshToken(tokenHash)
+	if err != nil || time.Now().After(stored.ExpiresAt) {
+		recordAuthFailure("invalid_refresh_token")
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	if stored.Revoked {
+		// 已轮换的令牌被再次使用，说明令牌可能泄露，吊销该用户的全部刷新令牌
+		a.tokenRepo.RevokeUserRefreshTokens(stored.UserID)
+		recordAuthFailure("invalid_refresh_token")
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	// 并发刷新同一令牌时只有一方能成功吊销
+	if err := a.tokenRepo.RevokeRefreshToken(tokenHash); err != nil {
+		recordAuthFailure("invalid_refresh_token")
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := a.userRepo.GetUserByUsername(stored.Username)
+	if err != nil || user.ID != stored.UserID {
+		recordAuthFailure("invalid_refresh_token")
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+	if user.Banned {
+		recordAuthFailure("banned")
+		http.Error(w, "Account is banned", http.StatusForbidden)
+		return
+	}
+
+	response, err := a.issueTokens(user)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// LogoutHandler 吊销当前访问令牌及请求中的刷新令牌
+func (a *AuthService) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := a.requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	var req LogoutRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := a.tokenSvc.RevokeToken(claims); err != nil {
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+
+	if req.All {
+		a.tokenRepo.RevokeUserRefreshTokens(claims.UserID)
+	} else if req.RefreshToken != "" {
+		tokenHash := hashRefreshToken(req.RefreshToken)
+		if stored, err := a.tokenRepo.GetRefreshToken(tokenHash); err == nil && stored.UserID == claims.UserID {
+			a.tokenRepo.RevokeRefreshToken(tokenHash)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// issueTokens 签发访问令牌与新的刷新令牌
+func (a *AuthService) issueTokens(user *User) (*AuthResponse, error) {
+	a.bootstrapAdmin(user)
+
+	token, expiresAt, err := a.tokenSvc.GenerateToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := generateTicket()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	refreshExpiresAt := now.Add(a.refreshTTL)
+	err = a.tokenRepo.SaveRefreshToken(&RefreshToken{
+		TokenHash: hashRefreshToken(refreshToken),
+		UserID:    user.ID,
+		Username:  user.Username,
+		ExpiresAt: refreshExpiresAt,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		Token:            token,
+		User:             user,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt.Unix(),
+	}, nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+type InMemoryTokenRepository struct {
+	mutex         sync.RWMutex
+	refreshTokens map[string]*RefreshToken
+	revoked       map[string]time.Time
+}
+
+func NewInMemoryTokenRepository() *InMemoryTokenRepository {
+	return &InMemoryTokenRepository{
+		refreshTokens: make(map[string]*RefreshToken),
+		revoked:       make(map[string]time.Time),
+	}
+}
+
+func (r *InMemoryTokenRepository) SaveRefreshToken(token *RefreshToken) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	for hash, existing := range r.refreshTokens {
+		if now.After(existing.ExpiresAt) {
+			delete(r.refreshTokens, hash)
+		}
+	}
+
+	stored := *token
+	r.refreshTokens[token.TokenHash] = &stored
+	return nil
+}
+
+func (r *InMemoryTokenRepository) GetRefreshToken(tokenHash string) (*RefreshToken, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	token, exists := r.refreshTokens[tokenHash]
+	if !exists {
+		return nil, fmt.Errorf("refresh token not found")
+	}
+	stored := *token
+	return &stored, nil
+}
+
+func (r *InMemoryTokenRepository) RevokeRefreshToken(tokenHash string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	token, exists := r.refreshTokens[tokenHash]
+	if !exists {
+		return fmt.Errorf("refresh token not found")
+	}
+	if token.Revoked {
+		return fmt.Errorf("refresh token already revoked")
+	}
+	token.Revoked = true
+	return nil
+}
+
+func (r *InMemoryTokenRepository) RevokeUserRefreshTokens(userID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, token := range r.refreshTokens {
+		if token.UserID == userID {
+			token.Revoked = true
+		}
+	}
+	return nil
+}
+
+func (r *InMemoryTokenRepository) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// 过期的访问令牌本身已无效，无需继续保留在吊销列表中
+	now := time.Now()
+	for id, exp := range r.revoked {
+		if now.After(exp) {
+			delete(r.revoked, id)
+		}
+	}
+
+	r.revoked[jti] = expiresAt
+	return nil
+}
+
+func (r *InMemoryTokenRepository) IsAccessTokenRevoked(jti string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	_, revoked := r.revoked[jti]
+	return revoked
+}
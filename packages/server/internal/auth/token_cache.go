@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"fmt"
+	"server/internal/cache"
+	"time"
+)
+
+// CacheTokenRepository 将刷新令牌与吊销记录保存在缓存中，缓存使用 Redis 时重启后仍然有效。
+// 每条记录的过期时间与对应令牌一致，过期后由缓存自动清理。
+type CacheTokenRepository struct {
+	cache *cache.CacheService
+	// refreshTTL 刷新令牌的最长有效期，用户级吊销记录需要保留这么久
+	refreshTTL time.Duration
+}
+
+func NewCacheTokenRepository(cacheSvc *cache.CacheService, refreshTTL time.Duration) *CacheTokenRepository {
+	return &CacheTokenRepository{cache: cacheSvc, refreshTTL: refreshTTL}
+}
+
+func refreshTokenKey(tokenHash string) string {
+	return fmt.Sprintf("token:refresh:%s", tokenHash)
+}
+
+func revokedRefreshTokenKey(tokenHash string) string {
+	return fmt.Sprintf("token:refresh-revoked:%s", tokenHash)
+}
+
+// userRevokedKey 保存吊销用户全部刷新令牌的时间，此前签发的刷新令牌均视为已吊销
+func userRevokedKey(userID string) string {
+	return fmt.Sprintf("token:user-revoked:%s", userID)
+}
+
+func revokedAccessTokenKey(jti string) string {
+	return fmt.Sprintf("token:revoked-jti:%s", jti)
+}
+
+func (r *CacheTokenRepository) SaveRefreshToken(token *RefreshToken) error {
+	ttl := time.Until(token.ExpiresAt)
+	if ttl <= 0 {
+		return fmt.Errorf("refresh token already expired")
+	}
+	stored := *token
+	stored.Revoked = false
+	return r.cache.SetJSON(refreshTokenKey(token.TokenHash), &stored, ttl)
+}
+
+func (r *CacheTokenRepository) GetRefreshToken(tokenHash string) (*RefreshToken, error) {
+	var token RefreshToken
+	found, err := r.cache.GetJSON(refreshTokenKey(tokenHash), &token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load refresh token: %w", err)
+	}
+	if !found {
+		return nil, fmt.Errorf("refresh token not found")
+	}
+
+	token.Revoked = r.cache.Exists(revokedRefreshTokenKey(tokenHash))
+	if !token.Revoked {
+		var revokedAt int64
+		if found, _ := r.cache.GetJSON(userRevokedKey(token.UserID), &revokedAt); found {
+			token.Revoked = !token.CreatedAt.After(time.Unix(0, revokedAt))
+		}
+	}
+	return &token, nil
+}
+
+// RevokeRefreshToken 以 SetNX 写入吊销记录，并发吊销同一令牌时只有一方成功
+func (r *CacheTokenRepository) RevokeRefreshToken(tokenHash string) error {
+	token, err := r.GetRefreshToken(tokenHash)
+	if err != nil {
+		return err
+	}
+	if token.Revoked {
+		return fmt.Errorf("refresh token already revoked")
+	}
+
+	ttl := time.Until(token.ExpiresAt)
+	if ttl <= 0 {
+		return fmt.Errorf("refresh token expired")
+	}
+	revoked, err := r.cache.SetNX(revokedRefreshTokenKey(tokenHash), "1", ttl)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	if !revoked {
+		return fmt.Errorf("refresh token already revoked")
+	}
+	return nil
+}
+
+func (r *CacheTokenRepository) RevokeUserRefreshTokens(userID string) error {
+	return r.cache.SetJSON(userRevokedKey(userID), time.Now().UnixNano(), r.refreshTTL)
+}
+
+func (r *CacheTokenRepository) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		// 过期的访问令牌本身已无效，无需记录
+		return nil
+	}
+	return r.cache.SetJSON(revokedAccessTokenKey(jti), true, ttl)
+}
+
+func (r *CacheTokenRepository) IsAccessTokenRevoked(jti string) bool {
+	return r.cache.Exists(revokedAccessTokenKey(jti))
+}
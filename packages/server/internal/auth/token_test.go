@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"server/internal/cache"
+	"testing"
+	"time"
+)
+
+func postJSON(handler http.HandlerFunc, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func registerTestUser(t *testing.T, service *AuthService) *AuthResponse {
+	t.Helper()
+	rec := postJSON(service.RegisterHandler, "/api/auth/register", "",
+		`{"username": "alice", "email": "alice@example.com", "password": "secret123"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 on register, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var response AuthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode auth response: %v", err)
+	}
+	if response.RefreshToken == "" {
+		t.Fatal("Expected a refresh token")
+	}
+	return &response
+}
+
+func TestRefreshToken_Rotation(t *testing.T) {
+	service, _ := newTestAuthService(time.Minute)
+	first := registerTestUser(t, service)
+
+	rec := postJSON(service.RefreshHandler, "/api/auth/refresh", "", `{"refreshToken": "`+first.RefreshToken+`"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 on refresh, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var second AuthResponse
+	json.NewDecoder(rec.Body).Decode(&second)
+	if second.RefreshToken == first.RefreshToken || second.Token == first.Token {
+		t.Fatal("Expected new tokens after refresh")
+	}
+
+	// 旧令牌被重复使用，视为泄露，新令牌也随之失效
+	rec = postJSON(service.RefreshHandler, "/api/auth/refresh", "", `{"refreshToken": "`+first.RefreshToken+`"}`)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected reused refresh token to be rejected, got %d", rec.Code)
+	}
+	rec = postJSON(service.RefreshHandler, "/api/auth/refresh", "", `{"refreshToken": "`+second.RefreshToken+`"}`)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected token family to be revoked after reuse, got %d", rec.Code)
+	}
+}
+
+func TestLogout_RevokesTokens(t *testing.T) {
+	service, _ := newTestAuthService(time.Minute)
+	session := registerTestUser(t, service)
+
+	handler := service.AuthMiddleware(echoUser)
+	req := httptest.NewRequest(http.MethodGet, "/api/cache/stats", nil)
+	req.Header.Set("Authorization", "Bearer "+session.Token)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected access token to work before logout, got %d", rec.Code)
+	}
+
+	rec = postJSON(service.LogoutHandler, "/api/auth/logout", session.Token, `{"refreshToken": "`+session.RefreshToken+`"}`)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 on logout, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected revoked access token to be rejected, got %d", rec.Code)
+	}
+
+	rec = postJSON(service.RefreshHandler, "/api/auth/refresh", "", `{"refreshToken": "`+session.RefreshToken+`"}`)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected revoked refresh token to be rejected, got %d", rec.Code)
+	}
+}
+
+// 新的仓库实例共享同一个缓存，模拟使用 Redis 时服务重启
+func TestCacheTokenRepository_SurvivesRestart(t *testing.T) {
+	cacheSvc := cache.NewCacheService(cache.NewInMemoryCache(time.Minute), cache.DefaultTTLs())
+	before := NewCacheTokenRepository(cacheSvc, time.Hour)
+
+	now := time.Now()
+	token := &RefreshToken{TokenHash: "hash-1", UserID: "user_1", Username: "alice", ExpiresAt: now.Add(time.Hour), CreatedAt: now}
+	if err := before.SaveRefreshToken(token); err != nil {
+		t.Fatalf("Expected no error saving token, got %v", err)
+	}
+	before.RevokeAccessToken("jti-1", now.Add(time.Minute))
+
+	repo := NewCacheTokenRepository(cacheSvc, time.Hour)
+	if !repo.IsAccessTokenRevoked("jti-1") || repo.IsAccessTokenRevoked("jti-2") {
+		t.Error("Expected only jti-1 to stay revoked")
+	}
+	stored, err := repo.GetRefreshToken("hash-1")
+	if err != nil || stored.UserID != "user_1" || stored.Revoked {
+		t.Fatalf("Expected stored refresh token, got %+v (%v)", stored, err)
+	}
+	if _, err := repo.GetRefreshToken("missing"); err == nil {
+		t.Error("Expected error for unknown refresh token")
+	}
+
+	// 同一令牌只能吊销一次，轮换时并发刷新只有一方成功
+	if err := repo.RevokeRefreshToken("hash-1"); err != nil {
+		t.Fatalf("Expected no error revoking, got %v", err)
+	}
+	if err := repo.RevokeRefreshToken("hash-1"); err == nil {
+		t.Error("Expected second revoke to fail")
+	}
+	if stored, _ := repo.GetRefreshToken("hash-1"); !stored.Revoked {
+		t.Error("Expected revoked refresh token")
+	}
+
+	// 吊销用户的全部令牌只影响此前签发的令牌
+	repo.SaveRefreshToken(&RefreshToken{TokenHash: "hash-2", UserID: "user_1", ExpiresAt: now.Add(time.Hour), CreatedAt: now})
+	repo.RevokeUserRefreshTokens("user_1")
+	later := time.Now().Add(time.Millisecond)
+	repo.SaveRefreshToken(&RefreshToken{TokenHash: "hash-3", UserID: "user_1", ExpiresAt: later.Add(time.Hour), CreatedAt: later})
+	if stored, _ := repo.GetRefreshToken("hash-2"); !stored.Revoked {
+		t.Error("Expected earlier token to be revoked with the user")
+	}
+	if stored, _ := repo.GetRefreshToken("hash-3"); stored.Revoked {
+		t.Error("Expected token issued after the revocation to stay valid")
+	}
+}
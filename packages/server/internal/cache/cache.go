@@ -2,7 +2,9 @@ package cache
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -20,6 +22,12 @@ type Cache interface {
 	Set(key string, value interface{}, ttl time.Duration) error
 	Get(key string) (interface{}, bool)
 	Delete(key string) bool
+	// SetNX 仅在键不存在时写入，返回是否写入成功
+	SetNX(key string, value interface{}, ttl time.Duration) (bool, error)
+	// Increment 原子地增加计数并返回新值，键不存在时从 0 开始并设置 ttl
+	Increment(key string, delta int64, ttl time.Duration) (int64, error)
+	// CompareAndDelete 仅当值等于 value 时删除，用于释放自己持有的锁
+	CompareAndDelete(key string, value string) (bool, error)
 	Clear()
 	GetStats() CacheStats
 }
@@ -30,72 +38,172 @@ type CacheStats struct {
 	ItemCount   int       `json:"itemCount"`
 	HitRate     float64   `json:"hitRate"`
 	LastCleanup time.Time `json:"lastCleanup"`
+	// Evictions 因超出内存预算被淘汰的项数，Expirations 为过期清理的项数
+	Evictions   int64 `json:"evictions"`
+	Expirations int64 `json:"expirations"`
+	MemoryBytes int64 `json:"memoryBytes"`
+	// MaxMemoryBytes 为 0 表示不限制
+	MaxMemoryBytes int64 `json:"maxMemoryBytes"`
 }
 
+var ErrItemTooLarge = errors.New("cache item exceeds memory budget")
+
+type Options struct {
+	CleanupInterval time.Duration
+	// MaxMemoryBytes 为 0 时不限制内存
+	MaxMemoryBytes int64
+	EvictionPolicy EvictionPolicy
+}
+
+// InMemoryCache 进程内缓存，按估算大小记账，超出预算时按 LRU 或 LFU 淘汰
 type InMemoryCache struct {
-	data            map[string]*CacheItem
-	mutex           sync.RWMutex
+	data            map[string]*entry
+	evictor         evictor
+	mutex           sync.Mutex
 	hits            int64
 	misses          int64
+	evictions       int64
+	expirations     int64
+	memoryBytes     int64
+	maxMemoryBytes  int64
+	lastCleanup     time.Time
 	cleanupInterval time.Duration
 	stopCleanup     chan bool
 }
 
 func NewInMemoryCache(cleanupInterval time.Duration) *InMemoryCache {
+	cache, _ := NewInMemoryCacheWithOptions(Options{CleanupInterval: cleanupInterval})
+	return cache
+}
+
+func NewInMemoryCacheWithOptions(opts Options) (*InMemoryCache, error) {
+	evictor, err := newEvictor(opts.EvictionPolicy)
+	if err != nil {
+		return nil, err
+	}
+
 	cache := &InMemoryCache{
-		data:            make(map[string]*CacheItem),
-		cleanupInterval: cleanupInterval,
+		data:            make(map[string]*entry),
+		evictor:         evictor,
+		maxMemoryBytes:  opts.MaxMemoryBytes,
+		cleanupInterval: opts.CleanupInterval,
 		stopCleanup:     make(chan bool),
 	}
 
 	go cache.startCleanup()
-	return cache
+	return cache, nil
 }
 
 func (c *InMemoryCache) Set(key string, value interface{}, ttl time.Duration) error {
+	size := estimateSize(key, value)
+	if c.maxMemoryBytes > 0 && size > c.maxMemoryBytes {
+		return ErrItemTooLarge
+	}
+
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	expiresAt := time.Now().Add(ttl)
-	c.data[key] = &CacheItem{
-		Value:     value,
-		ExpiresAt: expiresAt,
+	if existing, exists := c.data[key]; exists {
+		c.removeEntry(existing)
 	}
 
+	c.insert(key, value, size, time.Now().Add(ttl))
 	return nil
 }
 
 func (c *InMemoryCache) Get(key string) (interface{}, bool) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
-	item, exists := c.data[key]
+	e, exists := c.data[key]
 	if !exists {
 		c.misses++
 		return nil, false
 	}
 
-	if item.IsExpired() {
+	if e.item.IsExpired() {
 		c.misses++
-		go func() {
-			c.mutex.Lock()
-			delete(c.data, key)
-			c.mutex.Unlock()
-		}()
+		c.removeEntry(e)
+		c.expirations++
 		return nil, false
 	}
 
 	c.hits++
-	return item.Value, true
+	c.evictor.touch(e)
+	return e.item.Value, true
+}
+
+func (c *InMemoryCache) SetNX(key string, value interface{}, ttl time.Duration) (bool, error) {
+	size := estimateSize(key, value)
+	if c.maxMemoryBytes > 0 && size > c.maxMemoryBytes {
+		return false, ErrItemTooLarge
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if existing, exists := c.data[key]; exists {
+		if !existing.item.IsExpired() {
+			return false, nil
+		}
+		c.removeEntry(existing)
+		c.expirations++
+	}
+
+	c.insert(key, value, size, time.Now().Add(ttl))
+	return true, nil
+}
+
+func (c *InMemoryCache) Increment(key string, delta int64, ttl time.Duration) (int64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var current int64
+	expiresAt := time.Now().Add(ttl)
+	if existing, exists := c.data[key]; exists && !existing.item.IsExpired() {
+		switch v := existing.item.Value.(type) {
+		case int64:
+			current = v
+		case string:
+			parsed, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("cached value is not an integer: %s", key)
+			}
+			current = parsed
+		default:
+			return 0, fmt.Errorf("cached value is not an integer: %s", key)
+		}
+		expiresAt = existing.item.ExpiresAt
+	}
+
+	if existing, exists := c.data[key]; exists {
+		c.removeEntry(existing)
+	}
+
+	value := current + delta
+	c.insert(key, value, estimateSize(key, value), expiresAt)
+	return value, nil
+}
+
+func (c *InMemoryCache) CompareAndDelete(key string, value string) (bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, exists := c.data[key]
+	if !exists || e.item.IsExpired() || e.item.Value != value {
+		return false, nil
+	}
+	c.removeEntry(e)
+	return true, nil
 }
 
 func (c *InMemoryCache) Delete(key string) bool {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	_, exists := c.data[key]
+	e, exists := c.data[key]
 	if exists {
-		delete(c.data, key)
+		c.removeEntry(e)
 	}
 	return exists
 }
@@ -104,14 +212,20 @@ func (c *InMemoryCache) Clear() {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	c.data = make(map[string]*CacheItem)
+	for _, e := range c.data {
+		c.evictor.remove(e)
+	}
+	c.data = make(map[string]*entry)
+	c.memoryBytes = 0
 	c.hits = 0
 	c.misses = 0
+	c.evictions = 0
+	c.expirations = 0
 }
 
 func (c *InMemoryCache) GetStats() CacheStats {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
 	total := c.hits + c.misses
 	hitRate := float64(0)
@@ -120,10 +234,15 @@ func (c *InMemoryCache) GetStats() CacheStats {
 	}
 
 	return CacheStats{
-		Hits:      c.hits,
-		Misses:    c.misses,
-		ItemCount: len(c.data),
-		HitRate:   hitRate,
+		Hits:           c.hits,
+		Misses:         c.misses,
+		ItemCount:      len(c.data),
+		HitRate:        hitRate,
+		LastCleanup:    c.lastCleanup,
+		Evictions:      c.evictions,
+		Expirations:    c.expirations,
+		MemoryBytes:    c.memoryBytes,
+		MaxMemoryBytes: c.maxMemoryBytes,
 	}
 }
 
@@ -150,26 +269,94 @@ func (c *InMemoryCache) cleanup() {
 	defer c.mutex.Unlock()
 
 	now := time.Now()
-	for key, item := range c.data {
-		if now.After(item.ExpiresAt) {
-			delete(c.data, key)
+	for _, e := range c.data {
+		if now.After(e.item.ExpiresAt) {
+			c.removeEntry(e)
+			c.expirations++
 		}
 	}
+	c.lastCleanup = now
+}
+
+// evictOverBudget 调用方需持有锁。过期项由定期清理回收，这里只按策略淘汰。
+func (c *InMemoryCache) evictOverBudget() {
+	if c.maxMemoryBytes <= 0 {
+		return
+	}
+
+	for c.memoryBytes > c.maxMemoryBytes {
+		victim := c.evictor.victim()
+		if victim == nil {
+			return
+		}
+		c.removeEntry(victim)
+		c.evictions++
+	}
+}
+
+// insert 调用方需持有锁，且 key 不在缓存中
+func (c *InMemoryCache) insert(key string, value interface{}, size int64, expiresAt time.Time) {
+	e := &entry{
+		key: key,
+		item: CacheItem{
+			Value:     value,
+			ExpiresAt: expiresAt,
+		},
+		size: size,
+	}
+	c.data[key] = e
+	c.evictor.add(e)
+	c.memoryBytes += size
+
+	c.evictOverBudget()
+}
+
+// removeEntry 调用方需持有锁
+func (c *InMemoryCache) removeEntry(e *entry) {
+	delete(c.data, e.key)
+	c.evictor.remove(e)
+	c.memoryBytes -= e.size
+}
+
+// TTLs 各命名空间的默认过期时间，为 0 时使用 Default
+type TTLs struct {
+	Default    time.Duration
+	Session    time.Duration
+	GameState  time.Duration
+	Connection time.Duration
+}
+
+func DefaultTTLs() TTLs {
+	return TTLs{
+		Default:    time.Hour,
+		Session:    24 * time.Hour,
+		GameState:  2 * time.Hour,
+		Connection: 30 * time.Minute,
+	}
+}
+
+func (t TTLs) orDefault(ttl time.Duration) time.Duration {
+	if ttl > 0 {
+		return ttl
+	}
+	return t.Default
 }
 
 type CacheService struct {
 	cache Cache
+	ttls  TTLs
 }
 
-func NewCacheService(cache Cache) *CacheService {
+func NewCacheService(cache Cache, ttls TTLs) *CacheService {
 	return &CacheService{
 		cache: cache,
+		ttls:  ttls,
 	}
 }
 
 func (s *CacheService) SetUserSession(userID string, sessionData interface{}) error {
 	key := fmt.Sprintf("session:%s", userID)
-	return s.cache.Set(key, sessionData, 24*time.Hour)
+	return s.cache.Set(key, sessionData, s.ttls.orDefault(s.ttls.Session))
 }
 
 func (s *CacheService) GetUserSession(userID string) (interface{}, bool) {
@@ -184,7 +371,7 @@ func (s *CacheService) DeleteUserSession(userID string) bool {
 
 func (s *CacheService) SetGameState(gameID string, gameState interface{}) error {
 	key := fmt.Sprintf("game:%s", gameID)
-	return s.cache.Set(key, gameState, 2*time.Hour)
+	return s.cache.Set(key, gameState, s.ttls.orDefault(s.ttls.GameState))
 }
 
 func (s *CacheService) GetGameState(gameID string) (interface{}, bool) {
@@ -199,7 +386,7 @@ func (s *CacheService) DeleteGameState(gameID string) bool {
 
 func (s *CacheService) SetPlayerConnection(playerID string, connectionInfo interface{}) error {
 	key := fmt.Sprintf("connection:%s", playerID)
-	return s.cache.Set(key, connectionInfo, 30*time.Minute)
+	return s.cache.Set(key, connectionInfo, s.ttls.orDefault(s.ttls.Connection))
 }
 
 func (s *CacheService) GetPlayerConnection(playerID string) (interface{}, bool) {
@@ -212,16 +399,63 @@ func (s *CacheService) DeletePlayerConnection(playerID string) bool {
 	return s.cache.Delete(key)
 }
 
+func (s *CacheService) SetWSTicket(ticket string, value interface{}, ttl time.Duration) error {
+	key := fmt.Sprintf("wsticket:%s", ticket)
+	return s.SetJSON(key, value, ttl)
+}
+
+// ConsumeWSTicket 读取并删除票据，每张票据只能使用一次
+func (s *CacheService) ConsumeWSTicket(ticket string, dest interface{}) (bool, error) {
+	key := fmt.Sprintf("wsticket:%s", ticket)
+	found, err := s.GetJSON(key, dest)
+	if !found || err != nil {
+		return false, err
+	}
+
+	// 同一票据被并发使用时，只有成功删除的一方有效
+	if !s.cache.Delete(key) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// Increment 计数器在首次增加时开始计时，ttl 为 0 时使用默认过期时间
+func (s *CacheService) Increment(key string, delta int64, ttl time.Duration) (int64, error) {
+	return s.cache.Increment(fmt.Sprintf("counter:%s", key), delta, s.ttls.orDefault(ttl))
+}
+
+// TryLock 尝试取得名为 name 的锁，owner 标识持有者，锁在 ttl 后自动释放
+func (s *CacheService) TryLock(name, owner string, ttl time.Duration) (bool, error) {
+	return s.cache.SetNX(fmt.Sprintf("lock:%s", name), owner, ttl)
+}
+
+// Unlock 仅释放 owner 自己持有的锁，锁已过期并被他人取得时不受影响
+func (s *CacheService) Unlock(name, owner string) (bool, error) {
+	return s.cache.CompareAndDelete(fmt.Sprintf("lock:%s", name), owner)
+}
+
 func (s *CacheService) GetCacheStats() CacheStats {
 	return s.cache.GetStats()
 }
 
+// SetNX 仅在键不存在时写入，返回是否写入成功，ttl 为 0 时使用默认过期时间
+func (s *CacheService) SetNX(key string, value interface{}, ttl time.Duration) (bool, error) {
+	return s.cache.SetNX(key, value, s.ttls.orDefault(ttl))
+}
+
+// Exists 键存在且未过期时返回 true
+func (s *CacheService) Exists(key string) bool {
+	_, exists := s.cache.Get(key)
+	return exists
+}
+
+// SetJSON ttl 为 0 时使用默认过期时间
 func (s *CacheService) SetJSON(key string, value interface{}, ttl time.Duration) error {
 	jsonData, err := json.Marshal(value)
 	if err != nil {
 		return fmt.Errorf("failed to marshal JSON: %w", err)
 	}
-	return s.cache.Set(key, string(jsonData), ttl)
+	return s.cache.Set(key, string(jsonData), s.ttls.orDefault(ttl))
 }
 
 func (s *CacheService) GetJSON(key string, dest interface{}) (bool, error) {
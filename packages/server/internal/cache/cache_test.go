@@ -0,0 +1,219 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestCache(t *testing.T, maxBytes int64, policy EvictionPolicy) *InMemoryCache {
+	t.Helper()
+	c, err := NewInMemoryCacheWithOptions(Options{
+		CleanupInterval: time.Minute,
+		MaxMemoryBytes:  maxBytes,
+		EvictionPolicy:  policy,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	t.Cleanup(c.Stop)
+	return c
+}
+
+func TestInMemoryCache_LRUEviction(t *testing.T) {
+	value := strings.Repeat("x", 100)
+	c := newTestCache(t, 3*estimateSize("k0", value), EvictionLRU)
+
+	for i := 0; i < 3; i++ {
+		c.Set(fmt.Sprintf("k%d", i), value, time.Hour)
+	}
+
+	// 访问 k0 使其成为最近使用，k1 应被淘汰
+	if _, ok := c.Get("k0"); !ok {
+		t.Fatal("Expected k0 to be cached")
+	}
+	c.Set("k3", value, time.Hour)
+
+	if _, ok := c.Get("k1"); ok {
+		t.Error("Expected least recently used k1 to be evicted")
+	}
+	for _, key := range []string{"k0", "k2", "k3"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("Expected %s to remain cached", key)
+		}
+	}
+
+	stats := c.GetStats()
+	if stats.Evictions != 1 || stats.ItemCount != 3 {
+		t.Errorf("Expected 1 eviction and 3 items, got %+v", stats)
+	}
+	if stats.MemoryBytes > stats.MaxMemoryBytes {
+		t.Errorf("Expected memory %d within budget %d", stats.MemoryBytes, stats.MaxMemoryBytes)
+	}
+}
+
+func TestInMemoryCache_LFUEviction(t *testing.T) {
+	value := strings.Repeat("x", 100)
+	c := newTestCache(t, 3*estimateSize("k0", value), EvictionLFU)
+
+	for i := 0; i < 3; i++ {
+		c.Set(fmt.Sprintf("k%d", i), value, time.Hour)
+	}
+
+	// k1 访问次数最少；k0 虽然较早访问，但次数更多
+	for i := 0; i < 3; i++ {
+		c.Get("k0")
+	}
+	c.Get("k2")
+	c.Set("k3", value, time.Hour)
+
+	if _, ok := c.Get("k1"); ok {
+		t.Error("Expected least frequently used k1 to be evicted")
+	}
+	if _, ok := c.Get("k0"); !ok {
+		t.Error("Expected frequently used k0 to remain cached")
+	}
+}
+
+func TestInMemoryCache_SizeAccounting(t *testing.T) {
+	c := newTestCache(t, 0, EvictionLRU)
+
+	c.Set("a", "12345", time.Hour)
+	c.Set("a", "1234567890", time.Hour)
+	if got, want := c.GetStats().MemoryBytes, estimateSize("a", "1234567890"); got != want {
+		t.Errorf("Expected overwrite to replace size accounting, got %d want %d", got, want)
+	}
+
+	c.Delete("a")
+	if got := c.GetStats().MemoryBytes; got != 0 {
+		t.Errorf("Expected 0 bytes after delete, got %d", got)
+	}
+}
+
+func TestInMemoryCache_RejectsItemLargerThanBudget(t *testing.T) {
+	c := newTestCache(t, 200, EvictionLRU)
+
+	if err := c.Set("big", strings.Repeat("x", 500), time.Hour); err != ErrItemTooLarge {
+		t.Errorf("Expected ErrItemTooLarge, got %v", err)
+	}
+}
+
+func TestInMemoryCache_ExpiredItems(t *testing.T) {
+	c := newTestCache(t, 0, EvictionLRU)
+
+	c.Set("short", "v", time.Millisecond)
+	c.Set("gone", "v", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok := c.Get("short"); ok {
+		t.Error("Expected expired item to miss")
+	}
+	c.cleanup()
+
+	stats := c.GetStats()
+	if stats.Expirations != 2 || stats.ItemCount != 0 || stats.MemoryBytes != 0 {
+		t.Errorf("Expected both items expired, got %+v", stats)
+	}
+	if stats.LastCleanup.IsZero() {
+		t.Error("Expected LastCleanup to be set")
+	}
+}
+
+func TestInMemoryCache_UnknownPolicy(t *testing.T) {
+	if _, err := NewInMemoryCacheWithOptions(Options{CleanupInterval: time.Minute, EvictionPolicy: "fifo"}); err == nil {
+		t.Error("Expected error for unknown eviction policy")
+	}
+}
+
+func TestInMemoryCache_ConcurrentAccess(t *testing.T) {
+	c := newTestCache(t, 4096, EvictionLFU)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				key := fmt.Sprintf("k%d", (id*j)%50)
+				c.Set(key, "value", time.Hour)
+				c.Get(key)
+				if j%7 == 0 {
+					c.Delete(key)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if stats := c.GetStats(); stats.MemoryBytes > 4096 {
+		t.Errorf("Expected memory within budget, got %+v", stats)
+	}
+}
+
+func TestCacheService_NamespaceTTLs(t *testing.T) {
+	c := newTestCache(t, 0, EvictionLRU)
+	svc := NewCacheService(c, TTLs{Default: time.Hour, Session: 10 * time.Millisecond})
+
+	svc.SetUserSession("u1", "session")
+	svc.SetGameState("g1", "state")
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok := svc.GetUserSession("u1"); ok {
+		t.Error("Expected session to expire with its namespace TTL")
+	}
+	if _, ok := svc.GetGameState("g1"); !ok {
+		t.Error("Expected game state to fall back to the default TTL")
+	}
+
+	if err := svc.SetJSON("plain", map[string]int{"a": 1}, 0); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var got map[string]int
+	if found, err := svc.GetJSON("plain", &got); !found || err != nil || got["a"] != 1 {
+		t.Errorf("Expected JSON stored with default TTL, got %v (%v)", got, err)
+	}
+}
+
+func TestInMemoryCache_AtomicOperations(t *testing.T) {
+	c := newTestCache(t, 0, EvictionLRU)
+	svc := NewCacheService(c, DefaultTTLs())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			svc.Increment("hits", 1, time.Minute)
+		}()
+	}
+	wg.Wait()
+	if value, _ := svc.Increment("hits", 0, time.Minute); value != 10 {
+		t.Errorf("Expected counter 10, got %d", value)
+	}
+
+	c.Set("text", "abc", time.Minute)
+	if _, err := c.Increment("text", 1, time.Minute); err == nil {
+		t.Error("Expected error incrementing a non-integer value")
+	}
+
+	if locked, _ := svc.TryLock("g1", "owner-1", time.Minute); !locked {
+		t.Fatal("Expected first lock to succeed")
+	}
+	if locked, _ := svc.TryLock("g1", "owner-2", time.Minute); locked {
+		t.Error("Expected second lock to fail")
+	}
+	if released, _ := svc.Unlock("g1", "owner-2"); released {
+		t.Error("Expected unlock by non-owner to fail")
+	}
+	if released, _ := svc.Unlock("g1", "owner-1"); !released {
+		t.Error("Expected owner to release the lock")
+	}
+
+	c.SetNX("expiring", "v", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if ok, _ := c.SetNX("expiring", "v2", time.Minute); !ok {
+		t.Error("Expected SetNX to replace an expired key")
+	}
+}
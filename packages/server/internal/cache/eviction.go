@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+	"encoding/json"
+	"fmt"
+)
+
+// EvictionPolicy 超出内存预算时选择淘汰项的策略
+type EvictionPolicy string
+
+const (
+	EvictionLRU EvictionPolicy = "lru"
+	EvictionLFU EvictionPolicy = "lfu"
+)
+
+// entryOverhead 每个缓存项除键值外的估算开销（map 槽位、CacheItem 与淘汰索引）
+const entryOverhead = 96
+
+type entry struct {
+	key  string
+	item CacheItem
+	size int64
+
+	// LRU
+	element *list.Element
+	// LFU
+	freq  uint64
+	tick  uint64
+	index int
+}
+
+type evictor interface {
+	add(e *entry)
+	touch(e *entry)
+	remove(e *entry)
+	// victim 返回下一个应淘汰的项，没有时返回 nil
+	victim() *entry
+}
+
+func newEvictor(policy EvictionPolicy) (evictor, error) {
+	switch policy {
+	case EvictionLRU, "":
+		return &lruEvictor{order: list.New()}, nil
+	case EvictionLFU:
+		return &lfuEvictor{}, nil
+	default:
+		return nil, fmt.Errorf("unknown eviction policy: %s", policy)
+	}
+}
+
+// lruEvictor 链表头部为最近访问的项
+type lruEvictor struct {
+	order *list.List
+}
+
+func (l *lruEvictor) add(e *entry) {
+	e.element = l.order.PushFront(e)
+}
+
+func (l *lruEvictor) touch(e *entry) {
+	l.order.MoveToFront(e.element)
+}
+
+func (l *lruEvictor) remove(e *entry) {
+	l.order.Remove(e.element)
+	e.element = nil
+}
+
+func (l *lruEvictor) victim() *entry {
+	back := l.order.Back()
+	if back == nil {
+		return nil
+	}
+	return back.Value.(*entry)
+}
+
+// lfuEvictor 按访问次数的最小堆，次数相同时淘汰较久未访问的项
+type lfuEvictor struct {
+	entries lfuHeap
+	clock   uint64
+}
+
+func (l *lfuEvictor) add(e *entry) {
+	l.clock++
+	e.freq = 1
+	e.tick = l.clock
+	heap.Push(&l.entries, e)
+}
+
+func (l *lfuEvictor) touch(e *entry) {
+	l.clock++
+	e.freq++
+	e.tick = l.clock
+	heap.Fix(&l.entries, e.index)
+}
+
+func (l *lfuEvictor) remove(e *entry) {
+	heap.Remove(&l.entries, e.index)
+}
+
+func (l *lfuEvictor) victim() *entry {
+	if len(l.entries) == 0 {
+		return nil
+	}
+	return l.entries[0]
+}
+
+type lfuHeap []*entry
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].tick < h[j].tick
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x any) {
+	e := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *lfuHeap) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.index = -1
+	*h = old[:n-1]
+	return e
+}
+
+// estimateSize 估算缓存项占用的字节数。CacheService 写入的多为 JSON 字符串，
+// 其余类型按 JSON 编码长度近似。
+func estimateSize(key string, value interface{}) int64 {
+	size := int64(entryOverhead + len(key))
+
+	switch v := value.(type) {
+	case string:
+		size += int64(len(v))
+	case []byte:
+		size += int64(len(v))
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		size += 8
+	case nil:
+	default:
+		if data, err := json.Marshal(v); err == nil {
+			size += int64(len(data))
+		} else {
+			size += 256
+		}
+	}
+	return size
+}
@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ Cache = (*RedisCache)(nil)
+
+// 仅在键没有过期时间时设置，保持与 InMemoryCache.Increment 一致：过期时间从创建起算
+var incrementScript = redis.NewScript(`
+local value = redis.call("INCRBY", KEYS[1], ARGV[1])
+if tonumber(ARGV[2]) > 0 and redis.call("PTTL", KEYS[1]) == -1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return value`)
+
+var compareAndDeleteScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// RedisCache 基于 Redis 的缓存，多个实例共享会话与连接信息。
+//
+// Redis 只保存字符串：字符串原样写入，其他值编码为 JSON，读取时一律返回字符串，
+// 结构化数据请使用 CacheService 的 SetJSON/GetJSON。命中统计按进程计算。
+type RedisCache struct {
+	client *redis.Client
+	prefix string
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+func NewRedisCache(client *redis.Client, prefix string) *RedisCache {
+	return &RedisCache{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (c *RedisCache) Set(key string, value interface{}, ttl time.Duration) error {
+	data, err := redisValue(value)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(context.Background(), c.prefix+key, data, ttl).Err()
+}
+
+func (c *RedisCache) Get(key string) (interface{}, bool) {
+	value, err := c.client.Get(context.Background(), c.prefix+key).Result()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			slog.Error("redis cache get failed", "error", err, "key", key)
+		}
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	c.hits.Add(1)
+	return value, true
+}
+
+func (c *RedisCache) Delete(key string) bool {
+	deleted, err := c.client.Del(context.Background(), c.prefix+key).Result()
+	if err != nil {
+		slog.Error("redis cache delete failed", "error", err, "key", key)
+		return false
+	}
+	return deleted > 0
+}
+
+func (c *RedisCache) SetNX(key string, value interface{}, ttl time.Duration) (bool, error) {
+	data, err := redisValue(value)
+	if err != nil {
+		return false, err
+	}
+	return c.client.SetNX(context.Background(), c.prefix+key, data, ttl).Result()
+}
+
+func (c *RedisCache) Increment(key string, delta int64, ttl time.Duration) (int64, error) {
+	return incrementScript.Run(context.Background(), c.client, []string{c.prefix + key},
+		delta, ttl.Milliseconds()).Int64()
+}
+
+func (c *RedisCache) CompareAndDelete(key string, value string) (bool, error) {
+	deleted, err := compareAndDeleteScript.Run(context.Background(), c.client, []string{c.prefix + key}, value).Int()
+	return deleted > 0, err
+}
+
+// Clear 删除当前前缀下的所有键
+func (c *RedisCache) Clear() {
+	ctx := context.Background()
+	iter := c.client.Scan(ctx, 0, c.prefix+"*", 100).Iterator()
+
+	keys := make([]string, 0, 100)
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == cap(keys) {
+			c.client.Del(ctx, keys...)
+			keys = keys[:0]
+		}
+	}
+	if len(keys) > 0 {
+		c.client.Del(ctx, keys...)
+	}
+	if err := iter.Err(); err != nil {
+		slog.Error("redis cache clear failed", "error", err)
+	}
+
+	c.hits.Store(0)
+	c.misses.Store(0)
+}
+
+func (c *RedisCache) GetStats() CacheStats {
+	hits, misses := c.hits.Load(), c.misses.Load()
+
+	hitRate := float64(0)
+	if total := hits + misses; total > 0 {
+		hitRate = float64(hits) / float64(total)
+	}
+
+	return CacheStats{
+		Hits:    hits,
+		Misses:  misses,
+		HitRate: hitRate,
+	}
+}
+
+func redisValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string, []byte, int, int64, float64, bool:
+		return v, nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode cache value: %w", err)
+		}
+		return data, nil
+	}
+}
@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisCache(t *testing.T) (*RedisCache, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisCache(client, "test:"), mr
+}
+
+func TestRedisCache_SetGetDelete(t *testing.T) {
+	c, mr := newTestRedisCache(t)
+
+	if err := c.Set("k", "v", time.Minute); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if value, ok := c.Get("k"); !ok || value != "v" {
+		t.Errorf("Expected v, got %v (%v)", value, ok)
+	}
+	if got, _ := mr.Get("test:k"); got != "v" {
+		t.Errorf("Expected key to be stored with prefix, got %q", got)
+	}
+
+	mr.FastForward(2 * time.Minute)
+	if _, ok := c.Get("k"); ok {
+		t.Error("Expected key to expire")
+	}
+
+	c.Set("k", "v", time.Minute)
+	if !c.Delete("k") || c.Delete("k") {
+		t.Error("Expected delete to report existence once")
+	}
+
+	stats := c.GetStats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.HitRate != 0.5 {
+		t.Errorf("Expected 1 hit and 1 miss, got %+v", stats)
+	}
+}
+
+func TestRedisCache_SharedAcrossInstances(t *testing.T) {
+	first, mr := newTestRedisCache(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	second := NewRedisCache(client, "test:")
+
+	firstSvc := NewCacheService(first, DefaultTTLs())
+	secondSvc := NewCacheService(second, DefaultTTLs())
+
+	type connection struct {
+		GameId string
+		Online bool
+	}
+	if err := firstSvc.SetJSON("connection:p1", connection{GameId: "g1", Online: true}, 0); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var got connection
+	if found, err := secondSvc.GetJSON("connection:p1", &got); !found || err != nil {
+		t.Fatalf("Expected JSON visible from another instance, got %v (%v)", found, err)
+	}
+	if got.GameId != "g1" || !got.Online {
+		t.Errorf("Unexpected value %+v", got)
+	}
+
+	if ttl := mr.TTL("test:connection:p1"); ttl != time.Hour {
+		t.Errorf("Expected default TTL of 1h, got %v", ttl)
+	}
+}
+
+func TestRedisCache_AtomicOperations(t *testing.T) {
+	c, mr := newTestRedisCache(t)
+	svc := NewCacheService(c, DefaultTTLs())
+
+	for i := int64(1); i <= 3; i++ {
+		value, err := svc.Increment("logins", 1, time.Minute)
+		if err != nil || value != i {
+			t.Fatalf("Expected %d, got %d (%v)", i, value, err)
+		}
+	}
+	if ttl := mr.TTL("test:counter:logins"); ttl != time.Minute {
+		t.Errorf("Expected counter TTL 1m, got %v", ttl)
+	}
+
+	if locked, err := svc.TryLock("game:g1", "game-1", time.Minute); err != nil || !locked {
+		t.Fatalf("Expected first lock to succeed, got %v (%v)", locked, err)
+	}
+	if locked, _ := svc.TryLock("game:g1", "game-2", time.Minute); locked {
+		t.Error("Expected second lock to fail")
+	}
+	if released, _ := svc.Unlock("game:g1", "game-2"); released {
+		t.Error("Expected unlock by non-owner to fail")
+	}
+	if released, _ := svc.Unlock("game:g1", "game-1"); !released {
+		t.Error("Expected owner to release the lock")
+	}
+	if locked, _ := svc.TryLock("game:g1", "game-2", time.Minute); !locked {
+		t.Error("Expected lock to be available after release")
+	}
+}
+
+func TestRedisCache_Clear(t *testing.T) {
+	c, mr := newTestRedisCache(t)
+	mr.Set("other:k", "keep")
+
+	for _, key := range []string{"a", "b", "c"} {
+		c.Set(key, key, time.Minute)
+	}
+	c.Clear()
+
+	for _, key := range []string{"a", "b", "c"} {
+		if _, ok := c.Get(key); ok {
+			t.Errorf("Expected %s to be cleared", key)
+		}
+	}
+	if !mr.Exists("other:k") {
+		t.Error("Expected keys outside the prefix to be kept")
+	}
+}
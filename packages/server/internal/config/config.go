@@ -5,16 +5,21 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type Config struct {
-	Server   ServerConfig   `json:"server"`
-	Auth     AuthConfig     `json:"auth"`
-	Cache    CacheConfig    `json:"cache"`
-	Game     GameConfig     `json:"game"`
-	Database DatabaseConfig `json:"database"`
-	Logging  LoggingConfig  `json:"logging"`
+	Server    ServerConfig    `json:"server"`
+	Auth      AuthConfig      `json:"auth"`
+	Cache     CacheConfig     `json:"cache"`
+	Game      GameConfig      `json:"game"`
+	WebSocket WebSocketConfig `json:"websocket"`
+	Queue     QueueConfig     `json:"queue"`
+	Redis     RedisConfig     `json:"redis"`
+	Database  DatabaseConfig  `json:"database"`
+	Logging   LoggingConfig   `json:"logging"`
+	Metrics   MetricsConfig   `json:"metrics"`
 }
 
 type ServerConfig struct {
@@ -23,20 +28,39 @@ type ServerConfig struct {
 	ReadTimeout  Duration `json:"readTimeout"`
 	WriteTimeout Duration `json:"writeTimeout"`
 	StaticDir    string   `json:"staticDir"`
+	// Role 部署角色：all 单进程；websocket 只接入客户端；game 只承载对局。拆分部署需使用 redis 队列
+	Role string `json:"role"`
+	// ShutdownTimeout 停机时等待连接关闭与请求完成的最长时间
+	ShutdownTimeout Duration `json:"shutdownTimeout"`
 }
 
 type AuthConfig struct {
 	JWTSecret      string   `json:"jwtSecret"`
 	TokenExpiry    Duration `json:"tokenExpiry"`
+	RefreshExpiry  Duration `json:"refreshExpiry"`
 	BCryptCost     int      `json:"bcryptCost"`
 	RateLimitRPS   int      `json:"rateLimitRPS"`
 	RateLimitBurst int      `json:"rateLimitBurst"`
+	TrustedProxies []string `json:"trustedProxies"`
+	WSTicketTTL    Duration `json:"wsTicketTTL"`
+	// AdminUsers 登录时自动授予管理员角色的用户名
+	AdminUsers []string `json:"adminUsers"`
 }
 
 type CacheConfig struct {
+	// Type 为 memory 或 redis，redis 使用 redis 配置节的连接参数
+	Type            string   `json:"type"`
+	Prefix          string   `json:"prefix"`
 	CleanupInterval Duration `json:"cleanupInterval"`
 	DefaultTTL      Duration `json:"defaultTTL"`
-	MaxMemoryMB     int      `json:"maxMemoryMB"`
+	// MaxMemoryMB 为 0 时不限制
+	MaxMemoryMB int `json:"maxMemoryMB"`
+	// EvictionPolicy 为 lru 或 lfu
+	EvictionPolicy string `json:"evictionPolicy"`
+	// 各命名空间的过期时间，为 0 时使用 DefaultTTL
+	SessionTTL    Duration `json:"sessionTTL"`
+	GameStateTTL  Duration `json:"gameStateTTL"`
+	ConnectionTTL Duration `json:"connectionTTL"`
 }
 
 type GameConfig struct {
@@ -46,6 +70,57 @@ type GameConfig struct {
 	ReconnectTimeout    Duration `json:"reconnectTimeout"`
 	HeartbeatInterval   Duration `json:"heartbeatInterval"`
 	MatchmakingInterval Duration `json:"matchmakingInterval"`
+	MapsDir             string   `json:"mapsDir"`
+	// EventHistorySize 每个对局保留的最近广播事件数，用于断线重连补发
+	EventHistorySize int `json:"eventHistorySize"`
+	// MaxSpectators 每个对局的观战人数上限，为 0 时不允许观战
+	MaxSpectators int `json:"maxSpectators"`
+	// SpectatorDelay 观战事件流的延迟，防止观战者向玩家透露视野外的信息
+	SpectatorDelay Duration `json:"spectatorDelay"`
+}
+
+type WebSocketConfig struct {
+	MaxMessageSize int64    `json:"maxMessageSize"`
+	ReadTimeout    Duration `json:"readTimeout"`
+	MessageRPS     float64  `json:"messageRPS"`
+	MessageBurst   int      `json:"messageBurst"`
+	PlayerRPS      float64  `json:"playerRPS"`
+	PlayerBurst    int      `json:"playerBurst"`
+	MaxViolations  int      `json:"maxViolations"`
+	// ChatMaxLength 单条聊天消息的最大字符数
+	ChatMaxLength int `json:"chatMaxLength"`
+	// ChatRPS 与 ChatBurst 为每个用户的聊天令牌桶，ChatRPS 为 0 时不限制
+	ChatRPS   float64 `json:"chatRPS"`
+	ChatBurst int     `json:"chatBurst"`
+	// ChatBlockedWords 聊天中被替换为星号的词，不区分大小写
+	ChatBlockedWords []string `json:"chatBlockedWords"`
+}
+
+type QueueConfig struct {
+	// Type 为 memory 或 redis
+	Type   string `json:"type"`
+	Prefix string `json:"prefix"`
+	// InstanceID 为空时按主机名与随机后缀生成
+	InstanceID string   `json:"instanceId"`
+	ClaimTTL   Duration `json:"claimTTL"`
+	// Backpressure 仅对内存队列生效
+	Backpressure BackpressureConfig `json:"backpressure"`
+}
+
+// BackpressureConfig 订阅缓冲区满时的策略：dropNewest、dropOldest、block 或 disconnect。
+// 指令主题单独配置，BlockTimeout 为 0 表示一直等待。
+type BackpressureConfig struct {
+	BufferSize          int      `json:"bufferSize"`
+	Policy              string   `json:"policy"`
+	BlockTimeout        Duration `json:"blockTimeout"`
+	CommandPolicy       string   `json:"commandPolicy"`
+	CommandBlockTimeout Duration `json:"commandBlockTimeout"`
+}
+
+type RedisConfig struct {
+	Addr     string `json:"addr"`
+	Password string `json:"password"`
+	DB       int    `json:"db"`
 }
 
 type DatabaseConfig struct {
@@ -60,6 +135,12 @@ type DatabaseConfig struct {
 	SSLMode      string `json:"sslMode"`
 }
 
+// MetricsConfig Prometheus 指标端点
+type MetricsConfig struct {
+	Enabled bool   `json:"enabled"`
+	Path    string `json:"path"`
+}
+
 type LoggingConfig struct {
 	Level       string `json:"level"`
 	Format      string `json:"format"`
@@ -74,23 +155,35 @@ type LoggingConfig struct {
 func DefaultConfig() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Host:         "0.0.0.0",
-			Port:         8080,
-			ReadTimeout:  Duration(15 * time.Second),
-			WriteTimeout: Duration(15 * time.Second),
-			StaticDir:    "./static",
+			Host:            "0.0.0.0",
+			Port:            8080,
+			ReadTimeout:     Duration(15 * time.Second),
+			WriteTimeout:    Duration(15 * time.Second),
+			StaticDir:       "./static",
+			Role:            "all",
+			ShutdownTimeout: Duration(15 * time.Second),
 		},
 		Auth: AuthConfig{
 			JWTSecret:      "your-secret-key-change-this-in-production",
-			TokenExpiry:    Duration(24 * time.Hour),
+			TokenExpiry:    Duration(15 * time.Minute),
+			RefreshExpiry:  Duration(30 * 24 * time.Hour),
 			BCryptCost:     12,
 			RateLimitRPS:   10,
 			RateLimitBurst: 20,
+			TrustedProxies: []string{"127.0.0.1", "::1"},
+			WSTicketTTL:    Duration(30 * time.Second),
+			AdminUsers:     []string{},
 		},
 		Cache: CacheConfig{
+			Type:            "memory",
+			Prefix:          "slareneg:cache:",
 			CleanupInterval: Duration(10 * time.Minute),
 			DefaultTTL:      Duration(1 * time.Hour),
 			MaxMemoryMB:     100,
+			EvictionPolicy:  "lru",
+			SessionTTL:      Duration(24 * time.Hour),
+			GameStateTTL:    Duration(2 * time.Hour),
+			ConnectionTTL:   Duration(30 * time.Minute),
 		},
 		Game: GameConfig{
 			MaxRooms:            100,
@@ -99,6 +192,35 @@ func DefaultConfig() *Config {
 			ReconnectTimeout:    Duration(30 * time.Second),
 			HeartbeatInterval:   Duration(30 * time.Second),
 			MatchmakingInterval: Duration(5 * time.Second),
+			MapsDir:             "./maps",
+			EventHistorySize:    256,
+			MaxSpectators:       50,
+			SpectatorDelay:      Duration(10 * time.Second),
+		},
+		WebSocket: WebSocketConfig{
+			MaxMessageSize: 4096,
+			ReadTimeout:    Duration(5 * time.Minute),
+			MessageRPS:     20,
+			MessageBurst:   40,
+			PlayerRPS:      30,
+			PlayerBurst:    60,
+			MaxViolations:  20,
+			ChatMaxLength:  200,
+			ChatRPS:        1,
+			ChatBurst:      5,
+		},
+		Queue: QueueConfig{
+			Type:     "memory",
+			Prefix:   "slareneg:",
+			ClaimTTL: Duration(30 * time.Second),
+			Backpressure: BackpressureConfig{
+				BufferSize:    50,
+				Policy:        "dropNewest",
+				CommandPolicy: "block",
+			},
+		},
+		Redis: RedisConfig{
+			Addr: "localhost:6379",
 		},
 		Database: DatabaseConfig{
 			Type:         "sqlite",
@@ -121,6 +243,10 @@ func DefaultConfig() *Config {
 			Compress:    true,
 			Development: false,
 		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+			Path:    "/metrics",
+		},
 	}
 }
 
@@ -166,6 +292,22 @@ func (c *Config) loadFromEnv() {
 			c.Auth.TokenExpiry = Duration(d)
 		}
 	}
+	if trustedProxies := os.Getenv("TRUSTED_PROXIES"); trustedProxies != "" {
+		c.Auth.TrustedProxies = strings.Split(trustedProxies, ",")
+	}
+	if adminUsers := os.Getenv("ADMIN_USERS"); adminUsers != "" {
+		c.Auth.AdminUsers = strings.Split(adminUsers, ",")
+	}
+	if refreshExpiry := os.Getenv("REFRESH_TOKEN_EXPIRY"); refreshExpiry != "" {
+		if d, err := time.ParseDuration(refreshExpiry); err == nil {
+			c.Auth.RefreshExpiry = Duration(d)
+		}
+	}
+	if wsTicketTTL := os.Getenv("WS_TICKET_TTL"); wsTicketTTL != "" {
+		if d, err := time.ParseDuration(wsTicketTTL); err == nil {
+			c.Auth.WSTicketTTL = Duration(d)
+		}
+	}
 
 	if cleanupInterval := os.Getenv("CACHE_CLEANUP_INTERVAL"); cleanupInterval != "" {
 		if d, err := time.ParseDuration(cleanupInterval); err == nil {
@@ -177,6 +319,17 @@ func (c *Config) loadFromEnv() {
 			c.Cache.DefaultTTL = Duration(d)
 		}
 	}
+	if maxMemory := os.Getenv("CACHE_MAX_MEMORY_MB"); maxMemory != "" {
+		if mb, err := strconv.Atoi(maxMemory); err == nil {
+			c.Cache.MaxMemoryMB = mb
+		}
+	}
+	if cacheType := os.Getenv("CACHE_TYPE"); cacheType != "" {
+		c.Cache.Type = cacheType
+	}
+	if policy := os.Getenv("CACHE_EVICTION_POLICY"); policy != "" {
+		c.Cache.EvictionPolicy = policy
+	}
 
 	if maxRooms := os.Getenv("GAME_MAX_ROOMS"); maxRooms != "" {
 		if mr, err := strconv.Atoi(maxRooms); err == nil {
@@ -198,6 +351,48 @@ func (c *Config) loadFromEnv() {
 			c.Game.ReconnectTimeout = Duration(d)
 		}
 	}
+	if heartbeatInterval := os.Getenv("GAME_HEARTBEAT_INTERVAL"); heartbeatInterval != "" {
+		if d, err := time.ParseDuration(heartbeatInterval); err == nil {
+			c.Game.HeartbeatInterval = Duration(d)
+		}
+	}
+	if maxSpectators := os.Getenv("GAME_MAX_SPECTATORS"); maxSpectators != "" {
+		if ms, err := strconv.Atoi(maxSpectators); err == nil {
+			c.Game.MaxSpectators = ms
+		}
+	}
+	if spectatorDelay := os.Getenv("GAME_SPECTATOR_DELAY"); spectatorDelay != "" {
+		if d, err := time.ParseDuration(spectatorDelay); err == nil {
+			c.Game.SpectatorDelay = Duration(d)
+		}
+	}
+	if mapsDir := os.Getenv("GAME_MAPS_DIR"); mapsDir != "" {
+		c.Game.MapsDir = mapsDir
+	}
+
+	if maxMessageSize := os.Getenv("WS_MAX_MESSAGE_SIZE"); maxMessageSize != "" {
+		if size, err := strconv.ParseInt(maxMessageSize, 10, 64); err == nil {
+			c.WebSocket.MaxMessageSize = size
+		}
+	}
+	if messageRPS := os.Getenv("WS_MESSAGE_RPS"); messageRPS != "" {
+		if rps, err := strconv.ParseFloat(messageRPS, 64); err == nil {
+			c.WebSocket.MessageRPS = rps
+		}
+	}
+	if chatMaxLength := os.Getenv("WS_CHAT_MAX_LENGTH"); chatMaxLength != "" {
+		if n, err := strconv.Atoi(chatMaxLength); err == nil {
+			c.WebSocket.ChatMaxLength = n
+		}
+	}
+	if chatRPS := os.Getenv("WS_CHAT_RPS"); chatRPS != "" {
+		if rps, err := strconv.ParseFloat(chatRPS, 64); err == nil {
+			c.WebSocket.ChatRPS = rps
+		}
+	}
+	if blockedWords := os.Getenv("WS_CHAT_BLOCKED_WORDS"); blockedWords != "" {
+		c.WebSocket.ChatBlockedWords = strings.Split(blockedWords, ",")
+	}
 
 	if dbType := os.Getenv("DB_TYPE"); dbType != "" {
 		c.Database.Type = dbType
@@ -220,6 +415,27 @@ func (c *Config) loadFromEnv() {
 		c.Database.Password = dbPass
 	}
 
+	if role := os.Getenv("SERVER_ROLE"); role != "" {
+		c.Server.Role = role
+	}
+	if shutdownTimeout := os.Getenv("SHUTDOWN_TIMEOUT"); shutdownTimeout != "" {
+		if d, err := time.ParseDuration(shutdownTimeout); err == nil {
+			c.Server.ShutdownTimeout = Duration(d)
+		}
+	}
+	if queueType := os.Getenv("QUEUE_TYPE"); queueType != "" {
+		c.Queue.Type = queueType
+	}
+	if instanceID := os.Getenv("INSTANCE_ID"); instanceID != "" {
+		c.Queue.InstanceID = instanceID
+	}
+	if redisAddr := os.Getenv("REDIS_ADDR"); redisAddr != "" {
+		c.Redis.Addr = redisAddr
+	}
+	if redisPass := os.Getenv("REDIS_PASSWORD"); redisPass != "" {
+		c.Redis.Password = redisPass
+	}
+
 	if logLevel := os.Getenv("LOG_LEVEL"); logLevel != "" {
 		c.Logging.Level = logLevel
 	}
@@ -232,6 +448,10 @@ func (c *Config) loadFromEnv() {
 	if dev := os.Getenv("LOG_DEVELOPMENT"); dev != "" {
 		c.Logging.Development = dev == "true"
 	}
+
+	if enabled := os.Getenv("METRICS_ENABLED"); enabled != "" {
+		c.Metrics.Enabled = enabled == "true"
+	}
 }
 
 func (c *Config) SaveConfig(configPath string) error {
@@ -271,9 +491,87 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("max players per room must be positive")
 	}
 
+	if c.Game.ReconnectTimeout <= 0 {
+		return fmt.Errorf("game reconnect timeout must be positive")
+	}
+	if c.Game.HeartbeatInterval < 0 {
+		return fmt.Errorf("game heartbeat interval must not be negative")
+	}
+	if c.Game.EventHistorySize < 0 {
+		return fmt.Errorf("game event history size must not be negative")
+	}
+	if c.Game.MaxSpectators < 0 {
+		return fmt.Errorf("game max spectators must not be negative")
+	}
+	if c.Game.SpectatorDelay < 0 {
+		return fmt.Errorf("game spectator delay must not be negative")
+	}
+
+	if c.WebSocket.ChatMaxLength <= 0 {
+		return fmt.Errorf("websocket chat max length must be positive")
+	}
+	if c.WebSocket.ChatRPS < 0 {
+		return fmt.Errorf("websocket chat rps must not be negative")
+	}
+
+	switch c.Cache.Type {
+	case "memory", "redis":
+	default:
+		return fmt.Errorf("unknown cache type: %s", c.Cache.Type)
+	}
+	if c.Cache.MaxMemoryMB < 0 {
+		return fmt.Errorf("cache max memory must not be negative")
+	}
+	switch c.Cache.EvictionPolicy {
+	case "lru", "lfu":
+	default:
+		return fmt.Errorf("unknown cache eviction policy: %s", c.Cache.EvictionPolicy)
+	}
+
+	switch c.Queue.Type {
+	case "memory", "redis":
+	default:
+		return fmt.Errorf("unknown queue type: %s", c.Queue.Type)
+	}
+
+	if c.Queue.Backpressure.BufferSize <= 0 {
+		return fmt.Errorf("queue buffer size must be positive")
+	}
+	for _, policy := range []string{c.Queue.Backpressure.Policy, c.Queue.Backpressure.CommandPolicy} {
+		switch policy {
+		case "dropNewest", "dropOldest", "block", "disconnect":
+		default:
+			return fmt.Errorf("unknown backpressure policy: %s", policy)
+		}
+	}
+
+	if c.Metrics.Enabled && !strings.HasPrefix(c.Metrics.Path, "/") {
+		return fmt.Errorf("metrics path must start with /")
+	}
+
+	switch c.Server.Role {
+	case "all":
+	case "websocket", "game":
+		if c.Queue.Type != "redis" {
+			return fmt.Errorf("server role %s requires the redis queue", c.Server.Role)
+		}
+	default:
+		return fmt.Errorf("unknown server role: %s", c.Server.Role)
+	}
+
 	return nil
 }
 
+// RunsGames 当前实例是否承载对局
+func (c *Config) RunsGames() bool {
+	return c.Server.Role == "all" || c.Server.Role == "game"
+}
+
+// ServesWebSocket 当前实例是否接入 WebSocket 客户端
+func (c *Config) ServesWebSocket() bool {
+	return c.Server.Role == "all" || c.Server.Role == "websocket"
+}
+
 func (c *Config) GetServerAddr() string {
 	return fmt.Sprintf("%s:%d", c.Server.Host, c.Server.Port)
 }
@@ -7,11 +7,32 @@ import (
 	"log/slog"
 	"server/internal/game/block"
 	gamemap "server/internal/game/map"
+	"server/internal/metrics"
 	"server/internal/queue"
 	"sync"
 	"time"
 )
 
+// Move 的拒绝原因，可通过 errors.Is 判断
+var (
+	ErrNotInProgress   = errors.New("cannot move in status")
+	ErrPlayerNotFound  = errors.New("player not found")
+	ErrCannotOperate   = errors.New("player cannot operate")
+	ErrNoMovesLeft     = errors.New("player has no moves left")
+	ErrInvalidPosition = errors.New("invalid position")
+	ErrNotOwner        = errors.New("not the owner of the block at position")
+	ErrInvalidTroops   = errors.New("invalid number of blocks to move")
+	ErrNotEnoughTroops = errors.New("not enough blocks to move")
+	ErrMoveNotAllowed  = errors.New("move not allowed")
+	ErrMoveRejected    = errors.New("move rejected by target block")
+)
+
+// Spectate 的拒绝原因
+var (
+	ErrSpectatorLimit    = errors.New("spectator limit reached")
+	ErrAlreadySpectating = errors.New("already spectating")
+)
+
 // BaseCore 纯粹的游戏逻辑层，无外部依赖，提供标准化的事件返回接口
 type BaseCore struct {
 	gameId     string
@@ -33,6 +54,17 @@ type BaseCore struct {
 
 	// 地图管理器
 	mapManager gamemap.MapManager
+	mapId      string
+
+	// reconnectTimeout 断线玩家的重连期限，超时后转为观战
+	reconnectTimeout time.Duration
+
+	// spectators 观战者，与 players 分开保存以免占用 owner 索引
+	spectators    []Spectator
+	maxSpectators int
+
+	// room 私人房间的房主、密码与移出记录
+	room room
 }
 
 // NewBaseCore 创建新的BaseCore实例
@@ -47,6 +79,9 @@ func NewBaseCore(gameId string, mode GameMode, mapManager gamemap.MapManager) *B
 		turnNumber: 0,
 		mode:       mode,
 		mapManager: mapManager,
+
+		reconnectTimeout: DefaultReconnectTimeout,
+		maxSpectators:    DefaultMaxSpectators,
 	}
 }
 
@@ -59,6 +94,30 @@ func (gc *BaseCore) SetEventHandlers(
 	gc.onControlEvent = onControl
 }
 
+// SetMapId 指定对局使用的地图，为空时使用默认生成器。未知的地图 id 在此拒绝，而不是等到开局时才失败
+func (gc *BaseCore) SetMapId(mapId string) error {
+	if gc.status != StatusWaiting {
+		return fmt.Errorf("cannot change map in status: %s", gc.status)
+	}
+	if mapId != "" {
+		if err := gc.mapManager.ValidateMapId(mapId); err != nil {
+			return fmt.Errorf("invalid map %s: %w", mapId, err)
+		}
+	}
+	gc.mapId = mapId
+	return nil
+}
+
+// SetReconnectTimeout 设置断线玩家的重连期限
+func (gc *BaseCore) SetReconnectTimeout(timeout time.Duration) {
+	gc.reconnectTimeout = timeout
+}
+
+// SetMaxSpectators 设置观战人数上限，为 0 时不允许观战
+func (gc *BaseCore) SetMaxSpectators(limit int) {
+	gc.maxSpectators = limit
+}
+
 // =============================================================================
 // 实现Core接口
 // =============================================================================
@@ -101,6 +160,9 @@ func (gc *BaseCore) Join(player Player) error {
 	if existingPlayer != nil {
 		return errors.New("player already exists: " + player.Id)
 	}
+	if gc.findSpectatorIndex(player.Id) >= 0 {
+		return fmt.Errorf("%w: %s", ErrAlreadySpectating, player.Id)
+	}
 
 	player.Status = PlayerStatusWaiting
 	gc.players = append(gc.players, player)
@@ -125,6 +187,9 @@ func (gc *BaseCore) Leave(playerId string) error {
 	} else {
 		gc.players = append(gc.players[:i], gc.players[i+1:]...)
 		slog.Info("player left before game start", "player", playerId, "gameId", gc.gameId)
+		if gc.transferHostOnLeave(playerId) {
+			slog.Info("host left, transferred", "to", gc.room.host, "gameId", gc.gameId)
+		}
 	}
 
 	return nil
@@ -139,6 +204,61 @@ func (gc *BaseCore) GetPlayer(playerId string) (*Player, error) {
 	return &playerCopy, nil
 }
 
+func (gc *BaseCore) Spectators() []Spectator {
+	return gc.spectators
+}
+
+// Spectate 加入观战，对局的玩家不能同时观战，已结束的对局不接受观战
+func (gc *BaseCore) Spectate(spectator Spectator) error {
+	if gc.status == StatusFinished {
+		return fmt.Errorf("cannot spectate game in status: %s", gc.status)
+	}
+	if _, player := gc.findPlayerIndex(spectator.Id); player != nil {
+		return fmt.Errorf("player cannot spectate own game: %s", spectator.Id)
+	}
+	if gc.findSpectatorIndex(spectator.Id) >= 0 {
+		return fmt.Errorf("%w: %s", ErrAlreadySpectating, spectator.Id)
+	}
+	if len(gc.spectators) >= gc.maxSpectators {
+		return fmt.Errorf("%w: %d", ErrSpectatorLimit, gc.maxSpectators)
+	}
+
+	gc.spectators = append(gc.spectators, spectator)
+	slog.Info("spectator joined", "spectator", spectator.Id, "gameId", gc.gameId)
+	return nil
+}
+
+func (gc *BaseCore) StopSpectating(spectatorId string) error {
+	i := gc.findSpectatorIndex(spectatorId)
+	if i < 0 {
+		return errors.New("spectator not found: " + spectatorId)
+	}
+
+	gc.spectators = append(gc.spectators[:i], gc.spectators[i+1:]...)
+	slog.Info("spectator left", "spectator", spectatorId, "gameId", gc.gameId)
+	return nil
+}
+
+// Teammates 返回与玩家同队的玩家 ID（包括自己）。玩家按加入顺序每 TeamSize 人一队
+func (gc *BaseCore) Teammates(playerId string) ([]string, error) {
+	i, player := gc.findPlayerIndex(playerId)
+	if player == nil {
+		return nil, fmt.Errorf("%w: %s", ErrPlayerNotFound, playerId)
+	}
+	if gc.mode.TeamSize <= 1 {
+		return nil, fmt.Errorf("mode %s has no teams", gc.mode.Name)
+	}
+
+	team := i / int(gc.mode.TeamSize)
+	var teammates []string
+	for j, p := range gc.players {
+		if j/int(gc.mode.TeamSize) == team {
+			teammates = append(teammates, p.Id)
+		}
+	}
+	return teammates, nil
+}
+
 func (gc *BaseCore) Map() gamemap.Map {
 	if gc._map == nil {
 		slog.Error("map is not initialized", "gameId", gc.gameId)
@@ -147,6 +267,29 @@ func (gc *BaseCore) Map() gamemap.Map {
 	return gc._map
 }
 
+// PlayerMap 返回玩家视角的地图副本：对局中的玩家只能看到己方格子及其相邻格子，
+// 已结束的玩家可以看到完整地图。地图尚未生成时返回 nil。
+func (gc *BaseCore) PlayerMap(playerId string) (gamemap.Map, error) {
+	i, player := gc.findPlayerIndex(playerId)
+	if player == nil {
+		return nil, fmt.Errorf("player not found: %s", playerId)
+	}
+	if gc._map == nil || gc._map.IsEmpty() {
+		return nil, nil
+	}
+
+	view := gamemap.NewSnapshot(gc._map).Map()
+	if player.IsFinished() {
+		return view, nil
+	}
+
+	owner := block.Owner(i)
+	if err := view.Fog([]block.Owner{owner}, playerSight(gc._map, owner)); err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
 func (gc *BaseCore) Start() error {
 	if gc.status != StatusWaiting {
 		return fmt.Errorf("cannot start game in status: %s", gc.status)
@@ -179,6 +322,8 @@ func (gc *BaseCore) Start() error {
 	return nil
 }
 
+// Stop 停止对局。进行中的对局没有胜者，仍在对局中的玩家标记为 finished，
+// 并广播 GameEndedEvent，客户端据此收到最终结果
 func (gc *BaseCore) Stop() error {
 	gc.stopTurnTimer()
 
@@ -187,8 +332,26 @@ func (gc *BaseCore) Stop() error {
 		gc.cancel = nil
 	}
 
+	wasInProgress := gc.status == StatusInProgress
 	gc.status = StatusFinished
+	if !wasInProgress {
+		return nil
+	}
+
+	for i := range gc.players {
+		if gc.players[i].IsActive() {
+			gc.players[i].Status = PlayerStatusFinished
+		}
+	}
+	if gc.onBroadcastEvent != nil {
+		gc.onBroadcastEvent(GameEndedEvent{
+			BroadcastEvent: BroadcastEvent{},
+			GameStatus:     gc.status,
+			Players:        gc.players,
+		})
+	}
 
+	slog.Info("game stopped before finishing", "gameId", gc.gameId)
 	return nil
 }
 
@@ -221,6 +384,8 @@ func (gc *BaseCore) NextTurn(turnNumber uint16) error {
 		return nil
 	}
 
+	gc.startTurnTimer()
+
 	if gc.onBroadcastEvent != nil {
 		gc.onBroadcastEvent(TurnStartedEvent{
 			BroadcastEvent: BroadcastEvent{},
@@ -235,18 +400,18 @@ func (gc *BaseCore) NextTurn(turnNumber uint16) error {
 
 func (gc *BaseCore) Move(playerID string, move Move) error {
 	if gc.status != StatusInProgress {
-		return fmt.Errorf("cannot move in status: %s", gc.status)
+		return fmt.Errorf("%w: %s", ErrNotInProgress, gc.status)
 	}
 
 	playerIndex, player := gc.findPlayerIndex(playerID)
 	if player == nil {
-		return fmt.Errorf("player not found: %s", playerID)
+		return fmt.Errorf("%w: %s", ErrPlayerNotFound, playerID)
 	}
 	if !player.CanOperate() {
-		return fmt.Errorf("player cannot operate (status: %s)", player.Status)
+		return fmt.Errorf("%w (status: %s)", ErrCannotOperate, player.Status)
 	}
 	if player.Moves == 0 {
-		return fmt.Errorf("player has no moves left: %s", playerID)
+		return fmt.Errorf("%w: %s", ErrNoMovesLeft, playerID)
 	}
 
 	offset := getMoveOffset(move.Towards)
@@ -256,10 +421,10 @@ func (gc *BaseCore) Move(playerID string, move Move) error {
 	}
 
 	if !gc._map.Size().IsPosValid(move.Pos) {
-		return errors.New("invalid position: " + move.Pos.String())
+		return fmt.Errorf("%w: %s", ErrInvalidPosition, move.Pos)
 	}
 	if !gc._map.Size().IsPosValid(newPos) {
-		return errors.New("invalid position: " + newPos.String())
+		return fmt.Errorf("%w: %s", ErrInvalidPosition, newPos)
 	}
 
 	fromBlock, err := gc._map.Block(move.Pos)
@@ -273,7 +438,7 @@ func (gc *BaseCore) Move(playerID string, move Move) error {
 
 	owner := uint16(playerIndex)
 	if fromBlock.Owner() != block.Owner(owner) {
-		return errors.New("not the owner of the block at position: " + move.Pos.String())
+		return fmt.Errorf("%w: %s", ErrNotOwner, move.Pos)
 	}
 
 	if move.Num == 0 {
@@ -283,22 +448,22 @@ func (gc *BaseCore) Move(playerID string, move Move) error {
 	}
 
 	if move.Num <= 0 {
-		return fmt.Errorf("invalid number of blocks to move: %d", move.Num)
+		return fmt.Errorf("%w: %d", ErrInvalidTroops, move.Num)
 	}
 
 	if fromBlock.Num() < move.Num {
-		return fmt.Errorf("not enough blocks to move: %d, available: %d", move.Num, fromBlock.Num())
+		return fmt.Errorf("%w: %d, available: %d", ErrNotEnoughTroops, move.Num, fromBlock.Num())
 	}
 
 	if !fromBlock.AllowMove().From || !targetBlock.AllowMove().To {
-		return errors.New("move not allowed from " + move.Pos.String() + " to " + newPos.String())
+		return fmt.Errorf("%w from %s to %s", ErrMoveNotAllowed, move.Pos, newPos)
 	}
 
 	movedNum := fromBlock.MoveFrom(move.Num)
 	targetBlockNew := targetBlock.MoveTo(movedNum, fromBlock.Owner())
 
 	if targetBlockNew == nil {
-		return errors.New("move rejected by target block: " + newPos.String())
+		return fmt.Errorf("%w: %s", ErrMoveRejected, newPos)
 	}
 
 	gc._map.SetBlock(move.Pos, fromBlock)
@@ -383,9 +548,10 @@ func (gc *BaseCore) PlayerDisconnect(playerID string) error {
 		return fmt.Errorf("player not found: %s", playerID)
 	}
 
+	now := time.Now()
 	gc.players[i].Connection.IsConnected = false
-	gc.players[i].Connection.DisconnectedAt = time.Now().UnixMilli()
-	gc.players[i].Connection.ReconnectTimeout = time.Now().UnixMilli() + 30000
+	gc.players[i].Connection.DisconnectedAt = now.UnixMilli()
+	gc.players[i].Connection.ReconnectTimeout = now.Add(gc.reconnectTimeout).UnixMilli()
 
 	if player.Status == PlayerStatusInGame {
 		gc.players[i].Status = PlayerStatusDisconnected
@@ -449,6 +615,32 @@ func (gc *BaseCore) CheckDisconnectedPlayers(currentTimeMs int64) error {
 // 私有方法
 // =============================================================================
 
+// playerSight 己方格子及其周围八格可见
+func playerSight(m gamemap.Map, owner block.Owner) gamemap.Sight {
+	blocks := m.Blocks()
+	sight := make(gamemap.Sight, len(blocks))
+	for y := range blocks {
+		sight[y] = make([]bool, len(blocks[y]))
+	}
+
+	for y, row := range blocks {
+		for x, b := range row {
+			if b == nil || b.Owner() != owner {
+				continue
+			}
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					ny, nx := y+dy, x+dx
+					if ny >= 0 && ny < len(sight) && nx >= 0 && nx < len(sight[ny]) {
+						sight[ny][nx] = true
+					}
+				}
+			}
+		}
+	}
+	return sight
+}
+
 func (gc *BaseCore) findPlayerIndex(playerID string) (int, *Player) {
 	for i, p := range gc.players {
 		if p.Id == playerID {
@@ -458,6 +650,15 @@ func (gc *BaseCore) findPlayerIndex(playerID string) (int, *Player) {
 	return -1, nil
 }
 
+func (gc *BaseCore) findSpectatorIndex(spectatorId string) int {
+	for i := range gc.spectators {
+		if gc.spectators[i].Id == spectatorId {
+			return i
+		}
+	}
+	return -1
+}
+
 func (gc *BaseCore) checkGameTransition() {
 	oldStatus := gc.status
 
@@ -608,6 +809,7 @@ func (gc *BaseCore) autoEndGame() {
 // 定时器相关方法
 // =============================================================================
 
+// startTurnTimer 为下一回合计时，调用方需在事件循环中（持有 coreMu）
 func (gc *BaseCore) startTurnTimer() {
 	gc.timerMutex.Lock()
 	defer gc.timerMutex.Unlock()
@@ -617,7 +819,11 @@ func (gc *BaseCore) startTurnTimer() {
 	}
 
 	interval := gc.mode.GetTurnTime()
-	gc.timer = time.AfterFunc(interval, gc.handleTurnTimeout)
+	deadline := time.Now().Add(interval)
+	nextTurn := gc.turnNumber + 1
+	gc.timer = time.AfterFunc(interval, func() {
+		gc.handleTurnTimeout(deadline, nextTurn)
+	})
 }
 
 func (gc *BaseCore) stopTurnTimer() {
@@ -630,22 +836,19 @@ func (gc *BaseCore) stopTurnTimer() {
 	}
 }
 
-func (gc *BaseCore) handleTurnTimeout() {
-	if gc.status != StatusInProgress {
-		return
-	}
-
-	if err := gc.NextTurn(gc.turnNumber + 1); err != nil {
-		slog.Error("failed to advance turn", "error", err, "gameId", gc.gameId)
-		return
-	}
+// handleTurnTimeout 在定时器 goroutine 上运行，不直接修改对局状态，
+// 而是把 TurnAdvanceControl 发给事件循环，由 NextTurn 推进回合并重新计时
+func (gc *BaseCore) handleTurnTimeout(deadline time.Time, nextTurn uint16) {
+	metrics.TurnTimerLag.Observe(time.Since(deadline).Seconds())
 
-	if gc.isGameOver() {
-		gc.autoEndGame()
+	if gc.onControlEvent == nil {
+		slog.Warn("turn timer fired without control handler", "turn", nextTurn, "gameId", gc.gameId)
 		return
 	}
-
-	gc.startTurnTimer()
+	gc.onControlEvent(TurnAdvanceControl{
+		ControlEvent: ControlEvent{},
+		TurnNumber:   nextTurn,
+	})
 }
 
 func (gc *BaseCore) initializeMap() error {
@@ -660,8 +863,11 @@ func (gc *BaseCore) initializeMap() error {
 		}
 	}
 
-	config := gamemap.DefaultGeneratorConfig()
-	mapId := gc.mapManager.GenerateMapId("base", mapSize, len(players), config)
+	mapId := gc.mapId
+	if mapId == "" {
+		config := gamemap.DefaultGeneratorConfig()
+		mapId = gc.mapManager.GenerateMapId("base", mapSize, len(players), config)
+	}
 
 	generatedMap, err := gc.mapManager.GetMap(mapId, players)
 	if err != nil {
@@ -1,6 +1,7 @@
 package game
 
 import (
+	"errors"
 	"fmt"
 	gamemap "server/internal/game/map"
 	"server/internal/queue"
@@ -440,23 +441,41 @@ func TestBaseCore_TurnTimer(t *testing.T) {
 		core.Join(player1)
 		core.Join(player2)
 
-		var receivedEvents []queue.Event
+		controls := make(chan queue.Event, 4)
 		core.SetEventHandlers(
+			func(event queue.Event) {},
 			func(event queue.Event) {
-				receivedEvents = append(receivedEvents, event)
-			},
-			func(event queue.Event) {
-				receivedEvents = append(receivedEvents, event)
+				controls <- event
 			},
 		)
 
-		core.Start()
-		time.Sleep(time.Millisecond * 200)
-		core.Stop()
+		// 定时器只发送 TurnAdvanceControl，由调用方（事件循环）推进回合
+		expectAdvance := func(turn uint16) {
+			t.Helper()
+			select {
+			case event := <-controls:
+				advance, ok := event.(TurnAdvanceControl)
+				if !ok || advance.TurnNumber != turn {
+					t.Fatalf("Expected TurnAdvanceControl for turn %d, got %#v", turn, event)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("Expected TurnAdvanceControl for turn %d, got none", turn)
+			}
+		}
 
-		if len(receivedEvents) == 0 {
-			t.Error("Expected to receive timer events, got none")
+		if err := core.Start(); err != nil {
+			t.Fatalf("Failed to start game: %v", err)
+		}
+		expectAdvance(1)
+		if core.TurnNumber() != 0 {
+			t.Errorf("Expected timer not to advance the turn itself, got turn %d", core.TurnNumber())
 		}
+
+		if err := core.NextTurn(1); err != nil {
+			t.Fatalf("Failed to advance turn: %v", err)
+		}
+		expectAdvance(2)
+		core.Stop()
 	})
 }
 
@@ -487,7 +506,26 @@ func TestBaseCore_EventHandlers(t *testing.T) {
 		t.Error("Expected broadcast events, got none")
 	}
 
+	// 提前停止的对局广播没有胜者的最终结果
+	core.Stop()
+	ended, ok := broadcastEvents[len(broadcastEvents)-1].(GameEndedEvent)
+	if !ok {
+		t.Fatalf("Expected GameEndedEvent after stopping, got %T", broadcastEvents[len(broadcastEvents)-1])
+	}
+	if ended.Winner != "" || ended.GameStatus != StatusFinished {
+		t.Errorf("Expected finished game without winner, got %+v", ended)
+	}
+	for _, player := range ended.Players {
+		if player.Status != PlayerStatusFinished {
+			t.Errorf("Expected player %s to be finished, got %s", player.Id, player.Status)
+		}
+	}
+
+	count := len(broadcastEvents)
 	core.Stop()
+	if len(broadcastEvents) != count {
+		t.Error("Expected stopping a finished game not to broadcast again")
+	}
 }
 
 func BenchmarkCore_PlayerOperations(b *testing.B) {
@@ -517,3 +555,94 @@ func BenchmarkCore_EventGeneration(b *testing.B) {
 		core.ForceStart("player1", true)
 	}
 }
+
+func TestBaseCore_ConnectionManagement(t *testing.T) {
+	core := NewBaseCore("test-game", TestMode, createTestMapManager())
+	core.SetReconnectTimeout(50 * time.Millisecond)
+	core.Join(Player{Id: "player1", Name: "Player One"})
+	core.Join(Player{Id: "player2", Name: "Player Two"})
+	if err := core.Start(); err != nil {
+		t.Fatalf("Expected no error starting game, got %v", err)
+	}
+	defer core.Stop()
+
+	t.Run("disconnect_and_reconnect", func(t *testing.T) {
+		if err := core.PlayerDisconnect("player2"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		player, _ := core.GetPlayer("player2")
+		if player.Status != PlayerStatusDisconnected {
+			t.Errorf("Expected status %s, got %s", PlayerStatusDisconnected, player.Status)
+		}
+		if window := player.Connection.ReconnectTimeout - player.Connection.DisconnectedAt; window != 50 {
+			t.Errorf("Expected reconnect window of 50ms, got %dms", window)
+		}
+
+		if err := core.PlayerReconnect("player2"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		player, _ = core.GetPlayer("player2")
+		if player.Status != PlayerStatusInGame || !player.Connection.IsConnected {
+			t.Errorf("Expected connected in-game player, got %s", player.Status)
+		}
+	})
+
+	t.Run("reconnect_timeout", func(t *testing.T) {
+		core.PlayerDisconnect("player1")
+		player, _ := core.GetPlayer("player1")
+
+		core.CheckDisconnectedPlayers(player.Connection.ReconnectTimeout)
+		if player, _ := core.GetPlayer("player1"); player.Status != PlayerStatusDisconnected {
+			t.Errorf("Expected player to wait for reconnect, got %s", player.Status)
+		}
+
+		core.CheckDisconnectedPlayers(player.Connection.ReconnectTimeout + 1)
+		player, _ = core.GetPlayer("player1")
+		if player.Status != PlayerStatusSpectator || player.FinishReason != FinishReasonDisconnected {
+			t.Errorf("Expected spectator after timeout, got %s (%s)", player.Status, player.FinishReason)
+		}
+	})
+}
+
+func TestBaseCore_Spectators(t *testing.T) {
+	core := NewBaseCore("test-game-spectators", TestMode, createTestMapManager())
+	core.SetMaxSpectators(2)
+	core.Join(Player{Id: "player1", Name: "Player One"})
+
+	if err := core.Spectate(Spectator{Id: "player1"}); err == nil {
+		t.Error("Expected error when a player spectates own game")
+	}
+	if err := core.Spectate(Spectator{Id: "watcher1", Name: "Watcher One"}); err != nil {
+		t.Fatalf("Expected no error spectating, got %v", err)
+	}
+	if err := core.Spectate(Spectator{Id: "watcher1"}); !errors.Is(err, ErrAlreadySpectating) {
+		t.Errorf("Expected ErrAlreadySpectating, got %v", err)
+	}
+	if err := core.Join(Player{Id: "watcher1"}); !errors.Is(err, ErrAlreadySpectating) {
+		t.Errorf("Expected spectator join to be rejected, got %v", err)
+	}
+	core.Spectate(Spectator{Id: "watcher2"})
+	if err := core.Spectate(Spectator{Id: "watcher3"}); !errors.Is(err, ErrSpectatorLimit) {
+		t.Errorf("Expected ErrSpectatorLimit, got %v", err)
+	}
+
+	// 观战者不占用玩家位置
+	if len(core.Players()) != 1 || len(core.Spectators()) != 2 {
+		t.Errorf("Expected 1 player and 2 spectators, got %d and %d", len(core.Players()), len(core.Spectators()))
+	}
+
+	if err := core.StopSpectating("watcher1"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := core.StopSpectating("watcher1"); err == nil {
+		t.Error("Expected error stopping unknown spectator")
+	}
+	if spectators := core.Spectators(); len(spectators) != 1 || spectators[0].Id != "watcher2" {
+		t.Errorf("Expected only watcher2, got %+v", spectators)
+	}
+
+	core.Stop()
+	if err := core.Spectate(Spectator{Id: "watcher3"}); err == nil {
+		t.Error("Expected error spectating finished game")
+	}
+}
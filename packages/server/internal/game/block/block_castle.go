@@ -13,6 +13,12 @@ type Castle struct {
 var CastleMeta Meta
 var CastleName Name
 
+const (
+	// DefaultCastleNum 未指定驻军时城堡的初始兵力
+	DefaultCastleNum Num = 30
+	castleNumRange       = 30
+)
+
 func init() {
 	CastleName = Register("castle", "", toBlockCastle)
 	CastleMeta = GetMetaByName[CastleName]
@@ -21,7 +27,7 @@ func init() {
 func toBlockCastle(b Block) Block {
 	var ret Castle
 	if b.Num() == 0 {
-		ret.num = Num(30) + Num(rand.Intn(30))
+		ret.num = DefaultCastleNum
 	} else {
 		ret.num = b.Num()
 	}
@@ -29,6 +35,11 @@ func toBlockCastle(b Block) Block {
 	return &ret
 }
 
+// RandomCastleNum 使用给定随机源生成城堡驻军，同一种子得到相同结果
+func RandomCastleNum(rng *rand.Rand) Num {
+	return DefaultCastleNum + Num(rng.Intn(castleNumRange))
+}
+
 func (*Castle) Meta() Meta {
 	return CastleMeta
 }
@@ -0,0 +1,89 @@
+package game
+
+import (
+	"fmt"
+	"server/internal/queue"
+	"slices"
+	"time"
+)
+
+type ChatChannel string
+
+const (
+	// ChatChannelAll 对局内公共频道，观战者的消息只发给其他观战者
+	ChatChannelAll ChatChannel = "all"
+	// ChatChannelTeam 队伍频道，只发给同队玩家
+	ChatChannelTeam ChatChannel = "team"
+	// ChatChannelLobby 大厅频道，由接入层直接发布到 lobby/chat，不经过对局
+	ChatChannelLobby ChatChannel = "lobby"
+)
+
+// handleChatCommand 确定消息的接收者，记录到事件历史并发布到对局的聊天主题。
+// 玩家的公共频道消息同时放入观战事件流，观战者与对局画面一起延迟看到
+func (g *Game) handleChatCommand(cmd ChatCommand) error {
+	message := ChatMessageEvent{
+		Channel:  cmd.Channel,
+		SenderId: cmd.PlayerId,
+		Text:     cmd.Text,
+		SentAt:   time.Now().UnixMilli(),
+	}
+
+	_, player := g.core.findPlayerIndex(cmd.PlayerId)
+	spectator := g.core.findSpectatorIndex(cmd.PlayerId)
+	switch {
+	case player != nil:
+		message.SenderName = player.Name
+	case spectator >= 0:
+		message.SenderName = g.core.spectators[spectator].Name
+	default:
+		return fmt.Errorf("%w: %s", ErrPlayerNotFound, cmd.PlayerId)
+	}
+
+	switch cmd.Channel {
+	case ChatChannelAll:
+		// 观战者能看到延迟的完整视野，消息不能发给玩家
+		if player == nil {
+			for _, s := range g.core.Spectators() {
+				message.Recipients = append(message.Recipients, s.Id)
+			}
+		}
+	case ChatChannelTeam:
+		if player == nil {
+			return fmt.Errorf("spectators cannot use team chat")
+		}
+		teammates, err := g.core.Teammates(cmd.PlayerId)
+		if err != nil {
+			return err
+		}
+		message.Recipients = teammates
+	default:
+		return fmt.Errorf("unknown chat channel: %s", cmd.Channel)
+	}
+
+	topic := fmt.Sprintf("%s/chat", g.gameId)
+	public := len(message.Recipients) == 0
+	g.history.append(message, func(sequenced queue.Event) {
+		g.queue.Publish(topic, sequenced)
+		if public && g.spectatorCount.Load() > 0 {
+			g.spectatorFeed.push(sequenced)
+		}
+	})
+	return nil
+}
+
+// visibleTo 过滤补发的事件，去掉接收者不包含 playerId 的聊天消息
+func visibleTo(events []queue.Envelope, playerId string) []queue.Envelope {
+	visible := events[:0:0]
+	for _, envelope := range events {
+		event, err := envelope.Event()
+		if err != nil {
+			continue
+		}
+		if message, ok := event.(ChatMessageEvent); ok &&
+			len(message.Recipients) > 0 && !slices.Contains(message.Recipients, playerId) {
+			continue
+		}
+		visible = append(visible, envelope)
+	}
+	return visible
+}
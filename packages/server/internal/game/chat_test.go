@@ -0,0 +1,92 @@
+package game
+
+import (
+	gamemap "server/internal/game/map"
+	"server/internal/queue"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGame_Chat(t *testing.T) {
+	mode := TestMode
+	mode.TeamSize = 2
+	mode.MaxPlayers = 8
+
+	q := queue.NewInMemoryQueue()
+	game := NewGame("test-game-chat", q, mode, gamemap.NewMapManager())
+	for _, id := range []string{"p1", "p2", "p3", "p4"} {
+		game.core.Join(Player{Id: id, Name: "Player " + id})
+	}
+	game.core.Spectate(Spectator{Id: "watcher", Name: "Watcher"})
+	game.spectatorCount.Store(1)
+	chatCh := q.Subscribe("test-game-chat/chat")
+
+	receive := func() ChatMessageEvent {
+		t.Helper()
+		select {
+		case event := <-chatCh:
+			return event.(ChatMessageEvent)
+		case <-time.After(time.Second):
+			t.Fatal("Expected chat message")
+			return ChatMessageEvent{}
+		}
+	}
+
+	if err := game.handleChatCommand(ChatCommand{CommandEvent: CommandEvent{PlayerId: "p1"}, Channel: ChatChannelAll, Text: "hi"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if message := receive(); message.SenderName != "Player p1" || message.Recipients != nil || message.Seq != 1 {
+		t.Errorf("Expected all-chat to everyone, got %+v", message)
+	}
+
+	game.handleChatCommand(ChatCommand{CommandEvent: CommandEvent{PlayerId: "p3"}, Channel: ChatChannelTeam, Text: "push"})
+	if message := receive(); !slices.Equal(message.Recipients, []string{"p3", "p4"}) {
+		t.Errorf("Expected team chat to p3 and p4, got %v", message.Recipients)
+	}
+
+	// 观战者的消息只发给观战者，且不能使用队伍频道
+	game.handleChatCommand(ChatCommand{CommandEvent: CommandEvent{PlayerId: "watcher"}, Channel: ChatChannelAll, Text: "p2 is north"})
+	if message := receive(); !slices.Equal(message.Recipients, []string{"watcher"}) {
+		t.Errorf("Expected spectator chat to spectators only, got %v", message.Recipients)
+	}
+	if err := game.handleChatCommand(ChatCommand{CommandEvent: CommandEvent{PlayerId: "watcher"}, Channel: ChatChannelTeam, Text: "x"}); err == nil {
+		t.Error("Expected spectator team chat to be rejected")
+	}
+
+	// 消息记录在事件历史中，补发时只包含发给该玩家的消息
+	missed, seq, complete := game.history.since(0)
+	if seq != 3 || !complete || len(missed) != 3 {
+		t.Fatalf("Expected 3 recorded chat messages, got %d (seq %d)", len(missed), seq)
+	}
+	if visible := visibleTo(missed, "p1"); len(visible) != 1 {
+		t.Errorf("Expected p1 to see only the all-chat message, got %d", len(visible))
+	}
+	if visible := visibleTo(missed, "p4"); len(visible) != 2 {
+		t.Errorf("Expected p4 to see all-chat and team chat, got %d", len(visible))
+	}
+
+	// 观战事件流只包含玩家的公共频道消息
+	game.spectatorFeed.mu.Lock()
+	pending := len(game.spectatorFeed.pending)
+	game.spectatorFeed.mu.Unlock()
+	if pending != 1 {
+		t.Errorf("Expected only the public message in the spectator feed, got %d", pending)
+	}
+
+	if err := game.handleChatCommand(ChatCommand{CommandEvent: CommandEvent{PlayerId: "stranger"}, Channel: ChatChannelAll, Text: "x"}); err == nil {
+		t.Error("Expected chat from outside the game to be rejected")
+	}
+	if err := game.handleChatCommand(ChatCommand{CommandEvent: CommandEvent{PlayerId: "p1"}, Channel: "whisper", Text: "x"}); err == nil {
+		t.Error("Expected unknown channel to be rejected")
+	}
+}
+
+func TestBaseCore_TeammatesWithoutTeams(t *testing.T) {
+	core := NewBaseCore("test-game-teams", TestMode, createTestMapManager())
+	core.Join(Player{Id: "p1"})
+
+	if _, err := core.Teammates("p1"); err == nil {
+		t.Error("Expected error for team chat in a mode without teams")
+	}
+}
@@ -0,0 +1,169 @@
+package game
+
+import (
+	"encoding/json"
+	gamemap "server/internal/game/map"
+	"server/internal/queue"
+)
+
+// 注册对局相关事件，使其可以经由 Redis 等跨进程队列传输
+func init() {
+	queue.RegisterEvent("game.join", 1, JoinCommand{})
+	queue.RegisterEvent("game.leave", 1, LeaveCommand{})
+	queue.RegisterEvent("game.move", 1, MoveCommand{})
+	queue.RegisterEvent("game.forceStart", 1, ForceStartCommand{})
+	queue.RegisterEvent("game.surrender", 1, SurrenderCommand{})
+	queue.RegisterEvent("game.disconnect", 1, DisconnectCommand{})
+	queue.RegisterEvent("game.reconnect", 1, ReconnectCommand{})
+	queue.RegisterEvent("game.resume", 1, ResumeCommand{})
+	queue.RegisterEvent("game.spectate", 1, SpectateCommand{})
+	queue.RegisterEvent("game.stopSpectating", 1, StopSpectatingCommand{})
+	queue.RegisterEvent("game.chat", 1, ChatCommand{})
+	queue.RegisterEvent("game.kick", 1, KickCommand{})
+	queue.RegisterEvent("game.transferHost", 1, TransferHostCommand{})
+	queue.RegisterEvent("game.roomSettings", 1, RoomSettingsCommand{})
+	queue.RegisterEvent("game.hostStart", 1, HostStartCommand{})
+
+	queue.RegisterEvent("game.startControl", 1, StartGameControl{})
+	queue.RegisterEvent("game.stopControl", 1, StopGameControl{})
+	queue.RegisterEvent("game.turnAdvanceControl", 1, TurnAdvanceControl{})
+
+	queue.RegisterEvent("game.playerJoined", 1, PlayerJoinedEvent{})
+	queue.RegisterEvent("game.playerLeft", 1, PlayerLeftEvent{})
+	queue.RegisterEvent("game.mapUpdate", 1, MapUpdateEvent{})
+	queue.RegisterEvent("game.statusUpdate", 1, GameStatusUpdateEvent{})
+	queue.RegisterEvent("game.forceStartVote", 1, ForceStartVoteEvent{})
+	queue.RegisterEvent("game.playerSurrendered", 1, PlayerSurrenderedEvent{})
+	queue.RegisterEvent("game.started", 1, GameStartedEvent{})
+	queue.RegisterEvent("game.ended", 1, GameEndedEvent{})
+	queue.RegisterEvent("game.turnStarted", 1, TurnStartedEvent{})
+	queue.RegisterEvent("game.playerMoved", 1, PlayerMovedEvent{})
+	queue.RegisterEvent("game.playerError", 1, PlayerErrorEvent{})
+	queue.RegisterEvent("game.joinAck", 1, JoinAckEvent{})
+	queue.RegisterEvent("game.resumed", 1, ResumeEvent{})
+	queue.RegisterEvent("game.spectatorSnapshot", 1, SpectatorSnapshotEvent{})
+	queue.RegisterEvent("game.chatMessage", 1, ChatMessageEvent{})
+	queue.RegisterEvent("game.roomUpdated", 1, RoomUpdatedEvent{})
+}
+
+type mapUpdateJSON struct {
+	Map        *gamemap.Snapshot
+	TurnNumber uint16
+}
+
+// MarshalJSON 地图是接口类型，以快照形式序列化
+func (e MapUpdateEvent) MarshalJSON() ([]byte, error) {
+	data := mapUpdateJSON{TurnNumber: e.TurnNumber, Map: mapSnapshot(e.Map)}
+	return json.Marshal(data)
+}
+
+func (e *MapUpdateEvent) UnmarshalJSON(b []byte) error {
+	var data mapUpdateJSON
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	e.TurnNumber = data.TurnNumber
+	e.Map = snapshotMap(data.Map)
+	return nil
+}
+
+// resumeEventJSON 与 ResumeEvent 字段一致，地图以快照形式序列化
+type resumeEventJSON struct {
+	PlayerId   string
+	GameStatus Status
+	TurnNumber uint16
+	Players    []Player
+	Map        *gamemap.Snapshot
+	Seq        uint64
+	Missed     []queue.Envelope
+	Complete   bool
+}
+
+func (e ResumeEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(resumeEventJSON{
+		PlayerId:   e.PlayerId,
+		GameStatus: e.GameStatus,
+		TurnNumber: e.TurnNumber,
+		Players:    e.Players,
+		Map:        mapSnapshot(e.Map),
+		Seq:        e.Seq,
+		Missed:     e.Missed,
+		Complete:   e.Complete,
+	})
+}
+
+func (e *ResumeEvent) UnmarshalJSON(b []byte) error {
+	var data resumeEventJSON
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	*e = ResumeEvent{
+		PlayerId:   data.PlayerId,
+		GameStatus: data.GameStatus,
+		TurnNumber: data.TurnNumber,
+		Players:    data.Players,
+		Map:        snapshotMap(data.Map),
+		Seq:        data.Seq,
+		Missed:     data.Missed,
+		Complete:   data.Complete,
+	}
+	return nil
+}
+
+// spectatorSnapshotJSON 与 SpectatorSnapshotEvent 字段一致，地图以快照形式序列化
+type spectatorSnapshotJSON struct {
+	SpectatorId string
+	GameStatus  Status
+	TurnNumber  uint16
+	Players     []Player
+	Spectators  int
+	Map         *gamemap.Snapshot
+	Seq         uint64
+}
+
+func (e SpectatorSnapshotEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(spectatorSnapshotJSON{
+		SpectatorId: e.SpectatorId,
+		GameStatus:  e.GameStatus,
+		TurnNumber:  e.TurnNumber,
+		Players:     e.Players,
+		Spectators:  e.Spectators,
+		Map:         mapSnapshot(e.Map),
+		Seq:         e.Seq,
+	})
+}
+
+func (e *SpectatorSnapshotEvent) UnmarshalJSON(b []byte) error {
+	var data spectatorSnapshotJSON
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	*e = SpectatorSnapshotEvent{
+		SpectatorId: data.SpectatorId,
+		GameStatus:  data.GameStatus,
+		TurnNumber:  data.TurnNumber,
+		Players:     data.Players,
+		Spectators:  data.Spectators,
+		Map:         snapshotMap(data.Map),
+		Seq:         data.Seq,
+	}
+	return nil
+}
+
+func mapSnapshot(m gamemap.Map) *gamemap.Snapshot {
+	if m == nil {
+		return nil
+	}
+	snapshot := gamemap.NewSnapshot(m)
+	return &snapshot
+}
+
+func snapshotMap(s *gamemap.Snapshot) gamemap.Map {
+	if s == nil {
+		return nil
+	}
+	return s.Map()
+}
@@ -0,0 +1,138 @@
+package game
+
+import (
+	"reflect"
+	"server/internal/game/block"
+	gamemap "server/internal/game/map"
+	"server/internal/queue"
+	"strings"
+	"testing"
+	"time"
+)
+
+// codecSamples 每个已注册的 game.* 事件对应一个非零样例
+func codecSamples() map[string]queue.Event {
+	players := []Player{
+		{Id: "p1", Name: "Alice", Moves: 2, Status: PlayerStatusInGame,
+			Connection: PlayerConnectionInfo{IsConnected: true}},
+		{Id: "p2", Name: "Bob", Status: PlayerStatusLost, FinishReason: FinishReasonDefeated},
+	}
+
+	return map[string]queue.Event{
+		"game.join":           JoinCommand{CommandEvent: CommandEvent{PlayerId: "p1"}, PlayerName: "Alice", Password: "secret"},
+		"game.leave":          LeaveCommand{CommandEvent: CommandEvent{PlayerId: "p1"}},
+		"game.move":           MoveCommand{CommandEvent: CommandEvent{PlayerId: "p1"}, From: gamemap.Pos{X: 1, Y: 2}, Direction: MoveTowardsRight, Troops: 5},
+		"game.forceStart":     ForceStartCommand{CommandEvent: CommandEvent{PlayerId: "p1"}, IsVote: true},
+		"game.surrender":      SurrenderCommand{CommandEvent: CommandEvent{PlayerId: "p1"}},
+		"game.disconnect":     DisconnectCommand{CommandEvent: CommandEvent{PlayerId: "p1"}},
+		"game.reconnect":      ReconnectCommand{CommandEvent: CommandEvent{PlayerId: "p1"}},
+		"game.resume":         ResumeCommand{CommandEvent: CommandEvent{PlayerId: "p1"}, LastSeq: 42},
+		"game.spectate":       SpectateCommand{CommandEvent: CommandEvent{PlayerId: "s1"}, SpectatorName: "Carol"},
+		"game.stopSpectating": StopSpectatingCommand{CommandEvent: CommandEvent{PlayerId: "s1"}},
+		"game.chat":           ChatCommand{CommandEvent: CommandEvent{PlayerId: "p1"}, Channel: ChatChannelTeam, Text: "push left"},
+		"game.kick":           KickCommand{CommandEvent: CommandEvent{PlayerId: "p1"}, TargetId: "p2"},
+		"game.transferHost":   TransferHostCommand{CommandEvent: CommandEvent{PlayerId: "p1"}, TargetId: "p2"},
+		"game.roomSettings":   RoomSettingsCommand{CommandEvent: CommandEvent{PlayerId: "p1"}, GameMode: "classic_1v1", MapId: "m1"},
+		"game.hostStart":      HostStartCommand{CommandEvent: CommandEvent{PlayerId: "p1"}},
+
+		"game.startControl":       StartGameControl{},
+		"game.stopControl":        StopGameControl{},
+		"game.turnAdvanceControl": TurnAdvanceControl{TurnNumber: 12},
+
+		"game.playerJoined":      PlayerJoinedEvent{PlayerId: "p1", PlayerName: "Alice", GameStatus: StatusWaiting, Players: players},
+		"game.playerLeft":        PlayerLeftEvent{PlayerId: "p2", GameStatus: StatusWaiting, Players: players},
+		"game.mapUpdate":         MapUpdateEvent{Map: codecSampleMap(), TurnNumber: 3},
+		"game.statusUpdate":      GameStatusUpdateEvent{Status: StatusInProgress, Players: players, TurnNumber: 4, Spectators: 2},
+		"game.forceStartVote":    ForceStartVoteEvent{PlayerId: "p1", IsVote: true, GameStatus: StatusWaiting, Players: players},
+		"game.playerSurrendered": PlayerSurrenderedEvent{PlayerId: "p2", GameStatus: StatusInProgress, Players: players},
+		"game.started":           GameStartedEvent{GameStatus: StatusInProgress, Players: players, TurnNumber: 1},
+		"game.ended":             GameEndedEvent{Winner: "p1", GameStatus: StatusFinished, Players: players},
+		"game.turnStarted":       TurnStartedEvent{TurnNumber: 9, Players: players},
+		"game.playerMoved":       PlayerMovedEvent{PlayerId: "p1", Move: Move{Pos: gamemap.Pos{X: 3, Y: 4}, Towards: MoveTowardsUp, Num: 2}, MovesLeft: 1},
+		"game.playerError":       PlayerErrorEvent{PlayerId: "p1", Error: "invalid move"},
+		"game.joinAck":           JoinAckEvent{PlayerId: "p1", Error: "game is full"},
+		"game.resumed": ResumeEvent{PlayerId: "p1", GameStatus: StatusInProgress, TurnNumber: 5, Players: players,
+			Map: codecSampleMap(), Seq: 9, Missed: []queue.Envelope{codecSampleEnvelope()}, Complete: true},
+		"game.spectatorSnapshot": SpectatorSnapshotEvent{SpectatorId: "s1", GameStatus: StatusInProgress, TurnNumber: 5,
+			Players: players, Spectators: 1, Map: codecSampleMap(), Seq: 9},
+		"game.chatMessage": ChatMessageEvent{Channel: ChatChannelAll, SenderId: "p1", SenderName: "Alice", Text: "gg",
+			Recipients: []string{"p1", "p2"}, SentAt: 1735689600000},
+		"game.roomUpdated": RoomUpdatedEvent{Host: "p1", Private: true, HasPassword: true, GameMode: "classic_1v1",
+			MapId: "m1", Players: players},
+	}
+}
+
+func codecSampleEnvelope() queue.Envelope {
+	envelope, err := queue.NewEnvelope(TurnStartedEvent{BroadcastEvent: BroadcastEvent{Seq: 9}, TurnNumber: 5}, "codec")
+	if err != nil {
+		panic(err)
+	}
+	envelope.Timestamp = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	return *envelope
+}
+
+func codecSampleMap() gamemap.Map {
+	m := gamemap.NewEmptyBaseMap(gamemap.Size{Width: 3, Height: 2}, gamemap.Info{Id: "codec", Name: "Codec"})
+	m.SetBlock(gamemap.Pos{X: 1, Y: 1}, block.NewBlock(block.KingName, 10, 1))
+	return m
+}
+
+func TestCodec_RoundTripAllGameEvents(t *testing.T) {
+	samples := codecSamples()
+
+	for _, name := range queue.RegisteredEvents() {
+		if !strings.HasPrefix(name, "game.") {
+			continue
+		}
+
+		t.Run(name, func(t *testing.T) {
+			sample, ok := samples[name]
+			if !ok {
+				t.Fatalf("No round-trip sample for registered event %s", name)
+			}
+
+			data, err := queue.Encode(sample, "test")
+			if err != nil {
+				t.Fatalf("Expected no error encoding, got %v", err)
+			}
+			decoded, envelope, err := queue.Decode(data)
+			if err != nil {
+				t.Fatalf("Expected no error decoding, got %v", err)
+			}
+			if envelope.Type != name {
+				t.Errorf("Expected envelope type %s, got %s", name, envelope.Type)
+			}
+
+			// 地图为接口类型，按快照比较
+			if update, ok := sample.(MapUpdateEvent); ok {
+				got, ok := decoded.(MapUpdateEvent)
+				if !ok {
+					t.Fatalf("Expected MapUpdateEvent, got %T", decoded)
+				}
+				if got.TurnNumber != update.TurnNumber ||
+					!reflect.DeepEqual(gamemap.NewSnapshot(got.Map), gamemap.NewSnapshot(update.Map)) {
+					t.Errorf("Map update did not round-trip: %#v", got)
+				}
+				return
+			}
+			if resume, ok := sample.(ResumeEvent); ok {
+				got, ok := decoded.(ResumeEvent)
+				if !ok {
+					t.Fatalf("Expected ResumeEvent, got %T", decoded)
+				}
+				if !reflect.DeepEqual(gamemap.NewSnapshot(got.Map), gamemap.NewSnapshot(resume.Map)) {
+					t.Errorf("Resume map did not round-trip")
+				}
+				got.Map, resume.Map = nil, nil
+				if !reflect.DeepEqual(got, resume) {
+					t.Errorf("Expected %#v, got %#v", resume, got)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(decoded, sample) {
+				t.Errorf("Expected %#v, got %#v", sample, decoded)
+			}
+		})
+	}
+}
@@ -82,6 +82,12 @@ func (p *Player) IsFinished() bool {
 		p.Status == PlayerStatusFinished
 }
 
+// Spectator 观战者不占用玩家位置，也不对应地图上的 owner
+type Spectator struct {
+	Id   string
+	Name string
+}
+
 type Core interface {
 	Status() Status
 	Players() []Player
@@ -95,6 +101,10 @@ type Core interface {
 	Leave(playerId string) error
 	GetPlayer(playerId string) (*Player, error)
 
+	Spectators() []Spectator
+	Spectate(spectator Spectator) error
+	StopSpectating(spectatorId string) error
+
 	Map() gamemap.Map
 
 	Start() error
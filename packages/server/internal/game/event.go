@@ -3,6 +3,7 @@ package game
 import (
 	"server/internal/game/block"
 	gamemap "server/internal/game/map"
+	"server/internal/queue"
 )
 
 type MoveTowards string
@@ -40,13 +41,23 @@ type CommandEvent struct {
 
 type ControlEvent struct{}
 
-type BroadcastEvent struct{}
+// BroadcastEvent Seq 为对局内广播事件的递增序号，由 Game 在发布时分配。
+// 对局聊天消息也占用序号，玩家看不到的消息会使其收到的序号不连续
+type BroadcastEvent struct {
+	Seq uint64
+}
+
+func (e *BroadcastEvent) setSeq(seq uint64) {
+	e.Seq = seq
+}
 
 type PlayerEvent struct{}
 
+// JoinCommand Password 为私人房间的密码，公开对局忽略
 type JoinCommand struct {
 	CommandEvent
 	PlayerName string
+	Password   string
 }
 
 type LeaveCommand struct {
@@ -69,6 +80,65 @@ type SurrenderCommand struct {
 	CommandEvent
 }
 
+// DisconnectCommand 玩家的 WebSocket 连接断开
+type DisconnectCommand struct {
+	CommandEvent
+}
+
+// ReconnectCommand 断线玩家重新建立连接
+type ReconnectCommand struct {
+	CommandEvent
+}
+
+// ResumeCommand 客户端重连后请求补发 LastSeq 之后的广播事件与发给该玩家的聊天消息
+type ResumeCommand struct {
+	CommandEvent
+	LastSeq uint64
+}
+
+// SpectateCommand 以观战者身份加入对局，PlayerId 为观战者的用户 ID
+type SpectateCommand struct {
+	CommandEvent
+	SpectatorName string
+	Password      string
+}
+
+// StopSpectatingCommand 观战者离开对局
+type StopSpectatingCommand struct {
+	CommandEvent
+}
+
+// ChatCommand 已通过接入层审核的聊天消息，由对局确定接收者
+type ChatCommand struct {
+	CommandEvent
+	Channel ChatChannel
+	Text    string
+}
+
+// KickCommand 房主在开局前将 TargetId 移出房间
+type KickCommand struct {
+	CommandEvent
+	TargetId string
+}
+
+// TransferHostCommand 房主将房主身份转让给 TargetId
+type TransferHostCommand struct {
+	CommandEvent
+	TargetId string
+}
+
+// RoomSettingsCommand 房主在开局前修改模式与地图，字段为空时保持不变
+type RoomSettingsCommand struct {
+	CommandEvent
+	GameMode string
+	MapId    string
+}
+
+// HostStartCommand 房主直接开局
+type HostStartCommand struct {
+	CommandEvent
+}
+
 type StartGameControl struct {
 	ControlEvent
 }
@@ -97,8 +167,9 @@ type PlayerLeftEvent struct {
 	Players    []Player
 }
 
+// MapUpdateEvent 发往 <gameId>/player/<id> 的玩家视野地图，观战事件流中为完整地图。不参与广播序号
 type MapUpdateEvent struct {
-	BroadcastEvent
+	PlayerEvent
 	Map        gamemap.Map
 	TurnNumber uint16
 }
@@ -108,6 +179,18 @@ type GameStatusUpdateEvent struct {
 	Status     Status
 	Players    []Player
 	TurnNumber uint16
+	Spectators int
+}
+
+// RoomUpdatedEvent 私人房间的房主或设置变化
+type RoomUpdatedEvent struct {
+	BroadcastEvent
+	Host        string
+	Private     bool
+	HasPassword bool
+	GameMode    string
+	MapId       string
+	Players     []Player
 }
 
 type ForceStartVoteEvent struct {
@@ -158,6 +241,52 @@ type PlayerErrorEvent struct {
 	Error    string
 }
 
+// JoinAckEvent 对加入、观战、恢复与重连指令的确认，Error 为空表示已接受。
+// 接入层收到确认后才开始转发对局的广播与聊天，不转发给客户端
+type JoinAckEvent struct {
+	PlayerEvent
+	PlayerId string
+	Error    string
+}
+
+// ResumeEvent 对 ResumeCommand 的回复：当前状态快照（按玩家视野遮蔽的地图）与错过的广播事件。
+// Seq 为快照对应的序号；Complete 为 false 时缓冲区已丢弃部分事件，客户端应以快照为准。
+type ResumeEvent struct {
+	PlayerEvent
+	PlayerId   string
+	GameStatus Status
+	TurnNumber uint16
+	Players    []Player
+	Map        gamemap.Map
+	Seq        uint64
+	Missed     []queue.Envelope
+	Complete   bool
+}
+
+// SpectatorSnapshotEvent 观战者加入时的完整视野快照，与其他观战事件一样延迟发布到观战主题。
+// Seq 为快照对应的广播序号，观战者应忽略序号不大于 Seq 的事件。
+type SpectatorSnapshotEvent struct {
+	SpectatorId string
+	GameStatus  Status
+	TurnNumber  uint16
+	Players     []Player
+	Spectators  int
+	Map         gamemap.Map
+	Seq         uint64
+}
+
+// ChatMessageEvent 发布到 <gameId>/chat 的聊天消息，Recipients 为空时发给对局中的所有连接。
+// 对局内的消息与广播事件共用序号并记录在事件历史中，大厅消息没有序号。SentAt 为 Unix 毫秒时间戳。
+type ChatMessageEvent struct {
+	BroadcastEvent
+	Channel    ChatChannel
+	SenderId   string
+	SenderName string
+	Text       string
+	Recipients []string
+	SentAt     int64
+}
+
 type Move struct {
 	Pos     gamemap.Pos
 	Towards MoveTowards
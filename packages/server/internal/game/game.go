@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"log/slog"
 	gamemap "server/internal/game/map"
+	"server/internal/metrics"
 	"server/internal/queue"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Game 事件转发层 - 负责事件的订阅、解析和转发
@@ -13,25 +17,82 @@ type Game struct {
 	gameId string
 	core   *BaseCore
 	queue  queue.Queue
+	// coreMu 事件循环处理每个事件时持有写锁，外部通过 View 读取
+	coreMu sync.RWMutex
 
-	// 上下文管理
-	ctx    context.Context
-	cancel context.CancelFunc
+	// 上下文管理，lifecycleMu 保护 Start 与 Stop 并发调用
+	ctx         context.Context
+	cancel      context.CancelFunc
+	lifecycleMu sync.Mutex
+	stopped     bool
 
 	// 消息通道
 	commandCh <-chan queue.Event
 	controlCh <-chan queue.Event
+
+	// disconnectCheckInterval 检查断线玩家是否超过重连期限的间隔
+	disconnectCheckInterval time.Duration
+
+	// history 广播事件的序号与最近事件，用于断线重连补发
+	history *eventHistory
+
+	// spectatorFeed 观战主题的延迟事件流，spectatorCount 为 0 时不入队
+	spectatorFeed  *spectatorFeed
+	spectatorCount atomic.Int32
+}
+
+// DefaultReconnectTimeout 断线玩家默认的重连期限
+const DefaultReconnectTimeout = 30 * time.Second
+
+// Options 对局的连接管理参数
+type Options struct {
+	// ReconnectTimeout 断线玩家的重连期限，超时后转为观战
+	ReconnectTimeout time.Duration
+	// DisconnectCheckInterval 检查重连超时的间隔，为 0 时不检查
+	DisconnectCheckInterval time.Duration
+	// HistorySize 保留的最近广播事件数，重连时补发
+	HistorySize int
+	// MaxSpectators 观战人数上限，为 0 时不允许观战
+	MaxSpectators int
+	// SpectatorDelay 观战事件流的延迟
+	SpectatorDelay time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		ReconnectTimeout:        DefaultReconnectTimeout,
+		DisconnectCheckInterval: time.Second,
+		HistorySize:             DefaultHistorySize,
+		MaxSpectators:           DefaultMaxSpectators,
+		SpectatorDelay:          DefaultSpectatorDelay,
+	}
 }
 
-// NewGame 创建新的游戏实例
+// NewGame 使用默认参数创建新的游戏实例
 func NewGame(gameId string, q queue.Queue, mode GameMode, mapManager gamemap.MapManager) *Game {
+	return NewGameWithOptions(gameId, q, mode, mapManager, DefaultOptions())
+}
+
+// NewGameWithOptions 创建新的游戏实例
+func NewGameWithOptions(gameId string, q queue.Queue, mode GameMode, mapManager gamemap.MapManager, opts Options) *Game {
 	core := NewBaseCore(gameId, mode, mapManager)
+	if opts.ReconnectTimeout > 0 {
+		core.SetReconnectTimeout(opts.ReconnectTimeout)
+	}
+	core.SetMaxSpectators(opts.MaxSpectators)
 
 	game := &Game{
 		gameId: gameId,
 		core:   core,
 		queue:  q,
+
+		disconnectCheckInterval: opts.DisconnectCheckInterval,
+		history:                 newEventHistory(opts.HistorySize, gameId),
 	}
+	spectateTopic := fmt.Sprintf("%s/spectate", gameId)
+	game.spectatorFeed = newSpectatorFeed(opts.SpectatorDelay, func(event queue.Event) {
+		q.Publish(spectateTopic, event)
+	})
 
 	// 设置BaseCore的事件回调
 	core.SetEventHandlers(
@@ -44,6 +105,13 @@ func NewGame(gameId string, q queue.Queue, mode GameMode, mapManager gamemap.Map
 
 // Start 启动游戏事件处理循环
 func (g *Game) Start() error {
+	g.lifecycleMu.Lock()
+	defer g.lifecycleMu.Unlock()
+	if g.stopped {
+		// 对局在启动前已被停止（例如停机时刚创建的对局）
+		return nil
+	}
+
 	// 订阅消息通道
 	g.commandCh = g.queue.Subscribe(fmt.Sprintf("%s/commands", g.gameId))
 	g.controlCh = g.queue.Subscribe(fmt.Sprintf("%s/control", g.gameId))
@@ -53,6 +121,7 @@ func (g *Game) Start() error {
 
 	// 启动事件处理循环
 	go g.eventLoop()
+	go g.spectatorFeed.run(g.ctx)
 
 	slog.Info("game event handler started", "gameId", g.gameId)
 	return nil
@@ -60,11 +129,22 @@ func (g *Game) Start() error {
 
 // Stop 停止游戏事件处理
 func (g *Game) Stop() error {
+	g.lifecycleMu.Lock()
+	g.stopped = true
 	if g.cancel != nil {
 		g.cancel()
 	}
 
+	// 取消订阅以唤醒阻塞在无损指令主题上的发布方
+	if g.commandCh != nil {
+		g.queue.Unsubscribe(fmt.Sprintf("%s/commands", g.gameId), g.commandCh)
+		g.queue.Unsubscribe(fmt.Sprintf("%s/control", g.gameId), g.controlCh)
+	}
+	g.lifecycleMu.Unlock()
+
 	// 停止游戏核心
+	g.coreMu.Lock()
+	defer g.coreMu.Unlock()
 	if err := g.core.Stop(); err != nil {
 		slog.Error("failed to stop game core", "error", err, "gameId", g.gameId)
 	}
@@ -73,11 +153,39 @@ func (g *Game) Stop() error {
 	return nil
 }
 
-// Core 获取游戏核心（用于直接访问游戏状态）
+// Mode 返回对局的游戏模式
+func (g *Game) Mode() GameMode {
+	g.coreMu.RLock()
+	defer g.coreMu.RUnlock()
+	return g.core.mode
+}
+
+// SetMapId 设置对局地图，仅在游戏开始前有效
+func (g *Game) SetMapId(mapId string) error {
+	g.coreMu.Lock()
+	defer g.coreMu.Unlock()
+	return g.core.SetMapId(mapId)
+}
+
+// Room 返回私人房间的设置，公开对局的 Private 为 false
+func (g *Game) Room() RoomInfo {
+	g.coreMu.RLock()
+	defer g.coreMu.RUnlock()
+	return g.core.Room()
+}
+
+// Core 获取游戏核心（用于直接访问游戏状态），事件循环之外读取请使用 View
 func (g *Game) Core() Core {
 	return g.core
 }
 
+// View 在读锁下访问游戏核心，避免与事件循环并发读写
+func (g *Game) View(fn func(core Core)) {
+	g.coreMu.RLock()
+	defer g.coreMu.RUnlock()
+	fn(g.core)
+}
+
 // eventLoop 事件处理主循环
 func (g *Game) eventLoop() {
 	defer func() {
@@ -89,17 +197,40 @@ func (g *Game) eventLoop() {
 		}
 	}()
 
+	// 未启用检查时 checkCh 为 nil，对应分支永不触发
+	var checkCh <-chan time.Time
+	if g.disconnectCheckInterval > 0 {
+		ticker := time.NewTicker(g.disconnectCheckInterval)
+		defer ticker.Stop()
+		checkCh = ticker.C
+	}
+
 	for {
 		select {
 		case <-g.ctx.Done():
 			slog.Info("game event loop stopped", "gameId", g.gameId)
 			return
 
-		case event := <-g.controlCh:
+		case now := <-checkCh:
+			g.coreMu.Lock()
+			g.checkDisconnectedPlayers(now)
+			g.coreMu.Unlock()
+
+		case event, ok := <-g.controlCh:
+			if !ok {
+				return
+			}
+			g.coreMu.Lock()
 			g.handleControlEvent(event)
+			g.coreMu.Unlock()
 
-		case event := <-g.commandCh:
+		case event, ok := <-g.commandCh:
+			if !ok {
+				return
+			}
+			g.coreMu.Lock()
 			g.handleCommandEvent(event)
+			g.coreMu.Unlock()
 
 		}
 	}
@@ -116,20 +247,49 @@ func (g *Game) handleCommandEvent(event queue.Event) {
 		err = g.handleLeaveCommand(cmd)
 	case MoveCommand:
 		err = g.handleMoveCommand(cmd)
+		if err != nil {
+			metrics.MovesRejected.WithLabelValues(moveRejectReason(err)).Inc()
+		}
 	case ForceStartCommand:
 		err = g.handleForceStartCommand(cmd)
 	case SurrenderCommand:
 		err = g.handleSurrenderCommand(cmd)
+	case DisconnectCommand:
+		err = g.handleDisconnectCommand(cmd)
+	case ReconnectCommand:
+		err = g.handleReconnectCommand(cmd)
+	case ResumeCommand:
+		err = g.handleResumeCommand(cmd)
+	case SpectateCommand:
+		err = g.handleSpectateCommand(cmd)
+	case StopSpectatingCommand:
+		err = g.handleStopSpectatingCommand(cmd)
+	case ChatCommand:
+		err = g.handleChatCommand(cmd)
+	case KickCommand:
+		err = g.handleKickCommand(cmd)
+	case TransferHostCommand:
+		err = g.handleTransferHostCommand(cmd)
+	case RoomSettingsCommand:
+		err = g.handleRoomSettingsCommand(cmd)
+	case HostStartCommand:
+		err = g.handleHostStartCommand(cmd)
 	default:
 		slog.Warn("unknown command event", "type", fmt.Sprintf("%T", event), "gameId", g.gameId)
 		return
 	}
+	metrics.GameCommands.WithLabelValues(commandType(event)).Inc()
 
 	// 处理错误
 	if err != nil {
 		playerId := g.getPlayerIdFromCommand(event)
 		g.publishPlayerError(playerId, err)
 	}
+
+	switch event.(type) {
+	case JoinCommand, SpectateCommand, ResumeCommand, ReconnectCommand:
+		g.publishJoinAck(g.getPlayerIdFromCommand(event), err)
+	}
 }
 
 // handleControlEvent 处理控制事件
@@ -158,6 +318,9 @@ func (g *Game) handleControlEvent(event queue.Event) {
 
 // handleJoinCommand 处理加入游戏指令
 func (g *Game) handleJoinCommand(cmd JoinCommand) error {
+	if err := g.core.CheckRoomAccess(cmd.PlayerId, cmd.Password); err != nil {
+		return err
+	}
 	player := Player{
 		Id:   cmd.PlayerId,
 		Name: cmd.PlayerName,
@@ -181,6 +344,7 @@ func (g *Game) handleJoinCommand(cmd JoinCommand) error {
 
 // handleLeaveCommand 处理离开游戏指令
 func (g *Game) handleLeaveCommand(cmd LeaveCommand) error {
+	host := g.core.Room().Host
 	if err := g.core.Leave(cmd.PlayerId); err != nil {
 		return err
 	}
@@ -193,6 +357,11 @@ func (g *Game) handleLeaveCommand(cmd LeaveCommand) error {
 		Players:        g.core.Players(),
 	})
 
+	// 房主离开时通知新的房主
+	if g.core.Room().Host != host {
+		g.broadcastRoomUpdate()
+	}
+
 	return nil
 }
 
@@ -208,16 +377,28 @@ func (g *Game) handleMoveCommand(cmd MoveCommand) error {
 		return err
 	}
 
-	// 发布地图更新事件
-	g.forwardBroadcastEvent(MapUpdateEvent{
-		BroadcastEvent: BroadcastEvent{},
-		Map:            g.core.Map(),
-		TurnNumber:     g.core.TurnNumber(),
-	})
-
+	g.publishMapUpdates()
 	return nil
 }
 
+// publishMapUpdates 向每名玩家发布其视野内的地图，观战事件流中放入完整地图。
+// 地图不经过广播主题，以免玩家看到视野外的格子。
+func (g *Game) publishMapUpdates() {
+	turn := g.core.TurnNumber()
+	for _, player := range g.core.Players() {
+		view, err := g.core.PlayerMap(player.Id)
+		if err != nil {
+			slog.Error("failed to build player view", "error", err, "player", player.Id, "gameId", g.gameId)
+			continue
+		}
+		g.publishPlayerEvent(player.Id, MapUpdateEvent{PlayerEvent: PlayerEvent{}, Map: view, TurnNumber: turn})
+	}
+
+	if g.spectatorCount.Load() > 0 {
+		g.spectatorFeed.push(MapUpdateEvent{PlayerEvent: PlayerEvent{}, Map: g.core.Map(), TurnNumber: turn})
+	}
+}
+
 // handleForceStartCommand 处理强制开始指令
 func (g *Game) handleForceStartCommand(cmd ForceStartCommand) error {
 	if err := g.core.ForceStart(cmd.PlayerId, cmd.IsVote); err != nil {
@@ -253,13 +434,149 @@ func (g *Game) handleSurrenderCommand(cmd SurrenderCommand) error {
 	return nil
 }
 
+// handleDisconnectCommand 处理玩家连接断开，玩家需在重连期限内重新连接
+func (g *Game) handleDisconnectCommand(cmd DisconnectCommand) error {
+	if err := g.core.PlayerDisconnect(cmd.PlayerId); err != nil {
+		// 连接已断开，错误无法送达玩家
+		slog.Debug("ignoring disconnect", "error", err, "gameId", g.gameId)
+		return nil
+	}
+
+	g.broadcastStatusUpdate()
+	return nil
+}
+
+// handleReconnectCommand 处理玩家重新连接
+func (g *Game) handleReconnectCommand(cmd ReconnectCommand) error {
+	if err := g.core.PlayerReconnect(cmd.PlayerId); err != nil {
+		return err
+	}
+
+	g.broadcastStatusUpdate()
+	return nil
+}
+
+// handleResumeCommand 回复玩家视角的状态快照与 LastSeq 之后错过的广播事件，断线中的玩家同时恢复连接
+func (g *Game) handleResumeCommand(cmd ResumeCommand) error {
+	player, err := g.core.GetPlayer(cmd.PlayerId)
+	if err != nil {
+		return err
+	}
+	if player.Connection.DisconnectedAt != 0 {
+		if err := g.core.PlayerReconnect(cmd.PlayerId); err != nil {
+			return err
+		}
+		g.broadcastStatusUpdate()
+	}
+
+	view, err := g.core.PlayerMap(cmd.PlayerId)
+	if err != nil {
+		return fmt.Errorf("failed to build player view: %w", err)
+	}
+
+	missed, seq, complete := g.history.since(cmd.LastSeq)
+	missed = visibleTo(missed, cmd.PlayerId)
+
+	g.publishPlayerEvent(cmd.PlayerId, ResumeEvent{
+		PlayerEvent: PlayerEvent{},
+		PlayerId:    cmd.PlayerId,
+		GameStatus:  g.core.Status(),
+		TurnNumber:  g.core.TurnNumber(),
+		Players:     g.core.Players(),
+		Map:         view,
+		Seq:         seq,
+		Missed:      missed,
+		Complete:    complete,
+	})
+	return nil
+}
+
+// handleSpectateCommand 加入观战，并将完整视野快照放入观战事件流
+func (g *Game) handleSpectateCommand(cmd SpectateCommand) error {
+	if err := g.core.CheckRoomAccess(cmd.PlayerId, cmd.Password); err != nil {
+		return err
+	}
+	spectator := Spectator{Id: cmd.PlayerId, Name: cmd.SpectatorName}
+	if err := g.core.Spectate(spectator); err != nil {
+		return err
+	}
+	g.spectatorCount.Store(int32(len(g.core.Spectators())))
+
+	// 等待中的对局尚未生成地图
+	var m gamemap.Map
+	if g.core.Status() != StatusWaiting {
+		m = g.core.Map()
+	}
+	g.spectatorFeed.push(SpectatorSnapshotEvent{
+		SpectatorId: cmd.PlayerId,
+		GameStatus:  g.core.Status(),
+		TurnNumber:  g.core.TurnNumber(),
+		Players:     g.core.Players(),
+		Spectators:  len(g.core.Spectators()),
+		Map:         m,
+		Seq:         g.history.last(),
+	})
+
+	g.broadcastStatusUpdate()
+	return nil
+}
+
+// handleStopSpectatingCommand 观战者离开
+func (g *Game) handleStopSpectatingCommand(cmd StopSpectatingCommand) error {
+	if err := g.core.StopSpectating(cmd.PlayerId); err != nil {
+		return err
+	}
+	g.spectatorCount.Store(int32(len(g.core.Spectators())))
+
+	g.broadcastStatusUpdate()
+	return nil
+}
+
+// checkDisconnectedPlayers 将超过重连期限的断线玩家转为观战，有玩家状态变化时广播
+func (g *Game) checkDisconnectedPlayers(now time.Time) {
+	disconnected := g.countDisconnected()
+	if err := g.core.CheckDisconnectedPlayers(now.UnixMilli()); err != nil {
+		slog.Error("failed to check disconnected players", "error", err, "gameId", g.gameId)
+		return
+	}
+	if g.countDisconnected() != disconnected {
+		g.broadcastStatusUpdate()
+	}
+}
+
+func (g *Game) countDisconnected() int {
+	count := 0
+	for _, p := range g.core.Players() {
+		if p.Status == PlayerStatusDisconnected {
+			count++
+		}
+	}
+	return count
+}
+
+func (g *Game) broadcastStatusUpdate() {
+	g.forwardBroadcastEvent(GameStatusUpdateEvent{
+		BroadcastEvent: BroadcastEvent{},
+		Status:         g.core.Status(),
+		Players:        g.core.Players(),
+		TurnNumber:     g.core.TurnNumber(),
+		Spectators:     len(g.core.Spectators()),
+	})
+}
+
 // =============================================================================
 // 事件转发方法
 // =============================================================================
 
-// forwardBroadcastEvent 转发广播事件
+// forwardBroadcastEvent 为广播事件分配序号并转发，有观战者时同时放入观战事件流
 func (g *Game) forwardBroadcastEvent(event queue.Event) {
-	g.queue.Publish(fmt.Sprintf("%s/broadcast", g.gameId), event)
+	topic := fmt.Sprintf("%s/broadcast", g.gameId)
+	g.history.append(event, func(sequenced queue.Event) {
+		g.queue.Publish(topic, sequenced)
+		if g.spectatorCount.Load() > 0 {
+			g.spectatorFeed.push(sequenced)
+		}
+	})
 }
 
 // forwardControlEvent 转发控制事件
@@ -279,7 +596,21 @@ func (g *Game) publishPlayerError(playerId string, err error) {
 		PlayerId:    playerId,
 		Error:       err.Error(),
 	}
-	g.queue.Publish(fmt.Sprintf("%s/player/%s", g.gameId, playerId), errorEvent)
+	g.publishPlayerEvent(playerId, errorEvent)
+}
+
+// publishJoinAck 确认加入、观战或恢复的结果，接入层据此开始或放弃转发对局事件
+func (g *Game) publishJoinAck(playerId string, err error) {
+	ack := JoinAckEvent{PlayerEvent: PlayerEvent{}, PlayerId: playerId}
+	if err != nil {
+		ack.Error = err.Error()
+	}
+	g.publishPlayerEvent(playerId, ack)
+}
+
+// publishPlayerEvent 发布只发给单个玩家的事件
+func (g *Game) publishPlayerEvent(playerId string, event queue.Event) {
+	g.queue.Publish(fmt.Sprintf("%s/player/%s", g.gameId, playerId), event)
 }
 
 // getPlayerIdFromCommand 从指令事件中提取玩家ID
@@ -295,6 +626,24 @@ func (g *Game) getPlayerIdFromCommand(event queue.Event) string {
 		return e.PlayerId
 	case SurrenderCommand:
 		return e.PlayerId
+	case ReconnectCommand:
+		return e.PlayerId
+	case ResumeCommand:
+		return e.PlayerId
+	case SpectateCommand:
+		return e.PlayerId
+	case StopSpectatingCommand:
+		return e.PlayerId
+	case ChatCommand:
+		return e.PlayerId
+	case KickCommand:
+		return e.PlayerId
+	case TransferHostCommand:
+		return e.PlayerId
+	case RoomSettingsCommand:
+		return e.PlayerId
+	case HostStartCommand:
+		return e.PlayerId
 	default:
 		return ""
 	}
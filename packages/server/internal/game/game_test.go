@@ -1,6 +1,7 @@
 package game
 
 import (
+	"server/internal/game/block"
 	gamemap "server/internal/game/map"
 	"server/internal/queue"
 	"testing"
@@ -91,7 +92,8 @@ func TestGame_EventHandling(t *testing.T) {
 		queue.Publish(gameId+"/commands", joinCmd)
 		time.Sleep(50 * time.Millisecond)
 
-		players := game.Core().Players()
+		var players []Player
+		game.View(func(core Core) { players = core.Players() })
 		if len(players) != 1 {
 			t.Errorf("Expected 1 player after join, got %d", len(players))
 		}
@@ -105,7 +107,8 @@ func TestGame_EventHandling(t *testing.T) {
 		queue.Publish(gameId+"/commands", leaveCmd)
 		time.Sleep(50 * time.Millisecond)
 
-		players := game.Core().Players()
+		var players []Player
+		game.View(func(core Core) { players = core.Players() })
 		if len(players) != 0 {
 			t.Errorf("Expected 0 players after leave, got %d", len(players))
 		}
@@ -170,3 +173,149 @@ func BenchmarkGame_CommandProcessing(b *testing.B) {
 		queue.Publish(gameId+"/commands", leaveCmd)
 	}
 }
+
+func TestGame_DisconnectTimeout(t *testing.T) {
+	q := queue.NewInMemoryQueue()
+	game := NewGameWithOptions("test-game-disconnect", q, TestMode, gamemap.NewMapManager(), Options{
+		ReconnectTimeout:        30 * time.Millisecond,
+		DisconnectCheckInterval: 5 * time.Millisecond,
+	})
+	game.core.Join(Player{Id: "player1", Name: "Player One"})
+	game.core.Join(Player{Id: "player2", Name: "Player Two"})
+	if err := game.core.Start(); err != nil {
+		t.Fatalf("Expected no error starting core, got %v", err)
+	}
+
+	broadcastCh := q.Subscribe("test-game-disconnect/broadcast")
+	game.Start()
+	defer game.Stop()
+
+	q.Publish("test-game-disconnect/commands", DisconnectCommand{CommandEvent: CommandEvent{PlayerId: "player1"}})
+
+	playerStatus := func() PlayerStatus {
+		var status PlayerStatus
+		game.View(func(core Core) {
+			player, _ := core.GetPlayer("player1")
+			status = player.Status
+		})
+		return status
+	}
+
+	// 回合定时器的事件可能先到达
+	timeout := time.After(time.Second)
+	for updated := false; !updated; {
+		select {
+		case event := <-broadcastCh:
+			_, updated = event.(GameStatusUpdateEvent)
+		case <-timeout:
+			t.Fatal("Expected status update after disconnect")
+		}
+	}
+	if status := playerStatus(); status != PlayerStatusDisconnected {
+		t.Fatalf("Expected status %s, got %s", PlayerStatusDisconnected, status)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for playerStatus() != PlayerStatusSpectator {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected spectator after reconnect timeout, got %s", playerStatus())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestGame_Resume(t *testing.T) {
+	q := queue.NewInMemoryQueue()
+	game := NewGameWithOptions("test-game-resume", q, TestMode, gamemap.NewMapManager(), Options{HistorySize: 8})
+	game.core.Join(Player{Id: "player1", Name: "Player One"})
+	game.core.Join(Player{Id: "player2", Name: "Player Two"})
+	if err := game.core.Start(); err != nil {
+		t.Fatalf("Expected no error starting core, got %v", err)
+	}
+	ownKing, enemyKing := gamemap.Pos{X: 1, Y: 1}, gamemap.Pos{X: 10, Y: 10}
+	game.core._map.SetBlock(ownKing, block.NewBlock(block.KingName, 5, 0))
+	game.core._map.SetBlock(enemyKing, block.NewBlock(block.KingName, 5, 1))
+	game.core.PlayerDisconnect("player1")
+
+	playerCh := q.Subscribe("test-game-resume/player/player1")
+	game.Start()
+	defer game.Stop()
+
+	q.Publish("test-game-resume/commands", ResumeCommand{CommandEvent: CommandEvent{PlayerId: "player1"}})
+
+	var resumed ResumeEvent
+	select {
+	case event := <-playerCh:
+		var ok bool
+		if resumed, ok = event.(ResumeEvent); !ok {
+			t.Fatalf("Expected ResumeEvent, got %T", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected resume reply")
+	}
+
+	if !resumed.Complete || resumed.Seq == 0 || len(resumed.Missed) != int(resumed.Seq) {
+		t.Errorf("Expected all %d events to be replayed, got %d (complete %v)", resumed.Seq, len(resumed.Missed), resumed.Complete)
+	}
+	if resumed.Missed[0].Type != "game.started" {
+		t.Errorf("Expected first missed event game.started, got %s", resumed.Missed[0].Type)
+	}
+	if resumed.Map == nil {
+		t.Fatal("Expected map snapshot")
+	}
+	for _, p := range resumed.Players {
+		if p.Id == "player1" && p.Status != PlayerStatusInGame {
+			t.Errorf("Expected player1 to be reconnected, got %s", p.Status)
+		}
+	}
+
+	// 只能看到己方王城，对手的王城被遮蔽
+	own, _ := resumed.Map.Block(ownKing)
+	if own == nil || own.Meta().Name != block.KingName {
+		t.Errorf("Expected own king to be visible, got %v", own)
+	}
+	enemy, _ := resumed.Map.Block(enemyKing)
+	if enemy != nil && enemy.Meta().Name == block.KingName {
+		t.Error("Expected enemy king to be fogged")
+	}
+}
+
+func TestGame_MapUpdatesAreFogged(t *testing.T) {
+	q := queue.NewInMemoryQueue()
+	game := NewGame("test-game-fog", q, TestMode, gamemap.NewMapManager())
+	game.core.Join(Player{Id: "player1", Name: "Player One"})
+	game.core.Join(Player{Id: "player2", Name: "Player Two"})
+	if err := game.core.Start(); err != nil {
+		t.Fatalf("Expected no error starting core, got %v", err)
+	}
+	defer game.core.Stop()
+	ownKing, enemyKing := gamemap.Pos{X: 1, Y: 1}, gamemap.Pos{X: 10, Y: 10}
+	game.core._map.SetBlock(ownKing, block.NewBlock(block.KingName, 5, 0))
+	game.core._map.SetBlock(enemyKing, block.NewBlock(block.KingName, 5, 1))
+
+	broadcastCh := q.Subscribe("test-game-fog/broadcast")
+	playerCh := q.Subscribe("test-game-fog/player/player1")
+	game.publishMapUpdates()
+
+	select {
+	case event := <-playerCh:
+		update, ok := event.(MapUpdateEvent)
+		if !ok {
+			t.Fatalf("Expected MapUpdateEvent, got %T", event)
+		}
+		if own, _ := update.Map.Block(ownKing); own == nil || own.Meta().Name != block.KingName {
+			t.Errorf("Expected own king to be visible, got %v", own)
+		}
+		if enemy, _ := update.Map.Block(enemyKing); enemy != nil && enemy.Meta().Name == block.KingName {
+			t.Error("Expected enemy king to be fogged")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected map update on player topic")
+	}
+
+	select {
+	case event := <-broadcastCh:
+		t.Errorf("Expected no map on broadcast topic, got %T", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
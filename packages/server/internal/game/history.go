@@ -0,0 +1,100 @@
+package game
+
+import (
+	"log/slog"
+	"reflect"
+	"server/internal/queue"
+	"sync"
+)
+
+// DefaultHistorySize 每个对局默认保留的广播事件数
+const DefaultHistorySize = 256
+
+// eventHistory 为广播事件与对局聊天消息分配递增序号，并在环形缓冲区中保留最近的事件供断线重连补发。
+// 事件在记录时即编码为信封，补发的是事件发生时的状态，而不是之后被事件循环修改过的玩家列表等数据。
+type eventHistory struct {
+	mu     sync.Mutex
+	source string
+	// events 为 nil 的位置表示该事件编码失败，无法补发
+	events []*queue.Envelope
+	// seq 最近一个事件的序号，从 1 开始，0 表示尚无事件
+	seq uint64
+}
+
+func newEventHistory(size int, source string) *eventHistory {
+	return &eventHistory{source: source, events: make([]*queue.Envelope, max(size, 0))}
+}
+
+// append 为事件分配序号并记录，publish 在锁内调用，保证订阅方按序号顺序收到事件。
+// 发布的是由信封解码出的副本，订阅方在其他 goroutine 中序列化时不会与事件循环共享数据。
+func (h *eventHistory) append(event queue.Event, publish func(queue.Event)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	event = withSeq(event, h.seq)
+
+	envelope, err := queue.NewEnvelope(event, h.source)
+	if err != nil {
+		slog.Error("failed to encode broadcast event", "error", err, "seq", h.seq)
+	} else if copied, err := envelope.Event(); err == nil {
+		event = copied
+	}
+	if len(h.events) > 0 {
+		h.events[(h.seq-1)%uint64(len(h.events))] = envelope
+	}
+	publish(event)
+}
+
+// last 返回最近一个事件的序号
+func (h *eventHistory) last() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.seq
+}
+
+// since 返回序号大于 lastSeq 的事件信封与当前序号。
+// 缓冲区已覆盖部分事件或有事件无法补发时 complete 为 false，客户端应以快照为准。
+func (h *eventHistory) since(lastSeq uint64) (events []queue.Envelope, seq uint64, complete bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if lastSeq >= h.seq {
+		return nil, h.seq, true
+	}
+
+	oldest := uint64(1)
+	if h.seq > uint64(len(h.events)) {
+		oldest = h.seq - uint64(len(h.events)) + 1
+	}
+	first := lastSeq + 1
+	complete = first >= oldest
+	if !complete {
+		first = oldest
+	}
+
+	for s := first; s <= h.seq; s++ {
+		envelope := h.events[(s-1)%uint64(len(h.events))]
+		if envelope == nil {
+			complete = false
+			continue
+		}
+		events = append(events, *envelope)
+	}
+	return events, h.seq, complete
+}
+
+// withSeq 返回设置了序号的事件副本，未嵌入 BroadcastEvent 的事件原样返回
+func withSeq(event queue.Event, seq uint64) queue.Event {
+	if event == nil {
+		return event
+	}
+	ptr := reflect.New(reflect.TypeOf(event))
+	ptr.Elem().Set(reflect.ValueOf(event))
+	sequenced, ok := ptr.Interface().(interface{ setSeq(uint64) })
+	if !ok {
+		return event
+	}
+	sequenced.setSeq(seq)
+	return ptr.Elem().Interface()
+}
@@ -0,0 +1,103 @@
+package game
+
+import (
+	"server/internal/queue"
+	"testing"
+)
+
+func appendTurns(h *eventHistory, from, to uint16) []queue.Event {
+	var published []queue.Event
+	for turn := from; turn <= to; turn++ {
+		h.append(TurnStartedEvent{TurnNumber: turn}, func(e queue.Event) {
+			published = append(published, e)
+		})
+	}
+	return published
+}
+
+func TestEventHistory_AssignsSequence(t *testing.T) {
+	h := newEventHistory(4, "test-game")
+	published := appendTurns(h, 1, 3)
+
+	for i, event := range published {
+		if seq := event.(TurnStartedEvent).Seq; seq != uint64(i+1) {
+			t.Errorf("Expected seq %d, got %d", i+1, seq)
+		}
+	}
+
+	// 没有嵌入 BroadcastEvent 的事件原样发布
+	h.append(PlayerErrorEvent{PlayerId: "p1"}, func(e queue.Event) {
+		if _, ok := e.(PlayerErrorEvent); !ok {
+			t.Errorf("Expected PlayerErrorEvent, got %T", e)
+		}
+	})
+}
+
+func turnOf(t *testing.T, envelope queue.Envelope) TurnStartedEvent {
+	t.Helper()
+	event, err := envelope.Event()
+	if err != nil {
+		t.Fatalf("Expected no error decoding, got %v", err)
+	}
+	return event.(TurnStartedEvent)
+}
+
+func TestEventHistory_Since(t *testing.T) {
+	h := newEventHistory(4, "test-game")
+	appendTurns(h, 1, 6)
+
+	t.Run("within_buffer", func(t *testing.T) {
+		events, seq, complete := h.since(3)
+		if seq != 6 || !complete {
+			t.Errorf("Expected seq 6 and complete, got %d %v", seq, complete)
+		}
+		if len(events) != 3 || turnOf(t, events[0]).TurnNumber != 4 {
+			t.Errorf("Expected turns 4-6, got %v", events)
+		}
+	})
+
+	t.Run("up_to_date", func(t *testing.T) {
+		events, _, complete := h.since(6)
+		if len(events) != 0 || !complete {
+			t.Errorf("Expected no events, got %v", events)
+		}
+	})
+
+	t.Run("overwritten", func(t *testing.T) {
+		events, _, complete := h.since(0)
+		if complete {
+			t.Error("Expected incomplete history after buffer wrapped")
+		}
+		if len(events) != 4 || turnOf(t, events[0]).Seq != 3 {
+			t.Errorf("Expected oldest retained seq 3, got %v", events)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		h := newEventHistory(0, "test-game")
+		appendTurns(h, 1, 2)
+		events, seq, complete := h.since(1)
+		if len(events) != 0 || seq != 2 || complete {
+			t.Errorf("Expected no retained events, got %v %d %v", events, seq, complete)
+		}
+	})
+}
+
+func TestEventHistory_RecordsStateAtAppend(t *testing.T) {
+	h := newEventHistory(4, "test-game")
+	players := []Player{{Id: "p1", Status: PlayerStatusInGame}}
+
+	var published queue.Event
+	h.append(GameStatusUpdateEvent{Players: players}, func(e queue.Event) { published = e })
+	// 事件循环之后修改玩家状态，不应影响已发布与已记录的事件
+	players[0].Status = PlayerStatusDisconnected
+
+	if got := published.(GameStatusUpdateEvent).Players[0].Status; got != PlayerStatusInGame {
+		t.Errorf("Expected published event to keep status at append, got %s", got)
+	}
+	events, _, _ := h.since(0)
+	recorded, _ := events[0].Event()
+	if got := recorded.(GameStatusUpdateEvent).Players[0].Status; got != PlayerStatusInGame {
+		t.Errorf("Expected recorded event to keep status at append, got %s", got)
+	}
+}
@@ -14,16 +14,9 @@ type BaseMapGenerator struct {
 }
 
 func NewBaseMapGenerator(config GeneratorConfig) *BaseMapGenerator {
-	var rng *rand.Rand
-	if config.Seed != 0 {
-		rng = rand.New(rand.NewSource(config.Seed))
-	} else {
-		rng = rand.New(rand.NewSource(rand.Int63()))
-	}
-
 	return &BaseMapGenerator{
 		config: config,
-		rng:    rng,
+		rng:    newGeneratorRng(config.Seed),
 	}
 }
 
@@ -34,11 +27,7 @@ func (g *BaseMapGenerator) Name() string {
 func (g *BaseMapGenerator) Generate(size Size, players []Player, config ...GeneratorConfig) (Map, error) {
 	if len(config) > 0 {
 		g.config = config[0]
-		if config[0].Seed != 0 {
-			g.rng = rand.New(rand.NewSource(config[0].Seed))
-		} else {
-			g.rng = rand.New(rand.NewSource(rand.Int63()))
-		}
+		g.rng = newGeneratorRng(config[0].Seed)
 	}
 
 	mapId := fmt.Sprintf("generated-%d", g.config.Seed)
@@ -147,8 +136,6 @@ func (g *BaseMapGenerator) generatePlayerStartPositions(size Size, players []Pla
 func (g *BaseMapGenerator) generateTerrain(gameMap *BaseMap) error {
 	size := gameMap.Size()
 
-	noiseMap := g.generatePerlinNoise(int(size.Width), int(size.Height))
-
 	mountainThreshold := 0.8 - (g.config.MountainDensity * 0.6)
 	castleThreshold := 0.6 - (g.config.CastleDensity * 0.4)
 
@@ -160,6 +147,7 @@ func (g *BaseMapGenerator) generateTerrain(gameMap *BaseMap) error {
 
 	for attempt := 0; attempt < maxAttempts; attempt++ {
 		castlePositions = nil
+		noiseMap := g.generatePerlinNoise(int(size.Width), int(size.Height))
 
 		for y := uint16(1); y <= size.Height; y++ {
 			for x := uint16(1); x <= size.Width; x++ {
@@ -188,7 +176,7 @@ func (g *BaseMapGenerator) generateTerrain(gameMap *BaseMap) error {
 			}
 		}
 
-		if g.validateMap(gameMap, castlePositions) {
+		if g.validateTerrain(gameMap, castlePositions) {
 			break
 		}
 
@@ -209,6 +197,10 @@ func (g *BaseMapGenerator) generatePerlinNoise(width, height int) [][]float64 {
 	persistence := 0.5
 	lacunarity := 2.0
 
+	// 噪声采样起点由种子决定，不同种子得到不同地形
+	offsetX := g.rng.Float64() * 1000
+	offsetY := g.rng.Float64() * 1000
+
 	for y := 0; y < height; y++ {
 		for x := 0; x < width; x++ {
 			amplitude := 1.0
@@ -217,7 +209,7 @@ func (g *BaseMapGenerator) generatePerlinNoise(width, height int) [][]float64 {
 			maxValue := 0.0
 
 			for i := 0; i < octaves; i++ {
-				noiseValue += g.perlin(float64(x)*frequency, float64(y)*frequency) * amplitude
+				noiseValue += g.perlin(float64(x)*frequency+offsetX, float64(y)*frequency+offsetY) * amplitude
 				maxValue += amplitude
 				amplitude *= persistence
 				frequency *= lacunarity
@@ -287,23 +279,47 @@ func (g *BaseMapGenerator) canPlaceCastle(pos Pos, existing []Pos, minDistance i
 	return true
 }
 
-func (g *BaseMapGenerator) validateMap(gameMap *BaseMap, castlePositions []Pos) bool {
-	if len(castlePositions) < 2 {
+func (g *BaseMapGenerator) validateTerrain(gameMap *BaseMap, castlePositions []Pos) bool {
+	if !validateMap(gameMap, castlePositions) {
 		return false
 	}
 
+	// base 地形额外限制山脉在内的不可达格子总比例
 	size := gameMap.Size()
 	totalCells := int(size.Width * size.Height)
 
-	reachableCells := g.bfsReachability(gameMap, castlePositions[0])
+	reachableCells := bfsReachability(gameMap, castlePositions[0])
 	unreachableRatio := float64(totalCells-reachableCells) / float64(totalCells)
 
-	if unreachableRatio > 0.1 {
+	return unreachableRatio <= 0.1
+}
+
+// validateMap 检查关键位置（出生点、城堡等）均可从第一个位置到达，
+// 且被山脉隔绝的可通行格子比例不超过 maxIsolatedRatio
+func validateMap(gameMap Map, positions []Pos) bool {
+	if len(positions) < 2 {
+		return false
+	}
+
+	passableCells := 0
+	size := gameMap.Size()
+	for y := uint16(1); y <= size.Height; y++ {
+		for x := uint16(1); x <= size.Width; x++ {
+			b, _ := gameMap.Block(Pos{X: x, Y: y})
+			if b == nil || b.Meta().Name != block.MountainName {
+				passableCells++
+			}
+		}
+	}
+
+	reachableCells := bfsReachability(gameMap, positions[0])
+	isolatedRatio := float64(passableCells-reachableCells) / float64(passableCells)
+	if isolatedRatio > maxIsolatedRatio {
 		return false
 	}
 
-	for i := 1; i < len(castlePositions); i++ {
-		if !g.isReachable(gameMap, castlePositions[0], castlePositions[i]) {
+	for i := 1; i < len(positions); i++ {
+		if !isReachable(gameMap, positions[0], positions[i]) {
 			return false
 		}
 	}
@@ -311,7 +327,7 @@ func (g *BaseMapGenerator) validateMap(gameMap *BaseMap, castlePositions []Pos)
 	return true
 }
 
-func (g *BaseMapGenerator) bfsReachability(gameMap *BaseMap, start Pos) int {
+func bfsReachability(gameMap Map, start Pos) int {
 	size := gameMap.Size()
 	visited := make([][]bool, size.Height)
 	for i := range visited {
@@ -373,7 +389,7 @@ func (g *BaseMapGenerator) bfsReachability(gameMap *BaseMap, start Pos) int {
 	return count
 }
 
-func (g *BaseMapGenerator) isReachable(gameMap *BaseMap, start, end Pos) bool {
+func isReachable(gameMap Map, start, end Pos) bool {
 	size := gameMap.Size()
 	visited := make([][]bool, size.Height)
 	for i := range visited {
@@ -0,0 +1,302 @@
+package gamemap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"server/internal/game/block"
+	"strings"
+)
+
+const (
+	fileMapExt = ".json"
+
+	terrainBlank    = '.'
+	terrainMountain = '#'
+)
+
+// FileMap 自定义地图文件格式
+//
+// 地形使用字符串逐行描述：'.' 为空地，'#' 为山脉。
+// 城堡与出生点单独列出，坐标与 Pos 一致，从 1 开始。
+type FileMap struct {
+	Name       string       `json:"name"`
+	Desc       string       `json:"desc"`
+	MinPlayers int          `json:"minPlayers"`
+	MaxPlayers int          `json:"maxPlayers"`
+	Terrain    []string     `json:"terrain"`
+	Castles    []FileCastle `json:"castles"`
+	Spawns     []Pos        `json:"spawns"`
+}
+
+// FileCastle 地图文件中的城堡，Num 为初始驻军，0 表示使用默认值
+type FileCastle struct {
+	X   uint16    `json:"x"`
+	Y   uint16    `json:"y"`
+	Num block.Num `json:"num"`
+}
+
+// FileProvider 文件提供者，从目录中加载 file-<name> 地图
+type FileProvider struct {
+	basePath string
+}
+
+func NewFileProvider(basePath string) *FileProvider {
+	return &FileProvider{
+		basePath: basePath,
+	}
+}
+
+func (p *FileProvider) CanHandle(prefix string) bool {
+	return prefix == "file" || prefix == "custom"
+}
+
+// Cacheable 王城位置取决于参与的玩家，而缓存只按地图 id 区分，因此不缓存
+func (p *FileProvider) Cacheable() bool {
+	return false
+}
+
+func (p *FileProvider) GetMap(mapId string, players []Player) (Map, error) {
+	prefix, name, found := strings.Cut(mapId, "-")
+	if !found || !p.CanHandle(prefix) {
+		return nil, fmt.Errorf("invalid file map id: %s", mapId)
+	}
+
+	fileMap, err := p.load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return fileMap.Build(mapId, players)
+}
+
+func (p *FileProvider) ValidateMap(mapId string) error {
+	prefix, name, found := strings.Cut(mapId, "-")
+	if !found || !p.CanHandle(prefix) {
+		return fmt.Errorf("invalid file map id: %s", mapId)
+	}
+	_, err := p.load(name)
+	return err
+}
+
+func (p *FileProvider) ListMaps() ([]Listing, error) {
+	entries, err := os.ReadDir(p.basePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read map directory: %w", err)
+	}
+
+	listings := make([]Listing, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != fileMapExt {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), fileMapExt)
+		fileMap, err := p.load(name)
+		if err != nil {
+			slog.Warn("skipping invalid map file", "file", entry.Name(), "error", err)
+			continue
+		}
+
+		size := fileMap.Size()
+		listings = append(listings, Listing{
+			Id:         "file-" + name,
+			Name:       fileMap.Name,
+			Desc:       fileMap.Desc,
+			Width:      size.Width,
+			Height:     size.Height,
+			MinPlayers: fileMap.MinPlayers,
+			MaxPlayers: fileMap.MaxPlayers,
+		})
+	}
+
+	return listings, nil
+}
+
+func (p *FileProvider) load(name string) (*FileMap, error) {
+	if !isValidMapName(name) {
+		return nil, fmt.Errorf("invalid map name: %q", name)
+	}
+
+	data, err := os.ReadFile(filepath.Join(p.basePath, name+fileMapExt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read map file: %w", err)
+	}
+
+	var fileMap FileMap
+	if err := json.Unmarshal(data, &fileMap); err != nil {
+		return nil, fmt.Errorf("failed to decode map file: %w", err)
+	}
+	if fileMap.Name == "" {
+		fileMap.Name = name
+	}
+
+	if err := fileMap.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid map %s: %w", name, err)
+	}
+
+	return &fileMap, nil
+}
+
+// Size 返回地形描述的尺寸
+func (f *FileMap) Size() Size {
+	if len(f.Terrain) == 0 {
+		return Size{}
+	}
+	return Size{Width: uint16(len(f.Terrain[0])), Height: uint16(len(f.Terrain))}
+}
+
+// Validate 检查地图格式、人数范围、出生点数量以及连通性
+func (f *FileMap) Validate() error {
+	if len(f.Terrain) == 0 || len(f.Terrain[0]) == 0 {
+		return errors.New("terrain is empty")
+	}
+
+	size := f.Size()
+	for y, row := range f.Terrain {
+		if len(row) != int(size.Width) {
+			return fmt.Errorf("terrain row %d has width %d, expected %d", y+1, len(row), size.Width)
+		}
+		for x, c := range row {
+			if c != terrainBlank && c != terrainMountain {
+				return fmt.Errorf("unknown terrain %q at (%d,%d)", c, x+1, y+1)
+			}
+		}
+	}
+
+	if f.MinPlayers == 0 {
+		f.MinPlayers = 2
+	}
+	if f.MaxPlayers == 0 {
+		f.MaxPlayers = len(f.Spawns)
+	}
+	if f.MinPlayers < 1 || f.MaxPlayers < f.MinPlayers {
+		return fmt.Errorf("invalid player range: %d-%d", f.MinPlayers, f.MaxPlayers)
+	}
+	if len(f.Spawns) < f.MaxPlayers {
+		return fmt.Errorf("map has %d spawns but allows %d players", len(f.Spawns), f.MaxPlayers)
+	}
+
+	occupied := make(map[Pos]bool)
+	checkPos := func(kind string, pos Pos) error {
+		if !size.IsPosValid(pos) {
+			return fmt.Errorf("%s out of bounds: %s", kind, pos.String())
+		}
+		if f.Terrain[pos.Y-1][pos.X-1] == terrainMountain {
+			return fmt.Errorf("%s placed on mountain: %s", kind, pos.String())
+		}
+		if occupied[pos] {
+			return fmt.Errorf("%s overlaps another object: %s", kind, pos.String())
+		}
+		occupied[pos] = true
+		return nil
+	}
+
+	for _, spawn := range f.Spawns {
+		if err := checkPos("spawn", spawn); err != nil {
+			return err
+		}
+	}
+	for _, castle := range f.Castles {
+		if err := checkPos("castle", Pos{X: castle.X, Y: castle.Y}); err != nil {
+			return err
+		}
+	}
+
+	terrainMap := f.terrainMap("")
+	start := f.Spawns[0]
+	for pos := range occupied {
+		if !isReachable(terrainMap, start, pos) {
+			return fmt.Errorf("%s is not reachable from first spawn", pos.String())
+		}
+	}
+
+	return nil
+}
+
+// Build 根据参与的玩家生成可用于对局的地图，玩家按顺序占用出生点
+func (f *FileMap) Build(mapId string, players []Player) (Map, error) {
+	activePlayers := make([]Player, 0, len(players))
+	for _, player := range players {
+		if player.IsActive {
+			activePlayers = append(activePlayers, player)
+		}
+	}
+
+	if len(activePlayers) < f.MinPlayers || len(activePlayers) > f.MaxPlayers {
+		return nil, fmt.Errorf("map %s supports %d-%d players, got %d",
+			f.Name, f.MinPlayers, f.MaxPlayers, len(activePlayers))
+	}
+
+	gameMap := f.terrainMap(mapId)
+
+	// 未指定驻军的城堡使用由地图 id 决定的随机数，同一地图每局结果一致
+	rng := rand.New(rand.NewSource(seedFromId(mapId)))
+	for _, castle := range f.Castles {
+		num := castle.Num
+		if num == 0 {
+			num = block.RandomCastleNum(rng)
+		}
+		castleBlock := block.NewBlock(block.CastleName, num, 0)
+		if err := gameMap.SetBlock(Pos{X: castle.X, Y: castle.Y}, castleBlock); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, player := range activePlayers {
+		kingBlock := block.NewBlock(block.KingName, 1, player.Owner)
+		if err := gameMap.SetBlock(f.Spawns[i], kingBlock); err != nil {
+			return nil, err
+		}
+	}
+
+	return gameMap, nil
+}
+
+func seedFromId(mapId string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(mapId))
+	return int64(h.Sum64())
+}
+
+func (f *FileMap) terrainMap(mapId string) *BaseMap {
+	info := Info{
+		Id:   mapId,
+		Name: f.Name,
+		Desc: f.Desc,
+	}
+
+	gameMap := NewEmptyBaseMap(f.Size(), info)
+	for y, row := range f.Terrain {
+		for x, c := range row {
+			name := block.BlankName
+			if c == terrainMountain {
+				name = block.MountainName
+			}
+			gameMap.SetBlock(Pos{X: uint16(x + 1), Y: uint16(y + 1)}, block.NewBlock(name, 0, 0))
+		}
+	}
+	return gameMap
+}
+
+func isValidMapName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"math/rand"
 	"server/internal/game/block"
 )
 
@@ -39,6 +41,8 @@ func (c GeneratorConfig) String() string {
 		c.MountainDensity, c.CastleDensity, c.MinCastleDistance, c.Seed)
 }
 
+const maxIsolatedRatio = 0.1
+
 type GeneratorFunc func(size Size, players []Player, config ...GeneratorConfig) (Map, error)
 
 type generatorEntry struct {
@@ -88,3 +92,65 @@ func GenerateMap(generatorName string, size Size, players []Player, config ...Ge
 
 	return entry.generator(size, players, config...)
 }
+
+// newGeneratorRng 根据配置创建随机数生成器，种子为 0 时使用随机种子
+func newGeneratorRng(seed int64) *rand.Rand {
+	if seed != 0 {
+		return rand.New(rand.NewSource(seed))
+	}
+	return rand.New(rand.NewSource(rand.Int63()))
+}
+
+func activePlayersOf(players []Player) []Player {
+	active := make([]Player, 0, len(players))
+	for _, p := range players {
+		if p.IsActive {
+			active = append(active, p)
+		}
+	}
+	return active
+}
+
+// circlePositions 在地图内切圆上均匀分布 count 个位置，inset 为距边缘的格数
+func circlePositions(size Size, count int, inset int, phase float64) []Pos {
+	cx := float64(size.Width+1) / 2
+	cy := float64(size.Height+1) / 2
+	rx := max(0, float64(size.Width-1)/2-float64(inset))
+	ry := max(0, float64(size.Height-1)/2-float64(inset))
+
+	positions := make([]Pos, count)
+	for i := range positions {
+		angle := phase + float64(i)*2*math.Pi/float64(count)
+		positions[i] = Pos{
+			X: uint16(math.Round(cx + rx*math.Cos(angle))),
+			Y: uint16(math.Round(cy + ry*math.Sin(angle))),
+		}
+	}
+	return positions
+}
+
+// placeKings 按顺序将活跃玩家的王城放在出生点上
+func placeKings(gameMap *BaseMap, players []Player, spawns []Pos) error {
+	for i, player := range players {
+		if i >= len(spawns) {
+			return fmt.Errorf("not enough spawns for %d players", len(players))
+		}
+		if err := gameMap.SetBlock(spawns[i], block.NewBlock(block.KingName, 1, player.Owner)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fillBlank 将未设置的格子填充为空地
+func fillBlank(gameMap *BaseMap) {
+	size := gameMap.Size()
+	for y := uint16(1); y <= size.Height; y++ {
+		for x := uint16(1); x <= size.Width; x++ {
+			pos := Pos{X: x, Y: y}
+			if existing, _ := gameMap.Block(pos); existing == nil {
+				gameMap.SetBlock(pos, block.NewBlock(block.BlankName, 0, 0))
+			}
+		}
+	}
+}
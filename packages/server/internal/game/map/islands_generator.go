@@ -0,0 +1,196 @@
+package gamemap
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"server/internal/game/block"
+	"slices"
+)
+
+const islandsMaxAttempts = 20
+
+// IslandsMapGenerator 群岛地图生成器
+//
+// 每个玩家拥有一座环形分布的岛屿，地图中央另有一座公共岛屿，岛屿之外均为山地。
+// 各玩家岛屿通过单格宽的桥梁连接中央岛屿，形成天然的咽喉要道。MountainDensity
+// 越高岛屿越小，CastleDensity 决定每座岛上的城堡数量。
+type IslandsMapGenerator struct {
+	config GeneratorConfig
+	rng    *rand.Rand
+}
+
+func NewIslandsMapGenerator(config GeneratorConfig) *IslandsMapGenerator {
+	return &IslandsMapGenerator{
+		config: config,
+		rng:    newGeneratorRng(config.Seed),
+	}
+}
+
+func (g *IslandsMapGenerator) Name() string {
+	return "islands"
+}
+
+func (g *IslandsMapGenerator) Generate(size Size, players []Player) (Map, error) {
+	activePlayers := activePlayersOf(players)
+	if len(activePlayers) < 2 {
+		return nil, errors.New("islands generator requires at least 2 active players")
+	}
+
+	radius := g.islandRadius(size, len(activePlayers))
+	if radius < 1 {
+		return nil, fmt.Errorf("map %s is too small for %d islands", size, len(activePlayers))
+	}
+
+	info := Info{
+		Id:   fmt.Sprintf("islands-%d", g.config.Seed),
+		Name: "Islands",
+		Desc: "Player islands linked to a central island by narrow bridges",
+	}
+
+	for attempt := 0; attempt < islandsMaxAttempts; attempt++ {
+		gameMap := NewEmptyBaseMap(size, info)
+		land := make(map[Pos]bool)
+
+		cx, cy := center(size)
+		middle := Pos{X: uint16(cx), Y: uint16(cy)}
+		spawns := circlePositions(size, len(activePlayers), radius, g.rng.Float64()*2*math.Pi)
+
+		g.raiseIsland(size, land, middle, radius)
+		for _, spawn := range spawns {
+			g.raiseIsland(size, land, spawn, radius)
+			g.buildBridge(land, spawn, middle)
+		}
+
+		for y := uint16(1); y <= size.Height; y++ {
+			for x := uint16(1); x <= size.Width; x++ {
+				pos := Pos{X: x, Y: y}
+				if !land[pos] {
+					gameMap.SetBlock(pos, block.NewBlock(block.MountainName, 0, 0))
+				}
+			}
+		}
+
+		castles := g.placeCastles(gameMap, land, spawns, middle, radius)
+		fillBlank(gameMap)
+
+		if !validateMap(gameMap, append(spawns, castles...)) {
+			continue
+		}
+
+		if err := placeKings(gameMap, activePlayers, spawns); err != nil {
+			return nil, err
+		}
+		return gameMap, nil
+	}
+
+	return nil, fmt.Errorf("failed to generate valid islands in %d attempts", islandsMaxAttempts)
+}
+
+// islandRadius 根据地图大小、玩家数量与山地密度计算岛屿半径
+func (g *IslandsMapGenerator) islandRadius(size Size, playerCount int) int {
+	shortSide := int(min(size.Width, size.Height))
+	radius := shortSide / (4 + playerCount/2)
+	radius -= int(g.config.MountainDensity * 2)
+	return min(radius, 6)
+}
+
+func (g *IslandsMapGenerator) raiseIsland(size Size, land map[Pos]bool, origin Pos, radius int) {
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			// 边缘随机缺角，让岛屿形状不那么规整
+			distance := max(dx, -dx) + max(dy, -dy)
+			if distance > radius || (distance == radius && g.rng.Intn(2) == 0) {
+				continue
+			}
+			x, y := int(origin.X)+dx, int(origin.Y)+dy
+			if x < 1 || y < 1 || x > int(size.Width) || y > int(size.Height) {
+				continue
+			}
+			land[Pos{X: uint16(x), Y: uint16(y)}] = true
+		}
+	}
+}
+
+// buildBridge 沿 L 形路径铺设单格宽的桥梁，先横向或先纵向随机决定
+func (g *IslandsMapGenerator) buildBridge(land map[Pos]bool, from, to Pos) {
+	step := func(a, b uint16) uint16 {
+		switch {
+		case a < b:
+			return a + 1
+		case a > b:
+			return a - 1
+		}
+		return a
+	}
+
+	current := from
+	horizontalFirst := g.rng.Intn(2) == 0
+	for current != to {
+		if (horizontalFirst && current.X != to.X) || current.Y == to.Y {
+			current.X = step(current.X, to.X)
+		} else {
+			current.Y = step(current.Y, to.Y)
+		}
+		land[current] = true
+	}
+}
+
+func (g *IslandsMapGenerator) placeCastles(gameMap *BaseMap, land map[Pos]bool, spawns []Pos, middle Pos, radius int) []Pos {
+	perIsland := 1 + int(g.config.CastleDensity*2)
+	origins := append([]Pos{middle}, spawns...)
+
+	var castles []Pos
+	for i, origin := range origins {
+		count := perIsland
+		if i == 0 {
+			count = perIsland + len(spawns)/2
+		}
+
+		var candidates []Pos
+		for pos := range land {
+			if manhattan(pos, origin) > radius || containsPos(spawns, pos) || nearAny(pos, spawns, 2) {
+				continue
+			}
+			candidates = append(candidates, pos)
+		}
+		// map 遍历顺序不稳定，排序后再洗牌以保证同一种子结果一致
+		slices.SortFunc(candidates, func(a, b Pos) int {
+			if a.Y != b.Y {
+				return int(a.Y) - int(b.Y)
+			}
+			return int(a.X) - int(b.X)
+		})
+		g.rng.Shuffle(len(candidates), func(a, b int) { candidates[a], candidates[b] = candidates[b], candidates[a] })
+
+		placed := 0
+		for _, pos := range candidates {
+			if placed >= count {
+				break
+			}
+			if nearAny(pos, castles, min(g.config.MinCastleDistance, radius)) {
+				continue
+			}
+			castleNum := g.rng.Intn(20) + 10
+			gameMap.SetBlock(pos, block.NewBlock(block.CastleName, block.Num(castleNum), 0))
+			castles = append(castles, pos)
+			placed++
+		}
+	}
+	return castles
+}
+
+func init() {
+	RegisterGenerator("islands", func(size Size, players []Player, config ...GeneratorConfig) (Map, error) {
+		var cfg GeneratorConfig
+		if len(config) > 0 {
+			cfg = config[0]
+		} else {
+			cfg = DefaultGeneratorConfig()
+		}
+
+		generator := NewIslandsMapGenerator(cfg)
+		return generator.Generate(size, players)
+	})
+}
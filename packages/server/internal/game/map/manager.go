@@ -3,6 +3,9 @@ package gamemap
 import (
 	"errors"
 	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
 	"strconv"
 	"strings"
 )
@@ -11,6 +14,9 @@ import (
 type MapManager interface {
 	GetMap(mapId string, players []Player) (Map, error)
 	GenerateMapId(generator string, size Size, playerCount int, config GeneratorConfig) string
+	ListMaps() []Listing
+	// ValidateMapId 检查地图 id 能否被某个提供者加载，不生成地图
+	ValidateMapId(mapId string) error
 }
 
 // MapProvider 地图提供者接口
@@ -19,6 +25,38 @@ type MapProvider interface {
 	GetMap(mapId string, players []Player) (Map, error)
 }
 
+// MapLister 可列出现成地图的提供者
+type MapLister interface {
+	ListMaps() ([]Listing, error)
+}
+
+// MapValidator 可在不生成地图的情况下检查 id 的提供者，未实现时只检查前缀
+type MapValidator interface {
+	ValidateMap(mapId string) error
+}
+
+// CacheableProvider 可声明其地图是否允许被缓存的提供者，未实现时默认缓存
+type CacheableProvider interface {
+	Cacheable() bool
+}
+
+// MapStore 持久化地图存储，由地图市场等外部服务实现
+type MapStore interface {
+	LoadMap(id string) (*FileMap, error)
+	RecordPlay(id string) error
+}
+
+// Listing 地图列表条目，供房间创建者选择
+type Listing struct {
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	Desc       string `json:"desc"`
+	Width      uint16 `json:"width"`
+	Height     uint16 `json:"height"`
+	MinPlayers int    `json:"minPlayers"`
+	MaxPlayers int    `json:"maxPlayers"`
+}
+
 // DefaultMapManager 默认地图管理器实现
 type DefaultMapManager struct {
 	providers []MapProvider
@@ -40,7 +78,11 @@ func (m *DefaultMapManager) RegisterProvider(provider MapProvider) {
 	m.providers = append(m.providers, provider)
 }
 
+// GenerateMapId 生成地图 id，种子为 0 时会先确定一个随机种子，使 id 能完整复现地图
 func (m *DefaultMapManager) GenerateMapId(generator string, size Size, playerCount int, config GeneratorConfig) string {
+	if config.Seed == 0 {
+		config.Seed = rand.Int63n(math.MaxInt32) + 1
+	}
 	return fmt.Sprintf("%s-%s-%d-%s", generator, size.String(), playerCount, config.String())
 }
 
@@ -63,7 +105,9 @@ func (m *DefaultMapManager) GetMap(mapId string, players []Player) (Map, error)
 				return nil, err
 			}
 
-			m.cache[mapId] = gameMap
+			if cacheable, ok := provider.(CacheableProvider); !ok || cacheable.Cacheable() {
+				m.cache[mapId] = gameMap
+			}
 			return gameMap, nil
 		}
 	}
@@ -71,6 +115,38 @@ func (m *DefaultMapManager) GetMap(mapId string, players []Player) (Map, error)
 	return nil, fmt.Errorf("no provider found for map id: %s", mapId)
 }
 
+func (m *DefaultMapManager) ValidateMapId(mapId string) error {
+	prefix, _, _ := strings.Cut(mapId, "-")
+	for _, provider := range m.providers {
+		if !provider.CanHandle(prefix) {
+			continue
+		}
+		if validator, ok := provider.(MapValidator); ok {
+			return validator.ValidateMap(mapId)
+		}
+		return nil
+	}
+	return fmt.Errorf("no provider found for map id: %s", mapId)
+}
+
+func (m *DefaultMapManager) ListMaps() []Listing {
+	listings := make([]Listing, 0)
+	for _, provider := range m.providers {
+		lister, ok := provider.(MapLister)
+		if !ok {
+			continue
+		}
+
+		providerListings, err := lister.ListMaps()
+		if err != nil {
+			slog.Warn("failed to list maps", "provider", fmt.Sprintf("%T", provider), "error", err)
+			continue
+		}
+		listings = append(listings, providerListings...)
+	}
+	return listings
+}
+
 // GeneratorProvider 生成器提供者
 type GeneratorProvider struct{}
 
@@ -78,56 +154,98 @@ func (p *GeneratorProvider) CanHandle(prefix string) bool {
 	return GeneratorExists(prefix)
 }
 
+// Cacheable 生成结果完全由 id 决定且重新生成开销很小，不缓存可避免多个对局共享同一份地图
+func (p *GeneratorProvider) Cacheable() bool {
+	return false
+}
+
 func (p *GeneratorProvider) GetMap(mapId string, players []Player) (Map, error) {
+	generator, size, config, err := parseGeneratorMapId(mapId)
+	if err != nil {
+		return nil, err
+	}
+	return GenerateMap(generator, size, players, config)
+}
+
+func (p *GeneratorProvider) ValidateMap(mapId string) error {
+	_, _, _, err := parseGeneratorMapId(mapId)
+	return err
+}
+
+// parseGeneratorMapId 解析 <generator>-<WxH>-<players>-<config> 格式的地图 id
+func parseGeneratorMapId(mapId string) (generator string, size Size, config GeneratorConfig, err error) {
 	parts := strings.Split(mapId, "-")
 	if len(parts) < 4 {
-		return nil, errors.New("invalid generator map id format")
+		return "", Size{}, config, errors.New("invalid generator map id format")
 	}
 
-	generator := parts[0]
-	sizeStr := parts[1]
-	_ = parts[2]
-	configStr := strings.Join(parts[3:], "-")
-
-	size, err := parseSize(sizeStr)
+	size, err = parseSize(parts[1])
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse size: %w", err)
+		return "", Size{}, config, fmt.Errorf("failed to parse size: %w", err)
 	}
 
-	config, err := parseGeneratorConfig(configStr)
+	config, err = parseGeneratorConfig(strings.Join(parts[3:], "-"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", err)
+		return "", Size{}, config, fmt.Errorf("failed to parse config: %w", err)
 	}
-
-	return GenerateMap(generator, size, players, config)
+	return parts[0], size, config, nil
 }
 
-// DatabaseProvider 数据库提供者示例
+// DatabaseProvider 数据库提供者，通过 db-<id> 加载地图市场中的地图
 type DatabaseProvider struct {
-	// db connection, etc.
+	store MapStore
+}
+
+func NewDatabaseProvider(store MapStore) *DatabaseProvider {
+	return &DatabaseProvider{
+		store: store,
+	}
 }
 
 func (p *DatabaseProvider) CanHandle(prefix string) bool {
 	return prefix == "db" || prefix == "saved"
 }
 
-func (p *DatabaseProvider) GetMap(mapId string, players []Player) (Map, error) {
-	// TODO: 从数据库加载地图
-	return nil, errors.New("database provider not implemented")
+// Cacheable 地图可能更新版本，且每次对局都需要记录游玩次数，因此不缓存
+func (p *DatabaseProvider) Cacheable() bool {
+	return false
 }
 
-// FileProvider 文件提供者示例
-type FileProvider struct {
-	basePath string
+func (p *DatabaseProvider) GetMap(mapId string, players []Player) (Map, error) {
+	id, fileMap, err := p.load(mapId)
+	if err != nil {
+		return nil, err
+	}
+
+	gameMap, err := fileMap.Build(mapId, players)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.store.RecordPlay(id); err != nil {
+		slog.Warn("failed to record map play", "mapId", id, "error", err)
+	}
+
+	return gameMap, nil
 }
 
-func (p *FileProvider) CanHandle(prefix string) bool {
-	return prefix == "file" || prefix == "custom"
+// ValidateMap 只加载地图，不记录游玩次数
+func (p *DatabaseProvider) ValidateMap(mapId string) error {
+	_, _, err := p.load(mapId)
+	return err
 }
 
-func (p *FileProvider) GetMap(mapId string, players []Player) (Map, error) {
-	// TODO: 从文件加载地图
-	return nil, errors.New("file provider not implemented")
+func (p *DatabaseProvider) load(mapId string) (string, *FileMap, error) {
+	prefix, id, found := strings.Cut(mapId, "-")
+	if !found || !p.CanHandle(prefix) || id == "" {
+		return "", nil, fmt.Errorf("invalid database map id: %s", mapId)
+	}
+
+	fileMap, err := p.store.LoadMap(id)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load map %s: %w", id, err)
+	}
+	return id, fileMap, nil
 }
 
 // 辅助函数
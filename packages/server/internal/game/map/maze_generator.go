@@ -0,0 +1,261 @@
+package gamemap
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"server/internal/game/block"
+)
+
+const mazeMaxAttempts = 20
+
+// MazeMapGenerator 迷宫地图生成器
+//
+// 以奇数坐标为房间、偶数坐标为墙，用深度优先回溯挖出通道，再按 MountainDensity
+// 随机打通部分墙体形成回路（密度越低回路越多）。城堡优先放在死胡同里。
+type MazeMapGenerator struct {
+	config GeneratorConfig
+	rng    *rand.Rand
+}
+
+func NewMazeMapGenerator(config GeneratorConfig) *MazeMapGenerator {
+	return &MazeMapGenerator{
+		config: config,
+		rng:    newGeneratorRng(config.Seed),
+	}
+}
+
+func (g *MazeMapGenerator) Name() string {
+	return "maze"
+}
+
+func (g *MazeMapGenerator) Generate(size Size, players []Player) (Map, error) {
+	activePlayers := activePlayersOf(players)
+	if len(activePlayers) < 2 {
+		return nil, errors.New("maze generator requires at least 2 active players")
+	}
+	if size.Width < 5 || size.Height < 5 {
+		return nil, errors.New("maze requires at least 5x5")
+	}
+	// 房间位于奇数坐标，每个出生点需要独占一个房间
+	if rooms := int(size.Width+1) / 2 * (int(size.Height+1) / 2); rooms < len(activePlayers) {
+		return nil, fmt.Errorf("maze %s has %d rooms, not enough for %d players", size.String(), rooms, len(activePlayers))
+	}
+
+	info := Info{
+		Id:   fmt.Sprintf("maze-%d", g.config.Seed),
+		Name: "Maze",
+		Desc: "Corridors carved through mountain walls",
+	}
+
+	for attempt := 0; attempt < mazeMaxAttempts; attempt++ {
+		gameMap := NewEmptyBaseMap(size, info)
+		g.carve(gameMap)
+		g.braid(gameMap)
+
+		spawns, err := g.placeSpawns(gameMap, len(activePlayers))
+		if err != nil {
+			return nil, err
+		}
+		castles := g.placeCastles(gameMap, spawns)
+		fillBlank(gameMap)
+
+		if !validateMap(gameMap, append(spawns, castles...)) {
+			continue
+		}
+
+		if err := placeKings(gameMap, activePlayers, spawns); err != nil {
+			return nil, err
+		}
+		return gameMap, nil
+	}
+
+	return nil, fmt.Errorf("failed to generate a valid maze in %d attempts", mazeMaxAttempts)
+}
+
+func (g *MazeMapGenerator) isRoom(pos Pos) bool {
+	return pos.X%2 == 1 && pos.Y%2 == 1
+}
+
+func (g *MazeMapGenerator) carve(gameMap *BaseMap) {
+	size := gameMap.Size()
+	for y := uint16(1); y <= size.Height; y++ {
+		for x := uint16(1); x <= size.Width; x++ {
+			gameMap.SetBlock(Pos{X: x, Y: y}, block.NewBlock(block.MountainName, 0, 0))
+		}
+	}
+
+	start := Pos{X: 1, Y: 1}
+	visited := map[Pos]bool{start: true}
+	stack := []Pos{start}
+	gameMap.SetBlock(start, nil)
+
+	steps := [][2]int{{2, 0}, {-2, 0}, {0, 2}, {0, -2}}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+
+		var candidates []Pos
+		for _, step := range steps {
+			nx, ny := int(current.X)+step[0], int(current.Y)+step[1]
+			next := Pos{X: uint16(max(0, nx)), Y: uint16(max(0, ny))}
+			if nx < 1 || ny < 1 || !size.IsPosValid(next) || visited[next] {
+				continue
+			}
+			candidates = append(candidates, next)
+		}
+
+		if len(candidates) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		next := candidates[g.rng.Intn(len(candidates))]
+		wall := Pos{X: (current.X + next.X) / 2, Y: (current.Y + next.Y) / 2}
+		gameMap.SetBlock(wall, nil)
+		gameMap.SetBlock(next, nil)
+		visited[next] = true
+		stack = append(stack, next)
+	}
+}
+
+// braid 打通部分内墙以形成回路，避免迷宫只有唯一通路
+func (g *MazeMapGenerator) braid(gameMap *BaseMap) {
+	size := gameMap.Size()
+	openChance := 0.05 + (1-g.config.MountainDensity)*0.25
+
+	for y := uint16(1); y <= size.Height; y++ {
+		for x := uint16(1); x <= size.Width; x++ {
+			pos := Pos{X: x, Y: y}
+			if g.isRoom(pos) || (x%2 == 0 && y%2 == 0) {
+				continue
+			}
+			b, _ := gameMap.Block(pos)
+			if b == nil || g.rng.Float64() >= openChance {
+				continue
+			}
+
+			open := 0
+			for _, n := range neighbors(size, pos) {
+				if nb, _ := gameMap.Block(n); nb == nil {
+					open++
+				}
+			}
+			if open >= 2 {
+				gameMap.SetBlock(pos, nil)
+			}
+		}
+	}
+}
+
+func (g *MazeMapGenerator) placeSpawns(gameMap *BaseMap, count int) ([]Pos, error) {
+	size := gameMap.Size()
+	phase := g.rng.Float64()
+	spawns := make([]Pos, 0, count)
+
+	for _, target := range circlePositions(size, count, 1, phase) {
+		spawn, ok := g.nearestOpenRoom(gameMap, target, spawns)
+		if !ok {
+			return nil, fmt.Errorf("maze has no free room for spawn %d of %d", len(spawns)+1, count)
+		}
+		spawns = append(spawns, spawn)
+	}
+	return spawns, nil
+}
+
+// nearestOpenRoom 返回距 target 最近且未被占用的空房间，所有房间都已占用时 ok 为 false
+func (g *MazeMapGenerator) nearestOpenRoom(gameMap *BaseMap, target Pos, taken []Pos) (best Pos, ok bool) {
+	size := gameMap.Size()
+	bestDistance := -1
+
+	for y := uint16(1); y <= size.Height; y += 2 {
+		for x := uint16(1); x <= size.Width; x += 2 {
+			pos := Pos{X: x, Y: y}
+			if b, _ := gameMap.Block(pos); b != nil {
+				continue
+			}
+			if containsPos(taken, pos) {
+				continue
+			}
+			if d := manhattan(pos, target); bestDistance < 0 || d < bestDistance {
+				best, bestDistance = pos, d
+			}
+		}
+	}
+	return best, bestDistance >= 0
+}
+
+func (g *MazeMapGenerator) placeCastles(gameMap *BaseMap, spawns []Pos) []Pos {
+	size := gameMap.Size()
+	baseCastleCount := int(size.Width) * int(size.Height) / 50
+	targetCastleCount := int(float64(baseCastleCount) * (0.5 + g.config.CastleDensity))
+
+	var deadEnds, rooms []Pos
+	for y := uint16(1); y <= size.Height; y += 2 {
+		for x := uint16(1); x <= size.Width; x += 2 {
+			pos := Pos{X: x, Y: y}
+			if containsPos(spawns, pos) || nearAny(pos, spawns, 3) {
+				continue
+			}
+			open := 0
+			for _, n := range neighbors(size, pos) {
+				if nb, _ := gameMap.Block(n); nb == nil {
+					open++
+				}
+			}
+			if open == 1 {
+				deadEnds = append(deadEnds, pos)
+			} else {
+				rooms = append(rooms, pos)
+			}
+		}
+	}
+
+	g.rng.Shuffle(len(deadEnds), func(i, j int) { deadEnds[i], deadEnds[j] = deadEnds[j], deadEnds[i] })
+	g.rng.Shuffle(len(rooms), func(i, j int) { rooms[i], rooms[j] = rooms[j], rooms[i] })
+
+	var castles []Pos
+	for _, pos := range append(deadEnds, rooms...) {
+		if len(castles) >= targetCastleCount {
+			break
+		}
+		if nearAny(pos, castles, g.config.MinCastleDistance) {
+			continue
+		}
+		castleNum := g.rng.Intn(20) + 10
+		gameMap.SetBlock(pos, block.NewBlock(block.CastleName, block.Num(castleNum), 0))
+		castles = append(castles, pos)
+	}
+	return castles
+}
+
+func containsPos(positions []Pos, pos Pos) bool {
+	for _, p := range positions {
+		if p == pos {
+			return true
+		}
+	}
+	return false
+}
+
+func nearAny(pos Pos, positions []Pos, distance int) bool {
+	for _, p := range positions {
+		if manhattan(pos, p) < distance {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	RegisterGenerator("maze", func(size Size, players []Player, config ...GeneratorConfig) (Map, error) {
+		var cfg GeneratorConfig
+		if len(config) > 0 {
+			cfg = config[0]
+		} else {
+			cfg = DefaultGeneratorConfig()
+		}
+
+		generator := NewMazeMapGenerator(cfg)
+		return generator.Generate(size, players)
+	})
+}
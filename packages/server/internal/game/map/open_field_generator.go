@@ -0,0 +1,116 @@
+package gamemap
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"server/internal/game/block"
+)
+
+const openFieldMaxAttempts = 20
+
+// OpenFieldMapGenerator 开阔地图生成器
+//
+// 只有零星山地与少量城堡，出生点沿地图内切圆均匀分布，适合快节奏的正面交战。
+type OpenFieldMapGenerator struct {
+	config GeneratorConfig
+	rng    *rand.Rand
+}
+
+func NewOpenFieldMapGenerator(config GeneratorConfig) *OpenFieldMapGenerator {
+	return &OpenFieldMapGenerator{
+		config: config,
+		rng:    newGeneratorRng(config.Seed),
+	}
+}
+
+func (g *OpenFieldMapGenerator) Name() string {
+	return "open_field"
+}
+
+func (g *OpenFieldMapGenerator) Generate(size Size, players []Player) (Map, error) {
+	activePlayers := activePlayersOf(players)
+	if len(activePlayers) < 2 {
+		return nil, errors.New("open field generator requires at least 2 active players")
+	}
+
+	info := Info{
+		Id:   fmt.Sprintf("open_field-%d", g.config.Seed),
+		Name: "Open Field",
+		Desc: "Wide open terrain with scattered mountains",
+	}
+
+	for attempt := 0; attempt < openFieldMaxAttempts; attempt++ {
+		gameMap := NewEmptyBaseMap(size, info)
+		spawns := circlePositions(size, len(activePlayers), 1, g.rng.Float64()*2*math.Pi)
+
+		g.scatterMountains(gameMap, spawns)
+		castles := g.placeCastles(gameMap, spawns)
+		fillBlank(gameMap)
+
+		if !validateMap(gameMap, append(spawns, castles...)) {
+			continue
+		}
+
+		if err := placeKings(gameMap, activePlayers, spawns); err != nil {
+			return nil, err
+		}
+		return gameMap, nil
+	}
+
+	return nil, fmt.Errorf("failed to generate a valid open field in %d attempts", openFieldMaxAttempts)
+}
+
+func (g *OpenFieldMapGenerator) scatterMountains(gameMap *BaseMap, spawns []Pos) {
+	size := gameMap.Size()
+	chance := 0.02 + g.config.MountainDensity*0.06
+
+	for y := uint16(1); y <= size.Height; y++ {
+		for x := uint16(1); x <= size.Width; x++ {
+			pos := Pos{X: x, Y: y}
+			if nearAny(pos, spawns, 2) || g.rng.Float64() >= chance {
+				continue
+			}
+			gameMap.SetBlock(pos, block.NewBlock(block.MountainName, 0, 0))
+		}
+	}
+}
+
+func (g *OpenFieldMapGenerator) placeCastles(gameMap *BaseMap, spawns []Pos) []Pos {
+	size := gameMap.Size()
+	baseCastleCount := int(size.Width) * int(size.Height) / 100
+	targetCastleCount := max(1, int(float64(baseCastleCount)*(0.5+g.config.CastleDensity)))
+
+	var castles []Pos
+	for tries := 0; len(castles) < targetCastleCount && tries < targetCastleCount*20; tries++ {
+		pos := Pos{
+			X: uint16(g.rng.Intn(int(size.Width)) + 1),
+			Y: uint16(g.rng.Intn(int(size.Height)) + 1),
+		}
+		if existing, _ := gameMap.Block(pos); existing != nil {
+			continue
+		}
+		if nearAny(pos, spawns, 3) || nearAny(pos, castles, g.config.MinCastleDistance) {
+			continue
+		}
+		castleNum := g.rng.Intn(20) + 10
+		gameMap.SetBlock(pos, block.NewBlock(block.CastleName, block.Num(castleNum), 0))
+		castles = append(castles, pos)
+	}
+	return castles
+}
+
+func init() {
+	RegisterGenerator("open_field", func(size Size, players []Player, config ...GeneratorConfig) (Map, error) {
+		var cfg GeneratorConfig
+		if len(config) > 0 {
+			cfg = config[0]
+		} else {
+			cfg = DefaultGeneratorConfig()
+		}
+
+		generator := NewOpenFieldMapGenerator(cfg)
+		return generator.Generate(size, players)
+	})
+}
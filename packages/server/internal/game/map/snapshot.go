@@ -0,0 +1,49 @@
+package gamemap
+
+import "server/internal/game/block"
+
+// Snapshot 可序列化的地图快照，用于跨进程传输地图状态
+type Snapshot struct {
+	Info   Info
+	Size   Size
+	Blocks [][]BlockSnapshot
+}
+
+// BlockSnapshot 空白格的 Name 为空
+type BlockSnapshot struct {
+	Name  block.Name  `json:",omitempty"`
+	Num   block.Num   `json:",omitempty"`
+	Owner block.Owner `json:",omitempty"`
+}
+
+func NewSnapshot(m Map) Snapshot {
+	snapshot := Snapshot{Info: m.Info(), Size: m.Size()}
+
+	blocks := m.Blocks()
+	snapshot.Blocks = make([][]BlockSnapshot, len(blocks))
+	for y, row := range blocks {
+		snapshot.Blocks[y] = make([]BlockSnapshot, len(row))
+		for x, b := range row {
+			if b == nil {
+				continue
+			}
+			snapshot.Blocks[y][x] = BlockSnapshot{Name: b.Meta().Name, Num: b.Num(), Owner: b.Owner()}
+		}
+	}
+	return snapshot
+}
+
+// Map 按快照重建地图
+func (s Snapshot) Map() Map {
+	blocks := make(Blocks, len(s.Blocks))
+	for y, row := range s.Blocks {
+		blocks[y] = make([]block.Block, len(row))
+		for x, b := range row {
+			if b.Name == "" {
+				continue
+			}
+			blocks[y][x] = block.NewBlock(b.Name, b.Num, b.Owner)
+		}
+	}
+	return NewBaseMap(blocks, s.Size, s.Info)
+}
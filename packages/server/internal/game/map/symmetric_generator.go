@@ -0,0 +1,458 @@
+package gamemap
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"server/internal/game/block"
+	"slices"
+)
+
+const (
+	symmetricMaxAttempts       = 50
+	symmetricFairnessTolerance = 0.1
+	symmetricRoundingSlack     = 2
+)
+
+// SymmetricMapGenerator 对称地图生成器，适用于竞技对局
+//
+// 地形和城堡在各出生点之间镜像或旋转复制：2 人使用中心对称，4 人使用旋转（方形地图）
+// 或双轴镜像，其它人数按极坐标旋转近似对称。生成后校验各出生点的路径距离与城堡可达性，
+// 不满足容差时重新生成。
+type SymmetricMapGenerator struct {
+	config    GeneratorConfig
+	rng       *rand.Rand
+	tolerance float64
+}
+
+// FairnessReport 各出生点的公平性指标，下标与出生点一一对应
+type FairnessReport struct {
+	// NearestOpponent 到最近对手出生点的路径距离
+	NearestOpponent []int
+	// CastleDistance 到最近若干城堡的路径距离之和
+	CastleDistance []int
+	// CastleShare 距离该出生点最近的城堡数量
+	CastleShare []int
+	// Reachable 所有出生点与城堡是否相互可达
+	Reachable bool
+}
+
+func NewSymmetricMapGenerator(config GeneratorConfig) *SymmetricMapGenerator {
+	return &SymmetricMapGenerator{
+		config:    config,
+		rng:       newGeneratorRng(config.Seed),
+		tolerance: symmetricFairnessTolerance,
+	}
+}
+
+func (g *SymmetricMapGenerator) Name() string {
+	return "symmetric"
+}
+
+func (g *SymmetricMapGenerator) Generate(size Size, players []Player) (Map, error) {
+	activePlayers := activePlayersOf(players)
+	if len(activePlayers) < 2 {
+		return nil, errors.New("symmetric generator requires at least 2 active players")
+	}
+
+	info := Info{
+		Id:   fmt.Sprintf("symmetric-%d", g.config.Seed),
+		Name: "Symmetric Map",
+		Desc: "Procedurally generated symmetric map for competitive play",
+	}
+
+	for attempt := 0; attempt < symmetricMaxAttempts; attempt++ {
+		gameMap, spawns, castles := g.generateAttempt(size, info, len(activePlayers))
+		if gameMap == nil {
+			continue
+		}
+
+		report := EvaluateFairness(gameMap, spawns, castles)
+		if !report.WithinTolerance(g.tolerance) {
+			continue
+		}
+
+		if err := placeKings(gameMap, activePlayers, spawns); err != nil {
+			return nil, err
+		}
+		return gameMap, nil
+	}
+
+	return nil, fmt.Errorf("failed to generate a fair map for %d players in %d attempts",
+		len(activePlayers), symmetricMaxAttempts)
+}
+
+func (g *SymmetricMapGenerator) generateAttempt(size Size, info Info, playerCount int) (*BaseMap, []Pos, []Pos) {
+	gameMap := NewEmptyBaseMap(size, info)
+	if gameMap == nil {
+		return nil, nil, nil
+	}
+
+	transform := symmetryTransform(size, playerCount)
+
+	spawns := g.placeSpawns(size, playerCount, transform)
+	if spawns == nil {
+		return nil, nil, nil
+	}
+
+	reserved := make(map[Pos]bool)
+	for _, spawn := range spawns {
+		for _, pos := range append(neighbors(size, spawn), spawn) {
+			reserved[pos] = true
+		}
+	}
+
+	// 山脉：随机顺序遍历，每个格子连同其对称像一起决定
+	mountainChance := 0.1 + g.config.MountainDensity*0.25
+	for _, idx := range g.rng.Perm(int(size.Width) * int(size.Height)) {
+		pos := Pos{X: uint16(idx%int(size.Width)) + 1, Y: uint16(idx/int(size.Width)) + 1}
+		if existing, _ := gameMap.Block(pos); existing != nil || reserved[pos] {
+			continue
+		}
+
+		name := block.BlankName
+		if g.rng.Float64() < mountainChance {
+			name = block.MountainName
+		}
+
+		for k := 0; k < playerCount; k++ {
+			image, ok := transform(pos, k)
+			if !ok || reserved[image] {
+				continue
+			}
+			if existing, _ := gameMap.Block(image); existing == nil {
+				gameMap.SetBlock(image, block.NewBlock(name, 0, 0))
+			}
+		}
+	}
+
+	castles := g.placeCastles(gameMap, playerCount, spawns, transform)
+	fillBlank(gameMap)
+
+	return gameMap, spawns, castles
+}
+
+func (g *SymmetricMapGenerator) placeSpawns(size Size, playerCount int, transform symmetryFunc) []Pos {
+	margin := max(1, int(min(size.Width, size.Height))/8)
+
+	var first Pos
+	if isExactSymmetry(playerCount) {
+		first = Pos{
+			X: uint16(1 + margin + g.rng.Intn(2)),
+			Y: uint16(1 + margin + g.rng.Intn(2)),
+		}
+	} else {
+		cx, cy := center(size)
+		radius := float64(min(size.Width, size.Height))/2 - float64(margin)
+		angle := g.rng.Float64() * 2 * math.Pi / float64(playerCount)
+		first = Pos{
+			X: uint16(math.Round(cx + radius*math.Cos(angle))),
+			Y: uint16(math.Round(cy + radius*math.Sin(angle))),
+		}
+	}
+
+	spawns := make([]Pos, playerCount)
+	for k := 0; k < playerCount; k++ {
+		spawn, ok := transform(first, k)
+		if !ok || slices.Contains(spawns[:k], spawn) {
+			return nil
+		}
+		spawns[k] = spawn
+	}
+	return spawns
+}
+
+func (g *SymmetricMapGenerator) placeCastles(gameMap *BaseMap, playerCount int, spawns []Pos, transform symmetryFunc) []Pos {
+	size := gameMap.Size()
+	baseCastleCount := int(size.Width) * int(size.Height) / 50
+	targetCastleCount := int(float64(baseCastleCount) * (0.5 + g.config.CastleDensity))
+	orbits := max(1, targetCastleCount/playerCount)
+
+	var castles []Pos
+	for attempt := 0; attempt < orbits*20 && len(castles) < orbits*playerCount; attempt++ {
+		pos := Pos{
+			X: uint16(g.rng.Intn(int(size.Width))) + 1,
+			Y: uint16(g.rng.Intn(int(size.Height))) + 1,
+		}
+
+		images := make([]Pos, 0, playerCount)
+		valid := true
+		for k := 0; k < playerCount && valid; k++ {
+			image, ok := transform(pos, k)
+			if !ok || slices.Contains(images, image) {
+				valid = false
+				break
+			}
+			if existing, _ := gameMap.Block(image); existing == nil || existing.Meta().Name != block.BlankName {
+				valid = false
+				break
+			}
+			for _, spawn := range spawns {
+				if manhattan(image, spawn) < 3 {
+					valid = false
+					break
+				}
+			}
+			images = append(images, image)
+		}
+		if !valid || !g.canPlaceCastles(images, castles) {
+			continue
+		}
+
+		garrison := block.Num(g.rng.Intn(20) + 10)
+		for _, image := range images {
+			gameMap.SetBlock(image, block.NewBlock(block.CastleName, garrison, 0))
+		}
+		castles = append(castles, images...)
+	}
+
+	return castles
+}
+
+func (g *SymmetricMapGenerator) canPlaceCastles(images, existing []Pos) bool {
+	tooClose := func(a, b Pos) bool {
+		dx := int(a.X) - int(b.X)
+		dy := int(a.Y) - int(b.Y)
+		return int(math.Sqrt(float64(dx*dx+dy*dy))) < g.config.MinCastleDistance
+	}
+
+	for i, pos := range images {
+		for _, other := range existing {
+			if tooClose(pos, other) {
+				return false
+			}
+		}
+		for _, other := range images[:i] {
+			if tooClose(pos, other) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// EvaluateFairness 计算各出生点的路径距离与城堡可达性指标
+func EvaluateFairness(gameMap Map, spawns []Pos, castles []Pos) FairnessReport {
+	report := FairnessReport{
+		NearestOpponent: make([]int, len(spawns)),
+		CastleDistance:  make([]int, len(spawns)),
+		CastleShare:     make([]int, len(spawns)),
+		Reachable:       true,
+	}
+
+	distances := make([][][]int, len(spawns))
+	for i, spawn := range spawns {
+		distances[i] = distanceGrid(gameMap, spawn)
+	}
+
+	for i := range spawns {
+		nearest := -1
+		for j, other := range spawns {
+			if i == j {
+				continue
+			}
+			d := distances[i][other.Y-1][other.X-1]
+			if d < 0 {
+				report.Reachable = false
+				continue
+			}
+			if nearest < 0 || d < nearest {
+				nearest = d
+			}
+		}
+		report.NearestOpponent[i] = nearest
+	}
+
+	considered := max(1, len(castles)/max(1, len(spawns)))
+	for i := range spawns {
+		castleDistances := make([]int, 0, len(castles))
+		for _, castle := range castles {
+			d := distances[i][castle.Y-1][castle.X-1]
+			if d < 0 {
+				report.Reachable = false
+				continue
+			}
+			castleDistances = append(castleDistances, d)
+		}
+		slices.Sort(castleDistances)
+		for _, d := range castleDistances[:min(considered, len(castleDistances))] {
+			report.CastleDistance[i] += d
+		}
+	}
+
+	for _, castle := range castles {
+		best, bestDistance, tie := -1, -1, false
+		for i := range spawns {
+			d := distances[i][castle.Y-1][castle.X-1]
+			if d < 0 {
+				continue
+			}
+			if bestDistance < 0 || d < bestDistance {
+				best, bestDistance, tie = i, d, false
+			} else if d == bestDistance {
+				tie = true
+			}
+		}
+		if best >= 0 && !tie {
+			report.CastleShare[best]++
+		}
+	}
+
+	return report
+}
+
+// WithinTolerance 判断各出生点指标的相对差异是否都在容差范围内
+func (r FairnessReport) WithinTolerance(tolerance float64) bool {
+	if !r.Reachable {
+		return false
+	}
+	if !withinTolerance(r.NearestOpponent, tolerance) || !withinTolerance(r.CastleDistance, tolerance) {
+		return false
+	}
+
+	// 城堡归属是离散计数，允许相差一座
+	minShare, maxShare := slices.Min(r.CastleShare), slices.Max(r.CastleShare)
+	return maxShare-minShare <= 1
+}
+
+// withinTolerance 相对差异不超过容差即可；非整数倍旋转在网格上会有取整误差，
+// 因此始终允许 symmetricRoundingSlack 格的绝对差异
+func withinTolerance(values []int, tolerance float64) bool {
+	if len(values) == 0 {
+		return true
+	}
+	lo, hi := slices.Min(values), slices.Max(values)
+	if hi-lo <= symmetricRoundingSlack {
+		return true
+	}
+	return float64(hi-lo)/float64(hi) <= tolerance
+}
+
+// symmetryFunc 返回位置在第 k 个对称变换下的像，越界时返回 false
+type symmetryFunc func(pos Pos, k int) (Pos, bool)
+
+func isExactSymmetry(playerCount int) bool {
+	return playerCount == 2 || playerCount == 4
+}
+
+func symmetryTransform(size Size, playerCount int) symmetryFunc {
+	w, h := size.Width, size.Height
+
+	switch {
+	case playerCount == 2:
+		return func(pos Pos, k int) (Pos, bool) {
+			if k%2 == 0 {
+				return pos, true
+			}
+			return Pos{X: w + 1 - pos.X, Y: h + 1 - pos.Y}, true
+		}
+
+	case playerCount == 4 && w == h:
+		return func(pos Pos, k int) (Pos, bool) {
+			for i := 0; i < k%4; i++ {
+				pos = Pos{X: w + 1 - pos.Y, Y: pos.X}
+			}
+			return pos, true
+		}
+
+	case playerCount == 4:
+		return func(pos Pos, k int) (Pos, bool) {
+			switch k % 4 {
+			case 1:
+				return Pos{X: w + 1 - pos.X, Y: pos.Y}, true
+			case 2:
+				return Pos{X: w + 1 - pos.X, Y: h + 1 - pos.Y}, true
+			case 3:
+				return Pos{X: pos.X, Y: h + 1 - pos.Y}, true
+			}
+			return pos, true
+		}
+
+	default:
+		cx, cy := center(size)
+		return func(pos Pos, k int) (Pos, bool) {
+			angle := 2 * math.Pi * float64(k) / float64(playerCount)
+			dx := float64(pos.X) - cx
+			dy := float64(pos.Y) - cy
+			x := math.Round(cx + dx*math.Cos(angle) - dy*math.Sin(angle))
+			y := math.Round(cy + dx*math.Sin(angle) + dy*math.Cos(angle))
+			image := Pos{X: uint16(max(0, x)), Y: uint16(max(0, y))}
+			return image, x >= 1 && y >= 1 && size.IsPosValid(image)
+		}
+	}
+}
+
+func center(size Size) (float64, float64) {
+	return float64(size.Width+1) / 2, float64(size.Height+1) / 2
+}
+
+func manhattan(a, b Pos) int {
+	dx := int(a.X) - int(b.X)
+	dy := int(a.Y) - int(b.Y)
+	return max(dx, -dx) + max(dy, -dy)
+}
+
+func neighbors(size Size, pos Pos) []Pos {
+	result := make([]Pos, 0, 4)
+	if pos.X > 1 {
+		result = append(result, Pos{X: pos.X - 1, Y: pos.Y})
+	}
+	if pos.X < size.Width {
+		result = append(result, Pos{X: pos.X + 1, Y: pos.Y})
+	}
+	if pos.Y > 1 {
+		result = append(result, Pos{X: pos.X, Y: pos.Y - 1})
+	}
+	if pos.Y < size.Height {
+		result = append(result, Pos{X: pos.X, Y: pos.Y + 1})
+	}
+	return result
+}
+
+// distanceGrid 计算从起点出发到各格子的路径距离，山脉不可通行，不可达为 -1
+func distanceGrid(gameMap Map, start Pos) [][]int {
+	size := gameMap.Size()
+	dist := make([][]int, size.Height)
+	for i := range dist {
+		dist[i] = make([]int, size.Width)
+		for j := range dist[i] {
+			dist[i][j] = -1
+		}
+	}
+
+	dist[start.Y-1][start.X-1] = 0
+	queue := []Pos{start}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, next := range neighbors(size, current) {
+			if dist[next.Y-1][next.X-1] >= 0 {
+				continue
+			}
+			b, _ := gameMap.Block(next)
+			if b != nil && b.Meta().Name == block.MountainName {
+				continue
+			}
+			dist[next.Y-1][next.X-1] = dist[current.Y-1][current.X-1] + 1
+			queue = append(queue, next)
+		}
+	}
+
+	return dist
+}
+
+func init() {
+	RegisterGenerator("symmetric", func(size Size, players []Player, config ...GeneratorConfig) (Map, error) {
+		var cfg GeneratorConfig
+		if len(config) > 0 {
+			cfg = config[0]
+		} else {
+			cfg = DefaultGeneratorConfig()
+		}
+
+		generator := NewSymmetricMapGenerator(cfg)
+		return generator.Generate(size, players)
+	})
+}
@@ -1,6 +1,9 @@
 package game
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"server/internal/game/block"
 	gamemap "server/internal/game/map"
 	"testing"
@@ -164,3 +167,401 @@ func TestMapRoundOperations(t *testing.T) {
 	emptyMap.RoundStart(1)
 	emptyMap.RoundEnd(1)
 }
+
+func writeTestMapFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name+".json"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write map file: %v", err)
+	}
+}
+
+func TestFileMapProvider(t *testing.T) {
+	dir := t.TempDir()
+
+	writeTestMapFile(t, dir, "duel", `{
+		"name": "Duel",
+		"desc": "Two kings facing each other",
+		"terrain": [
+			".....",
+			".#.#.",
+			".....",
+			".#.#.",
+			"....."
+		],
+		"castles": [{"x": 3, "y": 3, "num": 25}],
+		"spawns": [{"x": 1, "y": 1}, {"x": 5, "y": 5}]
+	}`)
+	writeTestMapFile(t, dir, "walled", `{
+		"terrain": [
+			"..#..",
+			"..#..",
+			"..#.."
+		],
+		"spawns": [{"x": 1, "y": 1}, {"x": 5, "y": 3}]
+	}`)
+	writeTestMapFile(t, dir, "crowded", `{
+		"maxPlayers": 3,
+		"terrain": ["...."],
+		"spawns": [{"x": 1, "y": 1}, {"x": 4, "y": 1}]
+	}`)
+
+	players := []gamemap.Player{
+		{Index: 0, Owner: block.Owner(0), IsActive: true},
+		{Index: 1, Owner: block.Owner(1), IsActive: true},
+	}
+
+	provider := gamemap.NewFileProvider(dir)
+
+	t.Run("load_valid_map", func(t *testing.T) {
+		gameMap, err := provider.GetMap("file-duel", players)
+		if err != nil {
+			t.Fatalf("Expected map to load, got %v", err)
+		}
+
+		if gameMap.Size() != (gamemap.Size{Width: 5, Height: 5}) {
+			t.Errorf("Expected 5x5 map, got %v", gameMap.Size())
+		}
+
+		king, _ := gameMap.Block(gamemap.Pos{X: 5, Y: 5})
+		if king.Meta().Name != block.KingName || king.Owner() != 1 {
+			t.Errorf("Expected king of owner 1 at second spawn, got %s/%d", king.Meta().Name, king.Owner())
+		}
+
+		castle, _ := gameMap.Block(gamemap.Pos{X: 3, Y: 3})
+		if castle.Meta().Name != block.CastleName || castle.Num() != 25 {
+			t.Errorf("Expected castle with 25 troops, got %s/%d", castle.Meta().Name, castle.Num())
+		}
+
+		mountain, _ := gameMap.Block(gamemap.Pos{X: 2, Y: 2})
+		if mountain.Meta().Name != block.MountainName {
+			t.Errorf("Expected mountain, got %s", mountain.Meta().Name)
+		}
+	})
+
+	t.Run("reject_unreachable_spawn", func(t *testing.T) {
+		if _, err := provider.GetMap("file-walled", players); err == nil {
+			t.Error("Expected error for map with unreachable spawn")
+		}
+	})
+
+	t.Run("reject_insufficient_spawns", func(t *testing.T) {
+		if _, err := provider.GetMap("file-crowded", players); err == nil {
+			t.Error("Expected error for map with fewer spawns than max players")
+		}
+	})
+
+	t.Run("reject_player_count", func(t *testing.T) {
+		if _, err := provider.GetMap("file-duel", players[:1]); err == nil {
+			t.Error("Expected error for too few players")
+		}
+	})
+
+	t.Run("reject_path_traversal", func(t *testing.T) {
+		if _, err := provider.GetMap("file-../duel", players); err == nil {
+			t.Error("Expected error for invalid map name")
+		}
+	})
+
+	t.Run("list_maps", func(t *testing.T) {
+		listings, err := provider.ListMaps()
+		if err != nil {
+			t.Fatalf("Failed to list maps: %v", err)
+		}
+
+		if len(listings) != 1 {
+			t.Fatalf("Expected only the valid map to be listed, got %d", len(listings))
+		}
+		if listings[0].Id != "file-duel" || listings[0].MaxPlayers != 2 {
+			t.Errorf("Unexpected listing: %+v", listings[0])
+		}
+	})
+
+	t.Run("manager_routing", func(t *testing.T) {
+		manager := gamemap.NewMapManager()
+		manager.RegisterProvider(provider)
+
+		first, err := manager.GetMap("file-duel", players)
+		if err != nil {
+			t.Fatalf("Expected manager to route file map, got %v", err)
+		}
+		// 每局重新构建，不同对局不共享同一份地图
+		second, _ := manager.GetMap("file-duel", players)
+		if first == second {
+			t.Error("Expected file maps not to be cached across games")
+		}
+		if len(manager.ListMaps()) != 1 {
+			t.Errorf("Expected manager to list file maps")
+		}
+	})
+}
+
+func TestSymmetricGenerator(t *testing.T) {
+	size := gamemap.Size{Width: 20, Height: 20}
+
+	for _, playerCount := range []int{2, 3, 4, 5, 6} {
+		t.Run(fmt.Sprintf("%d_players", playerCount), func(t *testing.T) {
+			players := make([]gamemap.Player, playerCount)
+			for i := range players {
+				players[i] = gamemap.Player{Index: i, Owner: block.Owner(i + 1), IsActive: true}
+			}
+
+			config := gamemap.DefaultGeneratorConfig()
+			config.Seed = 42
+			gameMap, err := gamemap.GenerateMap("symmetric", size, players, config)
+			if err != nil {
+				t.Fatalf("Failed to generate symmetric map: %v", err)
+			}
+
+			spawns := make([]gamemap.Pos, playerCount)
+			var castles []gamemap.Pos
+			for y := uint16(1); y <= size.Height; y++ {
+				for x := uint16(1); x <= size.Width; x++ {
+					pos := gamemap.Pos{X: x, Y: y}
+					b, _ := gameMap.Block(pos)
+					if b == nil {
+						t.Fatalf("Expected every cell to be filled, %s is nil", pos.String())
+					}
+					switch b.Meta().Name {
+					case block.KingName:
+						spawns[b.Owner()-1] = pos
+					case block.CastleName:
+						castles = append(castles, pos)
+					}
+				}
+			}
+
+			for i, spawn := range spawns {
+				if spawn == (gamemap.Pos{}) {
+					t.Fatalf("Expected a king for player %d", i)
+				}
+			}
+
+			report := gamemap.EvaluateFairness(gameMap, spawns, castles)
+			if !report.WithinTolerance(0.1) {
+				t.Errorf("Expected fair map, got %+v", report)
+			}
+		})
+	}
+
+	t.Run("two_player_point_symmetry", func(t *testing.T) {
+		players := []gamemap.Player{
+			{Index: 0, Owner: block.Owner(1), IsActive: true},
+			{Index: 1, Owner: block.Owner(2), IsActive: true},
+		}
+		config := gamemap.DefaultGeneratorConfig()
+		config.Seed = 7
+		gameMap, err := gamemap.GenerateMap("symmetric", size, players, config)
+		if err != nil {
+			t.Fatalf("Failed to generate symmetric map: %v", err)
+		}
+
+		for y := uint16(1); y <= size.Height; y++ {
+			for x := uint16(1); x <= size.Width; x++ {
+				a, _ := gameMap.Block(gamemap.Pos{X: x, Y: y})
+				b, _ := gameMap.Block(gamemap.Pos{X: size.Width + 1 - x, Y: size.Height + 1 - y})
+				if a.Meta().Name != b.Meta().Name || a.Num() != b.Num() {
+					t.Fatalf("Expected point symmetry at (%d,%d): %s/%d vs %s/%d",
+						x, y, a.Meta().Name, a.Num(), b.Meta().Name, b.Num())
+				}
+			}
+		}
+	})
+}
+
+func TestTerrainGenerators(t *testing.T) {
+	size := gamemap.Size{Width: 25, Height: 25}
+
+	for _, name := range []string{"maze", "islands", "open_field"} {
+		for _, playerCount := range []int{2, 4} {
+			t.Run(fmt.Sprintf("%s_%d_players", name, playerCount), func(t *testing.T) {
+				players := make([]gamemap.Player, playerCount)
+				for i := range players {
+					players[i] = gamemap.Player{Index: i, Owner: block.Owner(i + 1), IsActive: true}
+				}
+
+				config := gamemap.DefaultGeneratorConfig()
+				config.Seed = 42
+				gameMap, err := gamemap.GenerateMap(name, size, players, config)
+				if err != nil {
+					t.Fatalf("Failed to generate %s map: %v", name, err)
+				}
+
+				var targets []gamemap.Pos
+				kings := 0
+				for y := uint16(1); y <= size.Height; y++ {
+					for x := uint16(1); x <= size.Width; x++ {
+						pos := gamemap.Pos{X: x, Y: y}
+						b, _ := gameMap.Block(pos)
+						if b == nil {
+							t.Fatalf("Expected every cell to be filled, %s is nil", pos.String())
+						}
+						switch b.Meta().Name {
+						case block.KingName:
+							kings++
+							targets = append(targets, pos)
+						case block.CastleName:
+							targets = append(targets, pos)
+						}
+					}
+				}
+				if kings != playerCount {
+					t.Fatalf("Expected %d kings, got %d", playerCount, kings)
+				}
+
+				reachable := reachableFrom(gameMap, targets[0])
+				for _, pos := range targets {
+					if !reachable[pos] {
+						t.Errorf("Expected %s to be reachable from %s", pos.String(), targets[0].String())
+					}
+				}
+
+				again, err := gamemap.GenerateMap(name, size, players, config)
+				if err != nil {
+					t.Fatalf("Failed to regenerate %s map: %v", name, err)
+				}
+				for y := uint16(1); y <= size.Height; y++ {
+					for x := uint16(1); x <= size.Width; x++ {
+						pos := gamemap.Pos{X: x, Y: y}
+						a, _ := gameMap.Block(pos)
+						b, _ := again.Block(pos)
+						if a.Meta().Name != b.Meta().Name {
+							t.Fatalf("Expected same seed to give the same terrain at %s: %s vs %s",
+								pos.String(), a.Meta().Name, b.Meta().Name)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+// 房间数少于玩家数时拒绝生成，而不是让两个王城落在同一格
+func TestMazeGenerator_TooFewRooms(t *testing.T) {
+	players := make([]gamemap.Player, 10)
+	for i := range players {
+		players[i] = gamemap.Player{Index: i, Owner: block.Owner(i + 1), IsActive: true}
+	}
+
+	// 5x5 的迷宫只有 9 个房间
+	_, err := gamemap.GenerateMap("maze", gamemap.Size{Width: 5, Height: 5}, players, gamemap.DefaultGeneratorConfig())
+	if err == nil {
+		t.Fatal("Expected error when the maze has fewer rooms than players")
+	}
+}
+
+func reachableFrom(gameMap gamemap.Map, start gamemap.Pos) map[gamemap.Pos]bool {
+	size := gameMap.Size()
+	visited := map[gamemap.Pos]bool{start: true}
+	queue := []gamemap.Pos{start}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, d := range [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+			x, y := int(current.X)+d[0], int(current.Y)+d[1]
+			if x < 1 || y < 1 || x > int(size.Width) || y > int(size.Height) {
+				continue
+			}
+			next := gamemap.Pos{X: uint16(x), Y: uint16(y)}
+			if visited[next] {
+				continue
+			}
+			if b, _ := gameMap.Block(next); b.Meta().Name == block.MountainName {
+				continue
+			}
+			visited[next] = true
+			queue = append(queue, next)
+		}
+	}
+	return visited
+}
+
+func assertSameGrid(t *testing.T, a, b gamemap.Map) {
+	t.Helper()
+	if a.Size() != b.Size() {
+		t.Fatalf("Expected same size, got %s vs %s", a.Size().String(), b.Size().String())
+	}
+
+	size := a.Size()
+	for y := uint16(1); y <= size.Height; y++ {
+		for x := uint16(1); x <= size.Width; x++ {
+			pos := gamemap.Pos{X: x, Y: y}
+			ba, _ := a.Block(pos)
+			bb, _ := b.Block(pos)
+			if ba == nil || bb == nil {
+				if ba != bb {
+					t.Fatalf("Grids differ at %s: one side is empty", pos.String())
+				}
+				continue
+			}
+			if ba.Meta().Name != bb.Meta().Name || ba.Num() != bb.Num() || ba.Owner() != bb.Owner() {
+				t.Fatalf("Grids differ at %s: %s/%d/%d vs %s/%d/%d", pos.String(),
+					ba.Meta().Name, ba.Num(), ba.Owner(), bb.Meta().Name, bb.Num(), bb.Owner())
+			}
+		}
+	}
+}
+
+func TestDeterministicMapId(t *testing.T) {
+	players := []gamemap.Player{
+		{Index: 0, Owner: block.Owner(1), IsActive: true},
+		{Index: 1, Owner: block.Owner(2), IsActive: true},
+	}
+	size := gamemap.Size{Width: 20, Height: 20}
+
+	for _, name := range gamemap.GetAllGeneratorNames() {
+		t.Run(name, func(t *testing.T) {
+			manager := gamemap.NewMapManager()
+			mapId := manager.GenerateMapId(name, size, len(players), gamemap.DefaultGeneratorConfig())
+
+			first, err := manager.GetMap(mapId, players)
+			if err != nil {
+				t.Fatalf("Failed to get map %s: %v", mapId, err)
+			}
+			second, err := gamemap.NewMapManager().GetMap(mapId, players)
+			if err != nil {
+				t.Fatalf("Failed to get map %s again: %v", mapId, err)
+			}
+			if first == second {
+				t.Fatal("Expected separate map instances for separate games")
+			}
+			assertSameGrid(t, first, second)
+		})
+	}
+
+	t.Run("unseeded_ids_differ", func(t *testing.T) {
+		manager := gamemap.NewMapManager()
+		config := gamemap.DefaultGeneratorConfig()
+		if manager.GenerateMapId("base", size, 2, config) == manager.GenerateMapId("base", size, 2, config) {
+			t.Error("Expected a fresh seed for each unseeded map id")
+		}
+	})
+
+	t.Run("default_castle_garrison", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestMapFile(t, dir, "plain", `{
+			"terrain": [".....", ".....", "....."],
+			"castles": [{"x": 3, "y": 1}, {"x": 3, "y": 3}],
+			"spawns": [{"x": 1, "y": 2}, {"x": 5, "y": 2}]
+		}`)
+
+		build := func() gamemap.Map {
+			manager := gamemap.NewMapManager()
+			manager.RegisterProvider(gamemap.NewFileProvider(dir))
+			gameMap, err := manager.GetMap("file-plain", players)
+			if err != nil {
+				t.Fatalf("Failed to load file map: %v", err)
+			}
+			return gameMap
+		}
+
+		first := build()
+		assertSameGrid(t, first, build())
+
+		castle, _ := first.Block(gamemap.Pos{X: 3, Y: 1})
+		if castle.Num() < block.DefaultCastleNum {
+			t.Errorf("Expected default garrison of at least %d, got %d", block.DefaultCastleNum, castle.Num())
+		}
+	})
+}
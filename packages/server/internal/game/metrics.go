@@ -0,0 +1,70 @@
+package game
+
+import (
+	"errors"
+	"server/internal/queue"
+)
+
+// commandType 指令在指标中的类型标签
+func commandType(event queue.Event) string {
+	switch event.(type) {
+	case JoinCommand:
+		return "join"
+	case LeaveCommand:
+		return "leave"
+	case MoveCommand:
+		return "move"
+	case ForceStartCommand:
+		return "forceStart"
+	case SurrenderCommand:
+		return "surrender"
+	case DisconnectCommand:
+		return "disconnect"
+	case ReconnectCommand:
+		return "reconnect"
+	case ResumeCommand:
+		return "resume"
+	case SpectateCommand:
+		return "spectate"
+	case StopSpectatingCommand:
+		return "stopSpectating"
+	case ChatCommand:
+		return "chat"
+	case KickCommand:
+		return "kick"
+	case TransferHostCommand:
+		return "transferHost"
+	case RoomSettingsCommand:
+		return "roomSettings"
+	case HostStartCommand:
+		return "hostStart"
+	default:
+		return "unknown"
+	}
+}
+
+var moveRejectReasons = []struct {
+	err    error
+	reason string
+}{
+	{ErrNotInProgress, "not_in_progress"},
+	{ErrPlayerNotFound, "player_not_found"},
+	{ErrCannotOperate, "cannot_operate"},
+	{ErrNoMovesLeft, "no_moves_left"},
+	{ErrInvalidPosition, "invalid_position"},
+	{ErrNotOwner, "not_owner"},
+	{ErrInvalidTroops, "invalid_troops"},
+	{ErrNotEnoughTroops, "not_enough_troops"},
+	{ErrMoveNotAllowed, "not_allowed"},
+	{ErrMoveRejected, "rejected_by_target"},
+}
+
+// moveRejectReason 将 Move 的错误归类为有限的标签值
+func moveRejectReason(err error) string {
+	for _, r := range moveRejectReasons {
+		if errors.Is(err, r.err) {
+			return r.reason
+		}
+	}
+	return "other"
+}
@@ -0,0 +1,54 @@
+package game
+
+import (
+	"errors"
+	gamemap "server/internal/game/map"
+	"testing"
+)
+
+func TestMoveRejectReason(t *testing.T) {
+	valid := Move{Pos: gamemap.Pos{X: 2, Y: 2}, Towards: MoveTowardsRight, Num: 5}
+
+	cases := []struct {
+		name   string
+		setup  func(core *BaseCore) (string, Move)
+		reason string
+	}{
+		{"not_in_progress", func(core *BaseCore) (string, Move) {
+			core.status = StatusWaiting
+			return "player1", valid
+		}, "not_in_progress"},
+		{"player_not_found", func(core *BaseCore) (string, Move) {
+			return "nobody", valid
+		}, "player_not_found"},
+		{"no_moves_left", func(core *BaseCore) (string, Move) {
+			core.players[0].Moves = 0
+			return "player1", valid
+		}, "no_moves_left"},
+		{"invalid_position", func(core *BaseCore) (string, Move) {
+			return "player1", Move{Pos: gamemap.Pos{X: 9, Y: 9}, Towards: MoveTowardsRight, Num: 5}
+		}, "invalid_position"},
+		{"not_enough_troops", func(core *BaseCore) (string, Move) {
+			return "player1", Move{Pos: gamemap.Pos{X: 2, Y: 2}, Towards: MoveTowardsRight, Num: 50}
+		}, "not_enough_troops"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			core := createTestCore()
+			playerId, move := tc.setup(core)
+
+			err := core.Move(playerId, move)
+			if err == nil {
+				t.Fatal("Expected move to be rejected")
+			}
+			if got := moveRejectReason(err); got != tc.reason {
+				t.Errorf("Expected reason %s, got %s (%v)", tc.reason, got, err)
+			}
+		})
+	}
+
+	if got := moveRejectReason(errors.New("boom")); got != "other" {
+		t.Errorf("Expected other for unknown errors, got %s", got)
+	}
+}
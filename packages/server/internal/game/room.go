@@ -0,0 +1,235 @@
+package game
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// 房间操作的拒绝原因
+var (
+	ErrNotHost       = errors.New("only the host can do this")
+	ErrWrongPassword = errors.New("wrong room password")
+	ErrKicked        = errors.New("kicked from this room")
+)
+
+// room 私人房间的设置，公开对局的 private 为 false 且没有房主
+type room struct {
+	private bool
+	host    string
+	// passwordHash 为空表示无需密码
+	passwordHash []byte
+	// kicked 被房主移出的玩家不能再加入
+	kicked map[string]bool
+}
+
+// RoomInfo 房间设置的公开部分
+type RoomInfo struct {
+	Private     bool
+	Host        string
+	HasPassword bool
+}
+
+func hashRoomPassword(password string) []byte {
+	sum := sha256.Sum256([]byte(password))
+	return sum[:]
+}
+
+// SetRoom 将尚无玩家的对局设为私人房间，password 为空时无需密码
+func (gc *BaseCore) SetRoom(host, password string) error {
+	if gc.status != StatusWaiting || len(gc.players) > 0 || gc.room.private {
+		return fmt.Errorf("game %s cannot become a private room", gc.gameId)
+	}
+
+	gc.room = room{private: true, host: host, kicked: make(map[string]bool)}
+	if password != "" {
+		gc.room.passwordHash = hashRoomPassword(password)
+	}
+	return nil
+}
+
+func (gc *BaseCore) Room() RoomInfo {
+	return RoomInfo{
+		Private:     gc.room.private,
+		Host:        gc.room.host,
+		HasPassword: len(gc.room.passwordHash) > 0,
+	}
+}
+
+// CheckRoomAccess 检查加入或观战私人房间的密码与移出记录，公开对局总是允许
+func (gc *BaseCore) CheckRoomAccess(userId, password string) error {
+	if !gc.room.private {
+		return nil
+	}
+	if gc.room.kicked[userId] {
+		return ErrKicked
+	}
+	if len(gc.room.passwordHash) > 0 && subtle.ConstantTimeCompare(hashRoomPassword(password), gc.room.passwordHash) != 1 {
+		return ErrWrongPassword
+	}
+	return nil
+}
+
+// Kick 房主在开局前将玩家移出房间
+func (gc *BaseCore) Kick(hostId, playerId string) error {
+	if err := gc.requireHostBeforeStart(hostId); err != nil {
+		return err
+	}
+	if playerId == hostId {
+		return fmt.Errorf("host cannot kick themselves")
+	}
+	if err := gc.Leave(playerId); err != nil {
+		return err
+	}
+
+	gc.room.kicked[playerId] = true
+	slog.Info("player kicked", "player", playerId, "host", hostId, "gameId", gc.gameId)
+	return nil
+}
+
+// TransferHost 将房主转让给房间中的另一名玩家
+func (gc *BaseCore) TransferHost(hostId, playerId string) error {
+	if err := gc.requireHost(hostId); err != nil {
+		return err
+	}
+	if _, player := gc.findPlayerIndex(playerId); player == nil {
+		return fmt.Errorf("%w: %s", ErrPlayerNotFound, playerId)
+	}
+
+	gc.room.host = playerId
+	slog.Info("host transferred", "from", hostId, "to", playerId, "gameId", gc.gameId)
+	return nil
+}
+
+// ChangeRoomSettings 房主在开局前修改模式与地图，modeName 或 mapId 为空时保持不变
+func (gc *BaseCore) ChangeRoomSettings(hostId, modeName, mapId string) error {
+	if err := gc.requireHostBeforeStart(hostId); err != nil {
+		return err
+	}
+	if mapId != "" {
+		if err := gc.mapManager.ValidateMapId(mapId); err != nil {
+			return fmt.Errorf("invalid map %s: %w", mapId, err)
+		}
+	}
+
+	if modeName != "" {
+		mode, exists := GetGameMode(modeName)
+		if !exists {
+			return fmt.Errorf("unknown game mode: %s", modeName)
+		}
+		if len(gc.players) > int(mode.MaxPlayers) {
+			return fmt.Errorf("mode %s allows at most %d players, room has %d", mode.Name, mode.MaxPlayers, len(gc.players))
+		}
+		gc.mode = mode
+	}
+	if mapId != "" {
+		gc.mapId = mapId
+	}
+	return nil
+}
+
+// HostStart 房主直接开局，无需强制开始投票
+func (gc *BaseCore) HostStart(hostId string) error {
+	if err := gc.requireHostBeforeStart(hostId); err != nil {
+		return err
+	}
+	if !gc.IsGameReady() {
+		return fmt.Errorf("mode %s needs %d-%d players, room has %d",
+			gc.mode.Name, gc.mode.MinPlayers, gc.mode.MaxPlayers, len(gc.players))
+	}
+	return gc.Start()
+}
+
+// transferHostOnLeave 房主在开局前离开时由最早加入的玩家接任，返回房主是否变化
+func (gc *BaseCore) transferHostOnLeave(playerId string) bool {
+	if !gc.room.private || gc.room.host != playerId || gc.status != StatusWaiting || len(gc.players) == 0 {
+		return false
+	}
+	gc.room.host = gc.players[0].Id
+	return true
+}
+
+// requireHost 操作者必须是房主且在玩家列表中，只观战的房主不能操作房间
+func (gc *BaseCore) requireHost(hostId string) error {
+	if !gc.room.private {
+		return fmt.Errorf("game %s is not a private room", gc.gameId)
+	}
+	if gc.room.host != hostId {
+		return ErrNotHost
+	}
+	if _, player := gc.findPlayerIndex(hostId); player == nil {
+		return fmt.Errorf("%w: host must join the room first", ErrNotHost)
+	}
+	return nil
+}
+
+func (gc *BaseCore) requireHostBeforeStart(hostId string) error {
+	if err := gc.requireHost(hostId); err != nil {
+		return err
+	}
+	if gc.status != StatusWaiting {
+		return fmt.Errorf("cannot change room in status: %s", gc.status)
+	}
+	return nil
+}
+
+// =============================================================================
+// 房间指令处理
+// =============================================================================
+
+// SetRoom 将对局设为私人房间，应在玩家加入前调用
+func (g *Game) SetRoom(host, password string) error {
+	g.coreMu.Lock()
+	defer g.coreMu.Unlock()
+	return g.core.SetRoom(host, password)
+}
+
+func (g *Game) handleKickCommand(cmd KickCommand) error {
+	if err := g.core.Kick(cmd.PlayerId, cmd.TargetId); err != nil {
+		return err
+	}
+
+	g.forwardBroadcastEvent(PlayerLeftEvent{
+		BroadcastEvent: BroadcastEvent{},
+		PlayerId:       cmd.TargetId,
+		GameStatus:     g.core.Status(),
+		Players:        g.core.Players(),
+	})
+	g.publishPlayerError(cmd.TargetId, ErrKicked)
+	return nil
+}
+
+func (g *Game) handleTransferHostCommand(cmd TransferHostCommand) error {
+	if err := g.core.TransferHost(cmd.PlayerId, cmd.TargetId); err != nil {
+		return err
+	}
+	g.broadcastRoomUpdate()
+	return nil
+}
+
+func (g *Game) handleRoomSettingsCommand(cmd RoomSettingsCommand) error {
+	if err := g.core.ChangeRoomSettings(cmd.PlayerId, cmd.GameMode, cmd.MapId); err != nil {
+		return err
+	}
+	g.broadcastRoomUpdate()
+	return nil
+}
+
+func (g *Game) handleHostStartCommand(cmd HostStartCommand) error {
+	return g.core.HostStart(cmd.PlayerId)
+}
+
+func (g *Game) broadcastRoomUpdate() {
+	info := g.core.Room()
+	g.forwardBroadcastEvent(RoomUpdatedEvent{
+		BroadcastEvent: BroadcastEvent{},
+		Host:           info.Host,
+		Private:        info.Private,
+		HasPassword:    info.HasPassword,
+		GameMode:       g.core.mode.Name,
+		MapId:          g.core.mapId,
+		Players:        g.core.Players(),
+	})
+}
@@ -0,0 +1,158 @@
+package game
+
+import (
+	"errors"
+	gamemap "server/internal/game/map"
+	"server/internal/queue"
+	"testing"
+	"time"
+)
+
+// testMapId 默认生成器可以解析的地图 id
+const testMapId = "base-20x20-2-m0.2-c0.1-d5-s1"
+
+func newTestRoom(t *testing.T, password string) *BaseCore {
+	t.Helper()
+	mode := TestMode
+	mode.MaxPlayers = 4
+
+	core := NewBaseCore("test-room", mode, createTestMapManager())
+	if err := core.SetRoom("host", password); err != nil {
+		t.Fatalf("Expected no error creating room, got %v", err)
+	}
+	core.Join(Player{Id: "host", Name: "Host"})
+	core.Join(Player{Id: "p2", Name: "Player 2"})
+	return core
+}
+
+func TestBaseCore_RoomAccess(t *testing.T) {
+	core := newTestRoom(t, "secret")
+
+	if err := core.CheckRoomAccess("p3", "wrong"); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("Expected ErrWrongPassword, got %v", err)
+	}
+	if err := core.CheckRoomAccess("p3", "secret"); err != nil {
+		t.Errorf("Expected correct password to be accepted, got %v", err)
+	}
+	if err := core.SetRoom("other", ""); err == nil {
+		t.Error("Expected error turning an occupied game into a room")
+	}
+
+	public := NewBaseCore("test-public", TestMode, createTestMapManager())
+	if err := public.CheckRoomAccess("p1", ""); err != nil {
+		t.Errorf("Expected public game to need no password, got %v", err)
+	}
+	if err := public.HostStart("p1"); err == nil {
+		t.Error("Expected host start to be rejected in a public game")
+	}
+}
+
+func TestBaseCore_HostControls(t *testing.T) {
+	core := newTestRoom(t, "")
+	core.Join(Player{Id: "p3", Name: "Player 3"})
+
+	// 房间有 3 人，不能改为 1v1
+	if err := core.ChangeRoomSettings("host", Classic1v1.Name, ""); err == nil {
+		t.Error("Expected error for mode that cannot fit the room")
+	}
+
+	if err := core.Kick("p2", "p3"); !errors.Is(err, ErrNotHost) {
+		t.Errorf("Expected ErrNotHost, got %v", err)
+	}
+	if err := core.Kick("host", "p3"); err != nil {
+		t.Fatalf("Expected no error kicking, got %v", err)
+	}
+	if len(core.Players()) != 2 {
+		t.Errorf("Expected 2 players after kick, got %d", len(core.Players()))
+	}
+	if err := core.CheckRoomAccess("p3", ""); !errors.Is(err, ErrKicked) {
+		t.Errorf("Expected kicked player to be blocked, got %v", err)
+	}
+
+	if err := core.ChangeRoomSettings("host", "", "unknown-map"); err == nil {
+		t.Error("Expected unknown map to be rejected")
+	}
+	if err := core.ChangeRoomSettings("host", "", testMapId); err != nil || core.mapId != testMapId {
+		t.Errorf("Expected map change, got mapId %q and error %v", core.mapId, err)
+	}
+
+	if err := core.TransferHost("host", "p2"); err != nil {
+		t.Fatalf("Expected no error transferring host, got %v", err)
+	}
+	if core.Room().Host != "p2" {
+		t.Errorf("Expected p2 to be host, got %s", core.Room().Host)
+	}
+	if err := core.TransferHost("p2", "stranger"); err == nil {
+		t.Error("Expected error transferring host to a non-player")
+	}
+
+	// 房主离开后由最早加入的玩家接任
+	core.Leave("p2")
+	if core.Room().Host != "host" {
+		t.Errorf("Expected host to pass to earliest player, got %s", core.Room().Host)
+	}
+}
+
+func TestBaseCore_HostStart(t *testing.T) {
+	core := newTestRoom(t, "")
+	defer core.Stop()
+
+	if err := core.HostStart("p2"); !errors.Is(err, ErrNotHost) {
+		t.Errorf("Expected ErrNotHost, got %v", err)
+	}
+
+	// 房主只观战、不在玩家列表中时不能开局
+	watching := NewBaseCore("test-room-watching", TestMode, createTestMapManager())
+	watching.SetRoom("host", "")
+	watching.Spectate(Spectator{Id: "host", Name: "Host"})
+	watching.Join(Player{Id: "p2", Name: "Player 2"})
+	watching.Join(Player{Id: "p3", Name: "Player 3"})
+	if err := watching.HostStart("host"); !errors.Is(err, ErrNotHost) {
+		t.Errorf("Expected spectating host to be rejected, got %v", err)
+	}
+
+	if err := core.HostStart("host"); err != nil {
+		t.Fatalf("Expected no error starting, got %v", err)
+	}
+	if core.Status() != StatusInProgress {
+		t.Errorf("Expected game in progress, got %s", core.Status())
+	}
+	if err := core.Kick("host", "p2"); err == nil {
+		t.Error("Expected kick to be rejected after start")
+	}
+}
+
+func TestGame_RoomUpdated(t *testing.T) {
+	q := queue.NewInMemoryQueue()
+	game := NewGame("test-game-room", q, TestMode, gamemap.NewMapManager())
+	if err := game.SetRoom("host", "secret"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	broadcastCh := q.Subscribe("test-game-room/broadcast")
+
+	if err := game.handleJoinCommand(JoinCommand{CommandEvent: CommandEvent{PlayerId: "host"}, Password: "wrong"}); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("Expected ErrWrongPassword, got %v", err)
+	}
+	game.handleJoinCommand(JoinCommand{CommandEvent: CommandEvent{PlayerId: "host"}, PlayerName: "Host", Password: "secret"})
+	<-broadcastCh
+
+	if err := game.handleRoomSettingsCommand(RoomSettingsCommand{CommandEvent: CommandEvent{PlayerId: "host"}, MapId: testMapId}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	select {
+	case event := <-broadcastCh:
+		update, ok := event.(RoomUpdatedEvent)
+		if !ok {
+			t.Fatalf("Expected RoomUpdatedEvent, got %T", event)
+		}
+		if update.Host != "host" || !update.HasPassword || update.MapId != testMapId || update.GameMode != TestMode.Name {
+			t.Errorf("Unexpected room update: %+v", update)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected room updated broadcast")
+	}
+	if game.Room().Host != "host" {
+		t.Errorf("Expected host to be unchanged, got %s", game.Room().Host)
+	}
+}
@@ -0,0 +1,99 @@
+package game
+
+import (
+	"context"
+	"log/slog"
+	"server/internal/queue"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxSpectators 每个对局默认的观战人数上限
+	DefaultMaxSpectators = 50
+	// DefaultSpectatorDelay 观战事件流默认的延迟
+	DefaultSpectatorDelay = 10 * time.Second
+)
+
+// spectatorFeed 将广播事件延迟 delay 后按原顺序发布到观战主题。
+// 事件入队时即编码为信封，地图与玩家列表在延迟期间不会随对局继续变化。
+type spectatorFeed struct {
+	delay   time.Duration
+	publish func(queue.Event)
+
+	mu      sync.Mutex
+	pending []delayedEnvelope
+	// wake 有新事件入队时唤醒 run
+	wake chan struct{}
+}
+
+type delayedEnvelope struct {
+	due      time.Time
+	envelope *queue.Envelope
+}
+
+func newSpectatorFeed(delay time.Duration, publish func(queue.Event)) *spectatorFeed {
+	return &spectatorFeed{
+		delay:   max(delay, 0),
+		publish: publish,
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// push 记录事件，延迟到期后由 run 发布
+func (f *spectatorFeed) push(event queue.Event) {
+	envelope, err := queue.NewEnvelope(event, "")
+	if err != nil {
+		slog.Error("failed to encode spectator event", "error", err)
+		return
+	}
+
+	f.mu.Lock()
+	f.pending = append(f.pending, delayedEnvelope{due: time.Now().Add(f.delay), envelope: envelope})
+	f.mu.Unlock()
+
+	select {
+	case f.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run 按到期顺序发布事件，ctx 取消后丢弃尚未到期的事件
+func (f *spectatorFeed) run(ctx context.Context) {
+	for {
+		f.mu.Lock()
+		if len(f.pending) == 0 {
+			f.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return
+			case <-f.wake:
+				continue
+			}
+		}
+		next := f.pending[0]
+		f.mu.Unlock()
+
+		// 所有事件的延迟相同，队首总是最早到期
+		if wait := time.Until(next.due); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+
+		f.mu.Lock()
+		f.pending = f.pending[1:]
+		f.mu.Unlock()
+
+		event, err := next.envelope.Event()
+		if err != nil {
+			slog.Error("failed to decode spectator event", "error", err, "type", next.envelope.Type)
+			continue
+		}
+		f.publish(event)
+	}
+}
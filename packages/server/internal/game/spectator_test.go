@@ -0,0 +1,106 @@
+package game
+
+import (
+	"context"
+	"server/internal/game/block"
+	gamemap "server/internal/game/map"
+	"server/internal/queue"
+	"testing"
+	"time"
+)
+
+func TestSpectatorFeed_DelaysInOrder(t *testing.T) {
+	published := make(chan queue.Event, 4)
+	feed := newSpectatorFeed(50*time.Millisecond, func(event queue.Event) { published <- event })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go feed.run(ctx)
+
+	start := time.Now()
+	feed.push(TurnStartedEvent{BroadcastEvent: BroadcastEvent{Seq: 1}, TurnNumber: 1})
+	feed.push(TurnStartedEvent{BroadcastEvent: BroadcastEvent{Seq: 2}, TurnNumber: 2})
+
+	for want := uint64(1); want <= 2; want++ {
+		select {
+		case event := <-published:
+			turn, ok := event.(TurnStartedEvent)
+			if !ok || turn.Seq != want {
+				t.Fatalf("Expected turn event with seq %d, got %+v", want, event)
+			}
+			if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+				t.Errorf("Expected event to be delayed by 50ms, got %v", elapsed)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected event with seq %d", want)
+		}
+	}
+}
+
+func TestGame_Spectate(t *testing.T) {
+	q := queue.NewInMemoryQueue()
+	game := NewGameWithOptions("test-game-spectate", q, TestMode, gamemap.NewMapManager(), Options{
+		MaxSpectators:  1,
+		SpectatorDelay: 30 * time.Millisecond,
+	})
+	game.core.Join(Player{Id: "player1", Name: "Player One"})
+	game.core.Join(Player{Id: "player2", Name: "Player Two"})
+	if err := game.core.Start(); err != nil {
+		t.Fatalf("Expected no error starting core, got %v", err)
+	}
+	enemyKing := gamemap.Pos{X: 10, Y: 10}
+	game.core._map.SetBlock(enemyKing, block.NewBlock(block.KingName, 5, 1))
+
+	broadcastCh := q.Subscribe("test-game-spectate/broadcast")
+	spectateCh := q.Subscribe("test-game-spectate/spectate")
+	errorCh := q.Subscribe("test-game-spectate/player/watcher2")
+	game.Start()
+	defer game.Stop()
+
+	q.Publish("test-game-spectate/commands", SpectateCommand{CommandEvent: CommandEvent{PlayerId: "watcher1"}, SpectatorName: "Watcher"})
+
+	// 玩家收到观战人数变化，回合定时器的事件可能先到达
+	timeout := time.After(time.Second)
+	for updated := false; !updated; {
+		select {
+		case event := <-broadcastCh:
+			status, ok := event.(GameStatusUpdateEvent)
+			if ok && status.Spectators != 1 {
+				t.Errorf("Expected 1 spectator in status update, got %d", status.Spectators)
+			}
+			updated = ok
+		case <-timeout:
+			t.Fatal("Expected status update after spectate")
+		}
+	}
+
+	// 快照经过延迟后发布，观战者可以看到完整地图
+	timeout = time.After(time.Second)
+	var snapshot SpectatorSnapshotEvent
+	for received := false; !received; {
+		select {
+		case event := <-spectateCh:
+			snapshot, received = event.(SpectatorSnapshotEvent)
+		case <-timeout:
+			t.Fatal("Expected spectator snapshot")
+		}
+	}
+	if snapshot.SpectatorId != "watcher1" || snapshot.Spectators != 1 || len(snapshot.Players) != 2 {
+		t.Errorf("Unexpected snapshot %+v", snapshot)
+	}
+	if snapshot.Map == nil {
+		t.Fatal("Expected map in snapshot")
+	}
+	if king, _ := snapshot.Map.Block(enemyKing); king == nil || king.Meta().Name != block.KingName {
+		t.Errorf("Expected full vision of enemy king, got %v", king)
+	}
+
+	q.Publish("test-game-spectate/commands", SpectateCommand{CommandEvent: CommandEvent{PlayerId: "watcher2"}})
+	select {
+	case event := <-errorCh:
+		if e, ok := event.(PlayerErrorEvent); !ok || e.Error == "" {
+			t.Errorf("Expected spectator limit error, got %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected error for spectator over the limit")
+	}
+}
@@ -0,0 +1,138 @@
+package lobby
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"server/internal/game"
+	"sort"
+)
+
+// GameSummary 管理接口中的对局概要
+type GameSummary struct {
+	GameId     string          `json:"gameId"`
+	Mode       string          `json:"mode"`
+	Status     game.Status     `json:"status"`
+	TurnNumber uint16          `json:"turnNumber"`
+	Players    []PlayerSummary `json:"players"`
+	// Spectators 当前观战人数，观战者不计入 Players
+	Spectators int `json:"spectators"`
+	// Private 私人房间，Host 为房主
+	Private bool   `json:"private"`
+	Host    string `json:"host,omitempty"`
+}
+
+type PlayerSummary struct {
+	Id     string            `json:"id"`
+	Name   string            `json:"name"`
+	Status game.PlayerStatus `json:"status"`
+}
+
+// ListGames 返回所有对局的概要，按 gameId 排序
+func (l *Lobby) ListGames() []GameSummary {
+	games := l.GetGameList()
+
+	summaries := make([]GameSummary, 0, len(games))
+	for gameId, gameInstance := range games {
+		summaries = append(summaries, summarizeGame(gameId, gameInstance))
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].GameId < summaries[j].GameId
+	})
+	return summaries
+}
+
+func summarizeGame(gameId string, gameInstance *game.Game) GameSummary {
+	room := gameInstance.Room()
+	summary := GameSummary{
+		GameId:  gameId,
+		Mode:    gameInstance.Mode().Name,
+		Players: make([]PlayerSummary, 0),
+		Private: room.Private,
+		Host:    room.Host,
+	}
+	gameInstance.View(func(core game.Core) {
+		summary.Status = core.Status()
+		summary.TurnNumber = core.TurnNumber()
+		summary.Spectators = len(core.Spectators())
+		for _, player := range core.Players() {
+			summary.Players = append(summary.Players, PlayerSummary{
+				Id:     player.Id,
+				Name:   player.Name,
+				Status: player.Status,
+			})
+		}
+	})
+	return summary
+}
+
+// StopGame 强制结束对局并从大厅移除
+func (l *Lobby) StopGame(gameId string) error {
+	l.gamesMu.Lock()
+	gameInstance, exists := l.games[gameId]
+	delete(l.games, gameId)
+	l.gamesMu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("game not found: %s", gameId)
+	}
+	if l.router != nil {
+		l.router.Release(gameId)
+	}
+
+	if err := gameInstance.Stop(); err != nil {
+		return err
+	}
+
+	l.queue.Publish("lobby/events", GameStoppedEvent{GameId: gameId, Reason: "admin"})
+	slog.Info("game stopped by admin", "gameId", gameId)
+	return nil
+}
+
+// KickPlayer 将玩家移出对局。离开指令经由对局的事件循环执行 Core.Leave，
+// 与玩家自行离开的处理一致，并广播 PlayerLeftEvent。
+func (l *Lobby) KickPlayer(gameId, playerId string) error {
+	l.gamesMu.RLock()
+	gameInstance, exists := l.games[gameId]
+	l.gamesMu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("game not found: %s", gameId)
+	}
+	var err error
+	gameInstance.View(func(core game.Core) {
+		_, err = core.GetPlayer(playerId)
+	})
+	if err != nil {
+		return err
+	}
+
+	l.queue.Publish(fmt.Sprintf("%s/commands", gameId), game.LeaveCommand{
+		CommandEvent: game.CommandEvent{PlayerId: playerId},
+	})
+	slog.Info("player kicked by admin", "gameId", gameId, "player", playerId)
+	return nil
+}
+
+func (l *Lobby) ListGamesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(l.ListGames())
+}
+
+func (l *Lobby) StopGameHandler(w http.ResponseWriter, r *http.Request) {
+	if err := l.StopGame(r.PathValue("id")); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (l *Lobby) KickPlayerHandler(w http.ResponseWriter, r *http.Request) {
+	if err := l.KickPlayer(r.PathValue("id"), r.PathValue("playerId")); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
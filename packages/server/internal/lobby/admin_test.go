@@ -0,0 +1,66 @@
+package lobby
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"server/internal/game"
+	"testing"
+	"time"
+)
+
+func TestLobby_KickAndStopGame(t *testing.T) {
+	lobby := createTestLobby()
+	gameId := "admin-test-game"
+	lobby.getOrCreateGame(gameId, game.Classic1v1)
+	broadcast := lobby.queue.Subscribe(gameId + "/broadcast")
+
+	// 等待对局订阅指令通道后再加入玩家
+	deadline := time.Now().Add(time.Second)
+	for len(lobby.ListGames()[0].Players) == 0 && time.Now().Before(deadline) {
+		lobby.queue.Publish(gameId+"/commands", game.JoinCommand{
+			CommandEvent: game.CommandEvent{PlayerId: "p1"},
+			PlayerName:   "Player 1",
+		})
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	summaries := lobby.ListGames()
+	if len(summaries) != 1 || len(summaries[0].Players) != 1 || summaries[0].Players[0].Id != "p1" {
+		t.Fatalf("Expected one game with p1, got %+v", summaries)
+	}
+
+	if err := lobby.KickPlayer(gameId, "nobody"); err == nil {
+		t.Error("Expected error when kicking unknown player")
+	}
+	if err := lobby.KickPlayer(gameId, "p1"); err != nil {
+		t.Fatalf("Expected no error kicking p1, got %v", err)
+	}
+
+	timeout := time.After(time.Second)
+	for kicked := false; !kicked; {
+		select {
+		case event := <-broadcast:
+			left, ok := event.(game.PlayerLeftEvent)
+			kicked = ok && left.PlayerId == "p1"
+		case <-timeout:
+			t.Fatal("Expected PlayerLeftEvent for kicked player")
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/admin/games/"+gameId, nil)
+	req.SetPathValue("id", gameId)
+	rec := httptest.NewRecorder()
+	lobby.StopGameHandler(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 on stop, got %d", rec.Code)
+	}
+	if len(lobby.GetGameList()) != 0 {
+		t.Error("Expected stopped game to be removed")
+	}
+
+	rec = httptest.NewRecorder()
+	lobby.StopGameHandler(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown game, got %d", rec.Code)
+	}
+}
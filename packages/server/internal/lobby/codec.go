@@ -0,0 +1,92 @@
+package lobby
+
+import (
+	"encoding/json"
+	"fmt"
+	"server/internal/game"
+	"server/internal/queue"
+)
+
+func init() {
+	queue.RegisterEvent("lobby.command", 1, LobbyCommand{})
+
+	queue.RegisterEvent("lobby.gameCreated", 1, GameCreatedEvent{})
+	queue.RegisterEvent("lobby.gameInfo", 1, GameInfoEvent{})
+	queue.RegisterEvent("lobby.gameStopped", 1, GameStoppedEvent{})
+	queue.RegisterEvent("lobby.roomCreated", 1, RoomCreatedEvent{})
+}
+
+type lobbyCommandJSON struct {
+	Type    string          `json:"type"`
+	GameId  string          `json:"gameId"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// UnmarshalJSON 按指令类型还原 Payload 的具体类型
+func (c *LobbyCommand) UnmarshalJSON(b []byte) error {
+	var data lobbyCommandJSON
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	c.Type = data.Type
+	c.GameId = data.GameId
+	c.Payload = nil
+	if len(data.Payload) == 0 || string(data.Payload) == "null" {
+		return nil
+	}
+
+	switch data.Type {
+	case "createGame":
+		var payload CreateGamePayload
+		if err := json.Unmarshal(data.Payload, &payload); err != nil {
+			return err
+		}
+		c.Payload = payload
+	case "createRoom":
+		var payload CreateRoomPayload
+		if err := json.Unmarshal(data.Payload, &payload); err != nil {
+			return err
+		}
+		c.Payload = payload
+	default:
+		var payload interface{}
+		if err := json.Unmarshal(data.Payload, &payload); err != nil {
+			return err
+		}
+		c.Payload = payload
+	}
+	return nil
+}
+
+type createGamePayloadJSON struct {
+	GameMode string  `json:"gameMode"`
+	Speed    float64 `json:"speed,omitempty"`
+	MapId    string  `json:"mapId,omitempty"`
+}
+
+// MarshalJSON 游戏模式包含结束条件等接口，只按名称与速度传输
+func (p CreateGamePayload) MarshalJSON() ([]byte, error) {
+	return json.Marshal(createGamePayloadJSON{
+		GameMode: p.GameMode.Name,
+		Speed:    p.GameMode.Speed,
+		MapId:    p.MapId,
+	})
+}
+
+func (p *CreateGamePayload) UnmarshalJSON(b []byte) error {
+	var data createGamePayloadJSON
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	mode, exists := game.GetGameMode(data.GameMode)
+	if !exists {
+		return fmt.Errorf("unknown game mode: %s", data.GameMode)
+	}
+	mode.SetSpeed(data.Speed)
+
+	p.GameMode = mode
+	p.MapId = data.MapId
+	return nil
+}
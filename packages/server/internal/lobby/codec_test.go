@@ -0,0 +1,110 @@
+package lobby
+
+import (
+	"reflect"
+	"server/internal/game"
+	"server/internal/queue"
+	"strings"
+	"testing"
+)
+
+func TestCodec_RoundTripAllLobbyEvents(t *testing.T) {
+	samples := map[string]queue.Event{
+		"lobby.command": LobbyCommand{
+			Type:    "createGame",
+			GameId:  "g1",
+			Payload: CreateGamePayload{GameMode: game.Classic1v1, MapId: "map-1"},
+		},
+		"lobby.gameCreated": GameCreatedEvent{GameId: "g1", GameMode: game.Classic1v1.Name, MapId: "map-1", Private: true},
+		"lobby.gameInfo": GameInfoEvent{
+			GameId: "g1",
+			Exists: true,
+			Game: &GameSummary{
+				GameId:     "g1",
+				Status:     game.StatusInProgress,
+				TurnNumber: 5,
+				Players:    []PlayerSummary{{Id: "p1", Name: "Alice", Status: game.PlayerStatusInGame}},
+				Spectators: 3,
+				Private:    true,
+				Host:       "p1",
+			},
+		},
+		"lobby.gameStopped": GameStoppedEvent{GameId: "g1"},
+		"lobby.roomCreated": RoomCreatedEvent{GameId: "ROOM1", Host: "u1", Error: "game already has players"},
+	}
+
+	for _, name := range queue.RegisteredEvents() {
+		if !strings.HasPrefix(name, "lobby.") {
+			continue
+		}
+
+		t.Run(name, func(t *testing.T) {
+			sample, ok := samples[name]
+			if !ok {
+				t.Fatalf("No round-trip sample for registered event %s", name)
+			}
+
+			data, err := queue.Encode(sample, "test")
+			if err != nil {
+				t.Fatalf("Expected no error encoding, got %v", err)
+			}
+			decoded, envelope, err := queue.Decode(data)
+			if err != nil {
+				t.Fatalf("Expected no error decoding, got %v", err)
+			}
+			if envelope.Type != name {
+				t.Errorf("Expected envelope type %s, got %s", name, envelope.Type)
+			}
+
+			// 游戏模式包含函数字段，按名称与速度比较
+			if cmd, ok := sample.(LobbyCommand); ok {
+				got, ok := decoded.(LobbyCommand)
+				if !ok {
+					t.Fatalf("Expected LobbyCommand, got %T", decoded)
+				}
+				want := cmd.Payload.(CreateGamePayload)
+				payload, ok := got.Payload.(CreateGamePayload)
+				if !ok || got.Type != cmd.Type || got.GameId != cmd.GameId ||
+					payload.MapId != want.MapId || payload.GameMode.Name != want.GameMode.Name ||
+					payload.GameMode.Speed != want.GameMode.Speed {
+					t.Errorf("Lobby command did not round-trip: %#v", got)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(decoded, sample) {
+				t.Errorf("Expected %#v, got %#v", sample, decoded)
+			}
+		})
+	}
+}
+
+func TestCodec_CreateRoomPayload(t *testing.T) {
+	cmd := LobbyCommand{
+		Type:   "createRoom",
+		GameId: "K7QX2MPA",
+		Payload: CreateRoomPayload{
+			Game:     CreateGamePayload{GameMode: game.Classic1v1, MapId: "map-1"},
+			Host:     "p1",
+			Password: "secret",
+		},
+	}
+
+	data, err := queue.Encode(cmd, "test")
+	if err != nil {
+		t.Fatalf("Expected no error encoding, got %v", err)
+	}
+	decoded, _, err := queue.Decode(data)
+	if err != nil {
+		t.Fatalf("Expected no error decoding, got %v", err)
+	}
+
+	payload, ok := decoded.(LobbyCommand).Payload.(CreateRoomPayload)
+	if !ok {
+		t.Fatalf("Expected CreateRoomPayload, got %T", decoded.(LobbyCommand).Payload)
+	}
+	if payload.Host != "p1" || payload.Password != "secret" ||
+		payload.Game.GameMode.Name != game.Classic1v1.Name || payload.Game.MapId != "map-1" {
+		t.Errorf("Create room payload did not round-trip: %#v", payload)
+	}
+}
@@ -1,6 +1,7 @@
 package lobby
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"server/internal/game"
@@ -14,6 +15,12 @@ type Lobby struct {
 	gamesMu    sync.RWMutex
 	queue      queue.Queue
 	mapManager gamemap.MapManager
+	// router 非空时为多实例部署，只承载本实例取得归属的对局
+	router queue.Router
+	// draining 停机开始后不再创建对局，由 gamesMu 保护
+	draining bool
+	// gameOptions 创建对局时使用的连接管理参数
+	gameOptions game.Options
 }
 
 type LobbyCommand struct {
@@ -24,13 +31,66 @@ type LobbyCommand struct {
 
 type CreateGamePayload struct {
 	GameMode game.GameMode `json:"gameMode"`
+	MapId    string        `json:"mapId"`
+}
+
+// CreateRoomPayload 创建私人房间，GameId 为邀请码，Password 为空时无需密码
+type CreateRoomPayload struct {
+	Game     CreateGamePayload `json:"game"`
+	Host     string            `json:"host"`
+	Password string            `json:"password,omitempty"`
+}
+
+// ErrRoomExists createGame 或 createRoom 的 gameId 已有对局
+var ErrRoomExists = errors.New("game already exists")
+
+// PlayerTopic 发给单个用户的大厅事件主题
+func PlayerTopic(userId string) string {
+	return fmt.Sprintf("lobby/player/%s", userId)
+}
+
+// RoomCreatedEvent 发布到房主的大厅主题，Error 非空时房间未创建
+type RoomCreatedEvent struct {
+	GameId string `json:"gameId"`
+	Host   string `json:"host"`
+	Error  string `json:"error,omitempty"`
+}
+
+// 以下为发布到 lobby/events 的大厅事件
+
+type GameCreatedEvent struct {
+	GameId   string `json:"gameId"`
+	GameMode string `json:"gameMode"`
+	MapId    string `json:"mapId,omitempty"`
+	Private  bool   `json:"private,omitempty"`
+}
+
+// GameInfoEvent 对局不存在时 Game 为空
+type GameInfoEvent struct {
+	GameId string       `json:"gameId"`
+	Exists bool         `json:"exists"`
+	Game   *GameSummary `json:"game,omitempty"`
+}
+
+// GameStoppedEvent Reason 为 admin 或 shutdown
+type GameStoppedEvent struct {
+	GameId string `json:"gameId"`
+	Reason string `json:"reason,omitempty"`
 }
 
 func NewLobby(q queue.Queue, mapManager gamemap.MapManager) *Lobby {
+	return NewLobbyWithOptions(q, mapManager, game.DefaultOptions())
+}
+
+// NewLobbyWithOptions 创建大厅，gameOptions 应用于大厅创建的所有对局
+func NewLobbyWithOptions(q queue.Queue, mapManager gamemap.MapManager, gameOptions game.Options) *Lobby {
+	router, _ := q.(queue.Router)
 	return &Lobby{
-		games:      make(map[string]*game.Game),
-		queue:      q,
-		mapManager: mapManager,
+		games:       make(map[string]*game.Game),
+		queue:       q,
+		mapManager:  mapManager,
+		router:      router,
+		gameOptions: gameOptions,
 	}
 }
 
@@ -69,6 +129,8 @@ func (l *Lobby) handleCommand(cmd LobbyCommand) error {
 	switch cmd.Type {
 	case "createGame":
 		return l.handleCreateGame(cmd)
+	case "createRoom":
+		return l.handleCreateRoom(cmd)
 	case "getGameInfo":
 		return l.handleGetGameInfo(cmd)
 	default:
@@ -77,46 +139,156 @@ func (l *Lobby) handleCommand(cmd LobbyCommand) error {
 }
 
 func (l *Lobby) handleCreateGame(cmd LobbyCommand) error {
+	if l.isDraining() {
+		return fmt.Errorf("lobby is shutting down, game not created: %s", cmd.GameId)
+	}
+
 	payload, ok := cmd.Payload.(CreateGamePayload)
 	if !ok {
 		payload = CreateGamePayload{GameMode: game.Classic1v1} // 默认游戏模式
 	}
 
-	gameInstance := l.getOrCreateGame(cmd.GameId, payload.GameMode)
+	if payload.MapId != "" {
+		if err := l.mapManager.ValidateMapId(payload.MapId); err != nil {
+			return fmt.Errorf("invalid map %s: %w", payload.MapId, err)
+		}
+	}
+
+	gameInstance, created := l.loadOrCreateGame(cmd.GameId, payload.GameMode)
+	if gameInstance == nil {
+		// 对局由其他实例承载
+		return nil
+	}
+	// 已有的对局只能由房主或开局流程修改，不能被重复的 createGame 覆盖模式与地图
+	if !created {
+		return fmt.Errorf("%w: %s", ErrRoomExists, cmd.GameId)
+	}
+
+	if payload.MapId != "" {
+		if err := gameInstance.SetMapId(payload.MapId); err != nil {
+			return fmt.Errorf("failed to set map: %w", err)
+		}
+	}
 
-	l.queue.Publish("lobby/events", map[string]interface{}{
-		"type":   "gameCreated",
-		"gameId": cmd.GameId,
-		"game":   gameInstance,
+	l.queue.Publish("lobby/events", GameCreatedEvent{
+		GameId:   cmd.GameId,
+		GameMode: payload.GameMode.Name,
+		MapId:    payload.MapId,
 	})
 
 	return nil
 }
 
+// handleCreateRoom 以邀请码为 gameId 创建私人房间，邀请码已被占用时拒绝。
+// 承载房间的实例将结果发布到房主的大厅主题，接入层收到后才回复客户端
+func (l *Lobby) handleCreateRoom(cmd LobbyCommand) error {
+	payload, ok := cmd.Payload.(CreateRoomPayload)
+	if !ok || payload.Host == "" {
+		return fmt.Errorf("invalid createRoom payload for %s", cmd.GameId)
+	}
+
+	hosted, err := l.createRoom(cmd.GameId, payload)
+	if !hosted {
+		return nil
+	}
+	result := RoomCreatedEvent{GameId: cmd.GameId, Host: payload.Host}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	l.queue.Publish(PlayerTopic(payload.Host), result)
+	return err
+}
+
+// createRoom hosted 为 false 时房间由其他实例承载，本实例不回复
+func (l *Lobby) createRoom(gameId string, payload CreateRoomPayload) (hosted bool, err error) {
+	if l.isDraining() {
+		return true, fmt.Errorf("lobby is shutting down, room not created: %s", gameId)
+	}
+	if payload.Game.GameMode.Name == "" {
+		payload.Game.GameMode = game.Classic1v1
+	}
+
+	if payload.Game.MapId != "" {
+		if err := l.mapManager.ValidateMapId(payload.Game.MapId); err != nil {
+			return true, fmt.Errorf("invalid map %s: %w", payload.Game.MapId, err)
+		}
+	}
+
+	gameInstance, created := l.loadOrCreateGame(gameId, payload.Game.GameMode)
+	if gameInstance == nil {
+		return false, nil
+	}
+	if !created {
+		return true, fmt.Errorf("%w: %s", ErrRoomExists, gameId)
+	}
+
+	if err := gameInstance.SetRoom(payload.Host, payload.Password); err != nil {
+		return true, fmt.Errorf("failed to create room: %w", err)
+	}
+	if payload.Game.MapId != "" {
+		if err := gameInstance.SetMapId(payload.Game.MapId); err != nil {
+			return true, fmt.Errorf("failed to set map: %w", err)
+		}
+	}
+
+	l.queue.Publish("lobby/events", GameCreatedEvent{
+		GameId:   gameId,
+		GameMode: payload.Game.GameMode.Name,
+		MapId:    payload.Game.MapId,
+		Private:  true,
+	})
+	return true, nil
+}
+
 func (l *Lobby) handleGetGameInfo(cmd LobbyCommand) error {
 	l.gamesMu.RLock()
 	gameInstance, exists := l.games[cmd.GameId]
 	l.gamesMu.RUnlock()
 
-	l.queue.Publish("lobby/events", map[string]interface{}{
-		"type":   "gameInfo",
-		"gameId": cmd.GameId,
-		"exists": exists,
-		"game":   gameInstance,
-	})
+	if !exists && l.hostedElsewhere(cmd.GameId) {
+		return nil
+	}
+
+	event := GameInfoEvent{GameId: cmd.GameId, Exists: exists}
+	if exists {
+		summary := summarizeGame(cmd.GameId, gameInstance)
+		event.Game = &summary
+	}
+	l.queue.Publish("lobby/events", event)
 
 	return nil
 }
 
+// getOrCreateGame 多实例部署时若对局已由其他实例承载则返回 nil
 func (l *Lobby) getOrCreateGame(gameId string, gameMode game.GameMode) *game.Game {
+	gameInstance, _ := l.loadOrCreateGame(gameId, gameMode)
+	return gameInstance
+}
+
+// loadOrCreateGame 与 getOrCreateGame 相同，created 表示对局是否由本次调用创建
+func (l *Lobby) loadOrCreateGame(gameId string, gameMode game.GameMode) (gameInstance *game.Game, created bool) {
 	l.gamesMu.Lock()
 	defer l.gamesMu.Unlock()
 
 	if existingGame, exists := l.games[gameId]; exists {
-		return existingGame
+		return existingGame, false
+	}
+	if l.draining {
+		return nil, false
 	}
 
-	newGame := game.NewGame(gameId, l.queue, gameMode, l.mapManager)
+	if l.router != nil {
+		claimed, err := l.router.Claim(gameId)
+		if err != nil {
+			slog.Error("failed to claim game", "error", err, "gameId", gameId)
+			return nil, false
+		}
+		if !claimed {
+			return nil, false
+		}
+	}
+
+	newGame := game.NewGameWithOptions(gameId, l.queue, gameMode, l.mapManager, l.gameOptions)
 	l.games[gameId] = newGame
 
 	go func() {
@@ -126,7 +298,7 @@ func (l *Lobby) getOrCreateGame(gameId string, gameMode game.GameMode) *game.Gam
 	}()
 
 	slog.Info("created new game", "gameId", gameId, "gameMode", gameMode)
-	return newGame
+	return newGame, true
 }
 
 func (l *Lobby) GetGameList() map[string]*game.Game {
@@ -145,5 +317,17 @@ func (l *Lobby) RemoveGame(gameId string) {
 	defer l.gamesMu.Unlock()
 
 	delete(l.games, gameId)
+	if l.router != nil {
+		l.router.Release(gameId)
+	}
 	slog.Info("removed game", "gameId", gameId)
 }
+
+// hostedElsewhere 对局是否由其他实例承载
+func (l *Lobby) hostedElsewhere(gameId string) bool {
+	if l.router == nil {
+		return false
+	}
+	owner, err := l.router.Owner(gameId)
+	return err == nil && owner != "" && owner != l.router.InstanceID()
+}
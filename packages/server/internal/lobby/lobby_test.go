@@ -1,6 +1,7 @@
 package lobby
 
 import (
+	"errors"
 	"fmt"
 	"server/internal/game"
 	gamemap "server/internal/game/map"
@@ -157,6 +158,84 @@ func TestLobby_HandleCommand(t *testing.T) {
 		}
 	})
 
+	t.Run("create_room_command", func(t *testing.T) {
+		cmd := LobbyCommand{
+			Type:   "createRoom",
+			GameId: "ROOMCODE",
+			Payload: CreateRoomPayload{
+				Game:     CreateGamePayload{GameMode: game.Classic1v1},
+				Host:     "host-1",
+				Password: "secret",
+			},
+		}
+
+		results := lobby.queue.Subscribe(PlayerTopic("host-1"))
+		expectResult := func() RoomCreatedEvent {
+			t.Helper()
+			select {
+			case event := <-results:
+				return event.(RoomCreatedEvent)
+			case <-time.After(time.Second):
+				t.Fatal("Expected room created result for the host")
+				return RoomCreatedEvent{}
+			}
+		}
+
+		if err := lobby.handleCommand(cmd); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result := expectResult(); result.GameId != "ROOMCODE" || result.Error != "" {
+			t.Errorf("Expected room created for host-1, got %+v", result)
+		}
+
+		room := lobby.GetGameList()["ROOMCODE"].Room()
+		if !room.Private || room.Host != "host-1" || !room.HasPassword {
+			t.Errorf("Expected private room hosted by host-1 with password, got %+v", room)
+		}
+
+		// 邀请码已被占用
+		if err := lobby.handleCommand(cmd); err == nil {
+			t.Error("Expected error for duplicate invite code")
+		}
+		if result := expectResult(); result.Error == "" {
+			t.Error("Expected error result for duplicate invite code")
+		}
+
+		// createGame 不能修改已有的私人房间
+		err := lobby.handleCommand(LobbyCommand{
+			Type:    "createGame",
+			GameId:  "ROOMCODE",
+			Payload: CreateGamePayload{GameMode: game.Classic1v1},
+		})
+		if !errors.Is(err, ErrRoomExists) {
+			t.Errorf("Expected ErrRoomExists, got %v", err)
+		}
+	})
+
+	t.Run("create_game_existing_or_unknown_map", func(t *testing.T) {
+		// 重复的 createGame 不能修改其他人的对局
+		err := lobby.handleCommand(LobbyCommand{
+			Type:    "createGame",
+			GameId:  "cmd-test-game",
+			Payload: CreateGamePayload{GameMode: game.Classic1v1, MapId: "base-20x20-2-m0.2-c0.1-d5-s1"},
+		})
+		if !errors.Is(err, ErrRoomExists) {
+			t.Errorf("Expected ErrRoomExists for an existing game, got %v", err)
+		}
+
+		err = lobby.handleCommand(LobbyCommand{
+			Type:    "createGame",
+			GameId:  "unknown-map-game",
+			Payload: CreateGamePayload{GameMode: game.Classic1v1, MapId: "nope-1"},
+		})
+		if err == nil {
+			t.Error("Expected unknown map to be rejected")
+		}
+		if _, exists := lobby.GetGameList()["unknown-map-game"]; exists {
+			t.Error("Expected no game for an unknown map")
+		}
+	})
+
 	t.Run("unknown_command", func(t *testing.T) {
 		cmd := LobbyCommand{
 			Type:   "unknownCommand",
@@ -246,17 +325,17 @@ func TestLobby_EventPublishing(t *testing.T) {
 
 	select {
 	case event := <-eventChan:
-		eventMap, ok := event.(map[string]interface{})
+		created, ok := event.(GameCreatedEvent)
 		if !ok {
-			t.Fatal("Expected event to be map[string]interface{}")
+			t.Fatalf("Expected GameCreatedEvent, got %T", event)
 		}
 
-		if eventType, exists := eventMap["type"]; !exists || eventType != "gameCreated" {
-			t.Error("Expected gameCreated event type")
+		if created.GameId != "event-test-game" {
+			t.Errorf("Expected correct gameId in event, got %s", created.GameId)
 		}
 
-		if gameId, exists := eventMap["gameId"]; !exists || gameId != "event-test-game" {
-			t.Error("Expected correct gameId in event")
+		if created.GameMode != game.Classic1v1.Name {
+			t.Errorf("Expected game mode %s, got %s", game.Classic1v1.Name, created.GameMode)
 		}
 
 	case <-time.After(100 * time.Millisecond):
@@ -0,0 +1,84 @@
+package lobby
+
+import (
+	"server/internal/game"
+	gamemap "server/internal/game/map"
+	"server/internal/queue"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestLobby_MultiInstancePlacement(t *testing.T) {
+	mr := miniredis.RunT(t)
+	newQueue := func(instanceID string) *queue.RedisQueue {
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		q := queue.NewRedisQueue(client, "test:", instanceID, time.Second)
+		t.Cleanup(func() {
+			q.Close()
+			client.Close()
+		})
+		return q
+	}
+
+	wsTier := newQueue("ws-1")
+	lobbies := []*Lobby{
+		NewLobby(newQueue("game-1"), gamemap.NewMapManager()),
+		NewLobby(newQueue("game-2"), gamemap.NewMapManager()),
+	}
+	for _, l := range lobbies {
+		l.Start()
+		defer l.Stop()
+	}
+
+	hosts := func() (count int, host *Lobby) {
+		for _, l := range lobbies {
+			if len(l.GetGameList()) > 0 {
+				count++
+				host = l
+			}
+		}
+		return count, host
+	}
+
+	// 订阅在 Redis 端生效前的消息会丢失，重复发布即可，已创建对局后重复的 createGame 会被拒绝
+	deadline := time.Now().Add(2 * time.Second)
+	for count, _ := hosts(); count == 0 && time.Now().Before(deadline); count, _ = hosts() {
+		wsTier.Publish("lobby/commands", LobbyCommand{
+			Type:    "createGame",
+			GameId:  "shared-game",
+			Payload: CreateGamePayload{GameMode: game.Classic1v1},
+		})
+		time.Sleep(20 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	count, host := hosts()
+	if count != 1 {
+		t.Fatalf("Expected exactly one instance to host the game, got %d", count)
+	}
+	if owner, _ := wsTier.Owner("shared-game"); owner != host.router.InstanceID() {
+		t.Errorf("Expected owner %s, got %q", host.router.InstanceID(), owner)
+	}
+
+	deadline = time.Now().Add(2 * time.Second)
+	for len(host.ListGames()[0].Players) == 0 && time.Now().Before(deadline) {
+		wsTier.Publish("shared-game/commands", game.JoinCommand{
+			CommandEvent: game.CommandEvent{PlayerId: "p1"},
+			PlayerName:   "Player 1",
+		})
+		time.Sleep(20 * time.Millisecond)
+	}
+	if players := host.ListGames()[0].Players; len(players) != 1 || players[0].Id != "p1" {
+		t.Errorf("Expected join routed to hosting instance, got %+v", players)
+	}
+
+	if err := host.StopGame("shared-game"); err != nil {
+		t.Fatalf("Expected no error stopping game, got %v", err)
+	}
+	if owner, _ := wsTier.Owner("shared-game"); owner != "" {
+		t.Errorf("Expected claim released after stop, got %q", owner)
+	}
+}
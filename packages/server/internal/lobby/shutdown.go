@@ -0,0 +1,36 @@
+package lobby
+
+import (
+	"log/slog"
+	"server/internal/game"
+)
+
+func (l *Lobby) isDraining() bool {
+	l.gamesMu.RLock()
+	defer l.gamesMu.RUnlock()
+	return l.draining
+}
+
+// Shutdown 停止创建新对局并结束所有对局，返回结束的对局数。
+// 进行中的对局广播没有胜者的 GameEndedEvent 作为最终结果；每个对局都会发布 Reason 为 shutdown 的
+// GameStoppedEvent，并释放多实例归属。
+func (l *Lobby) Shutdown() int {
+	l.gamesMu.Lock()
+	l.draining = true
+	games := l.games
+	l.games = make(map[string]*game.Game)
+	l.gamesMu.Unlock()
+
+	for gameId, gameInstance := range games {
+		if err := gameInstance.Stop(); err != nil {
+			slog.Error("failed to stop game", "error", err, "gameId", gameId)
+		}
+		if l.router != nil {
+			l.router.Release(gameId)
+		}
+		l.queue.Publish("lobby/events", GameStoppedEvent{GameId: gameId, Reason: "shutdown"})
+	}
+
+	slog.Info("lobby drained", "games", len(games))
+	return len(games)
+}
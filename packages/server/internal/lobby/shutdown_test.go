@@ -0,0 +1,98 @@
+package lobby
+
+import (
+	"server/internal/game"
+	gamemap "server/internal/game/map"
+	"server/internal/queue"
+	"testing"
+	"time"
+)
+
+func TestLobby_Shutdown(t *testing.T) {
+	q := queue.NewInMemoryQueue()
+	lobby := NewLobby(q, gamemap.NewMapManager())
+	eventChan := q.Subscribe("lobby/events")
+
+	lobby.getOrCreateGame("game-b", game.Classic1v1)
+	lobby.getOrCreateGame("game-a", game.Classic1v1)
+
+	if stopped := lobby.Shutdown(); stopped != 2 {
+		t.Fatalf("Expected 2 games to be stopped, got %d", stopped)
+	}
+	if len(lobby.GetGameList()) != 0 {
+		t.Error("Expected no games after shutdown")
+	}
+
+	stopped := make(map[string]bool)
+	for len(stopped) < 2 {
+		select {
+		case event := <-eventChan:
+			if e, ok := event.(GameStoppedEvent); ok {
+				if e.Reason != "shutdown" {
+					t.Errorf("Expected reason shutdown, got %q", e.Reason)
+				}
+				stopped[e.GameId] = true
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected GameStoppedEvent for every game, got %v", stopped)
+		}
+	}
+}
+
+func TestLobby_ShutdownRejectsNewGames(t *testing.T) {
+	lobby := createTestLobby()
+	lobby.Shutdown()
+
+	err := lobby.handleCommand(LobbyCommand{Type: "createGame", GameId: "late-game"})
+	if err == nil {
+		t.Error("Expected createGame to fail after shutdown")
+	}
+	if lobby.getOrCreateGame("late-game", game.Classic1v1) != nil {
+		t.Error("Expected no game to be created after shutdown")
+	}
+}
+
+func TestLobby_ShutdownEndsGamesInProgress(t *testing.T) {
+	lobby := createTestLobby()
+	gameId := "running-game"
+	lobby.getOrCreateGame(gameId, game.Classic1v1)
+	broadcast := lobby.queue.Subscribe(gameId + "/broadcast")
+
+	// 等待对局订阅指令通道后再加入玩家
+	deadline := time.Now().Add(time.Second)
+	for len(lobby.ListGames()[0].Players) < 2 && time.Now().Before(deadline) {
+		for _, playerId := range []string{"p1", "p2"} {
+			lobby.queue.Publish(gameId+"/commands", game.JoinCommand{
+				CommandEvent: game.CommandEvent{PlayerId: playerId},
+				PlayerName:   playerId,
+			})
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	lobby.queue.Publish(gameId+"/control", game.StartGameControl{})
+
+	waitFor := func(match func(event queue.Event) bool, what string) {
+		t.Helper()
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case event := <-broadcast:
+				if match(event) {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("Expected %s on broadcast topic", what)
+			}
+		}
+	}
+	waitFor(func(event queue.Event) bool {
+		_, ok := event.(game.GameStartedEvent)
+		return ok
+	}, "GameStartedEvent")
+
+	lobby.Shutdown()
+	waitFor(func(event queue.Event) bool {
+		ended, ok := event.(game.GameEndedEvent)
+		return ok && ended.Winner == "" && ended.GameStatus == game.StatusFinished
+	}, "GameEndedEvent without winner")
+}
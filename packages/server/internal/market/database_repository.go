@@ -0,0 +1,93 @@
+package market
+
+import (
+	"fmt"
+	"time"
+
+	gamemap "server/internal/game/map"
+)
+
+// Tables:
+//   maps         (id, name, description, author_id, author_name, version, play_count, created_at, updated_at)
+//   map_versions (map_id, version, data, comment, created_at)
+//   map_ratings  (map_id, user_id, score)
+//   map_favorites(map_id, user_id, created_at)
+
+type DatabaseMapRepository struct {
+	// db *sql.DB
+}
+
+func NewDatabaseMapRepository( /* db *sql.DB */ ) *DatabaseMapRepository {
+	return &DatabaseMapRepository{
+		// db: db,
+	}
+}
+
+func (r *DatabaseMapRepository) CreateMap(authorId, authorName string, data gamemap.FileMap, comment string) (*StoredMap, error) {
+	// tx, err := r.db.Begin()
+	// tx.Exec("INSERT INTO maps (id, name, description, author_id, author_name, version, play_count, created_at, updated_at) VALUES (?, ?, ?, ?, ?, 1, 0, ?, ?)", ...)
+	// tx.Exec("INSERT INTO map_versions (map_id, version, data, comment, created_at) VALUES (?, 1, ?, ?, ?)", ...)
+	// tx.Commit()
+
+	now := time.Now()
+	return &StoredMap{
+		Id:         generateMapID(),
+		Name:       data.Name,
+		Desc:       data.Desc,
+		AuthorId:   authorId,
+		AuthorName: authorName,
+		Version:    1,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}, nil
+}
+
+func (r *DatabaseMapRepository) GetMap(id string) (*StoredMap, error) {
+	// query := "SELECT m.*, COALESCE(AVG(r.score), 0), COUNT(r.score) FROM maps m LEFT JOIN map_ratings r ON r.map_id = m.id WHERE m.id = ? GROUP BY m.id"
+
+	return nil, fmt.Errorf("map not found (database implementation)")
+}
+
+func (r *DatabaseMapRepository) ListMaps(query MapQuery) ([]*StoredMap, error) {
+	// query := "SELECT ... FROM maps m LEFT JOIN map_ratings r ON r.map_id = m.id WHERE m.name LIKE ? OR m.author_name LIKE ? GROUP BY m.id ORDER BY ... LIMIT ? OFFSET ?"
+
+	return []*StoredMap{}, nil
+}
+
+func (r *DatabaseMapRepository) AddVersion(id string, data gamemap.FileMap, comment string) (*StoredMap, error) {
+	// tx.Exec("INSERT INTO map_versions (map_id, version, data, comment, created_at) SELECT id, version + 1, ?, ?, ? FROM maps WHERE id = ?", ...)
+	// tx.Exec("UPDATE maps SET name = ?, description = ?, version = version + 1, updated_at = ? WHERE id = ?", ...)
+
+	return nil, fmt.Errorf("map not found (database implementation)")
+}
+
+func (r *DatabaseMapRepository) GetVersions(id string) ([]MapVersion, error) {
+	// query := "SELECT version, data, comment, created_at FROM map_versions WHERE map_id = ? ORDER BY version"
+
+	return nil, fmt.Errorf("map not found (database implementation)")
+}
+
+func (r *DatabaseMapRepository) IncrementPlayCount(id string) error {
+	// query := "UPDATE maps SET play_count = play_count + 1 WHERE id = ?"
+
+	return nil
+}
+
+func (r *DatabaseMapRepository) RateMap(id, userId string, score int) (*StoredMap, error) {
+	// query := "INSERT INTO map_ratings (map_id, user_id, score) VALUES (?, ?, ?) ON CONFLICT (map_id, user_id) DO UPDATE SET score = excluded.score"
+
+	return nil, fmt.Errorf("map not found (database implementation)")
+}
+
+func (r *DatabaseMapRepository) SetFavorite(id, userId string, favorite bool) error {
+	// favorite:  "INSERT INTO map_favorites (map_id, user_id, created_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING"
+	// otherwise: "DELETE FROM map_favorites WHERE map_id = ? AND user_id = ?"
+
+	return nil
+}
+
+func (r *DatabaseMapRepository) ListFavorites(userId string) ([]*StoredMap, error) {
+	// query := "SELECT m.* FROM maps m JOIN map_favorites f ON f.map_id = m.id WHERE f.user_id = ? ORDER BY f.created_at DESC"
+
+	return []*StoredMap{}, nil
+}
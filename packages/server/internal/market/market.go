@@ -0,0 +1,509 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gamemap "server/internal/game/map"
+)
+
+const (
+	maxUploadBytes = 256 * 1024
+	maxMapSide     = 100
+	maxNameLength  = 40
+	maxDescLength  = 500
+	defaultLimit   = 20
+	maxLimit       = 100
+)
+
+const (
+	SortNewest = "newest"
+	SortRating = "rating"
+	SortPlays  = "plays"
+)
+
+var _ gamemap.MapStore = (*MarketService)(nil)
+
+type MarketService struct {
+	repo MapRepository
+}
+
+type StoredMap struct {
+	Id          string    `json:"id"`
+	Name        string    `json:"name"`
+	Desc        string    `json:"desc"`
+	AuthorId    string    `json:"authorId"`
+	AuthorName  string    `json:"authorName"`
+	Version     int       `json:"version"`
+	Width       uint16    `json:"width"`
+	Height      uint16    `json:"height"`
+	MinPlayers  int       `json:"minPlayers"`
+	MaxPlayers  int       `json:"maxPlayers"`
+	PlayCount   int64     `json:"playCount"`
+	RatingCount int       `json:"ratingCount"`
+	Rating      float64   `json:"rating"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+type MapVersion struct {
+	Version   int             `json:"version"`
+	Map       gamemap.FileMap `json:"map"`
+	Comment   string          `json:"comment"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+type MapQuery struct {
+	Search string
+	Sort   string
+	Offset int
+	Limit  int
+}
+
+type UploadMapRequest struct {
+	Map     gamemap.FileMap `json:"map"`
+	Comment string          `json:"comment"`
+}
+
+type RateMapRequest struct {
+	Score int `json:"score"`
+}
+
+type MapDetailResponse struct {
+	Map      *StoredMap   `json:"map"`
+	Versions []MapVersion `json:"versions"`
+}
+
+type MapRepository interface {
+	CreateMap(authorId, authorName string, data gamemap.FileMap, comment string) (*StoredMap, error)
+	GetMap(id string) (*StoredMap, error)
+	ListMaps(query MapQuery) ([]*StoredMap, error)
+	AddVersion(id string, data gamemap.FileMap, comment string) (*StoredMap, error)
+	GetVersions(id string) ([]MapVersion, error)
+	IncrementPlayCount(id string) error
+	RateMap(id, userId string, score int) (*StoredMap, error)
+	SetFavorite(id, userId string, favorite bool) error
+	ListFavorites(userId string) ([]*StoredMap, error)
+}
+
+func NewMarketService(repo MapRepository) *MarketService {
+	return &MarketService{
+		repo: repo,
+	}
+}
+
+// LoadMap 返回地图的最新版本，供 DatabaseProvider 使用
+func (s *MarketService) LoadMap(id string) (*gamemap.FileMap, error) {
+	versions, err := s.repo.GetVersions(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("map has no versions: %s", id)
+	}
+
+	latest := versions[len(versions)-1].Map
+	return &latest, nil
+}
+
+func (s *MarketService) RecordPlay(id string) error {
+	return s.repo.IncrementPlayCount(id)
+}
+
+func (s *MarketService) ListHandler(w http.ResponseWriter, r *http.Request) {
+	query := MapQuery{
+		Search: r.URL.Query().Get("q"),
+		Sort:   r.URL.Query().Get("sort"),
+		Limit:  defaultLimit,
+	}
+	if offset, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && offset > 0 {
+		query.Offset = offset
+	}
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit > 0 {
+		query.Limit = min(limit, maxLimit)
+	}
+
+	maps, err := s.repo.ListMaps(query)
+	if err != nil {
+		http.Error(w, "Failed to list maps", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, maps)
+}
+
+func (s *MarketService) DetailHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	storedMap, err := s.repo.GetMap(id)
+	if err != nil {
+		http.Error(w, "Map not found", http.StatusNotFound)
+		return
+	}
+
+	versions, err := s.repo.GetVersions(id)
+	if err != nil {
+		http.Error(w, "Failed to load map versions", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, MapDetailResponse{
+		Map:      storedMap,
+		Versions: versions,
+	})
+}
+
+func (s *MarketService) UploadHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeUpload(w, r)
+	if !ok {
+		return
+	}
+
+	storedMap, err := s.repo.CreateMap(r.Header.Get("X-User-ID"), r.Header.Get("X-Username"), req.Map, req.Comment)
+	if err != nil {
+		http.Error(w, "Failed to store map", http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("map uploaded", "mapId", storedMap.Id, "author", storedMap.AuthorId)
+
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, storedMap)
+}
+
+func (s *MarketService) UpdateHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	storedMap, err := s.repo.GetMap(id)
+	if err != nil {
+		http.Error(w, "Map not found", http.StatusNotFound)
+		return
+	}
+	if storedMap.AuthorId != r.Header.Get("X-User-ID") {
+		http.Error(w, "Only the author can update this map", http.StatusForbidden)
+		return
+	}
+
+	req, ok := decodeUpload(w, r)
+	if !ok {
+		return
+	}
+
+	storedMap, err = s.repo.AddVersion(id, req.Map, req.Comment)
+	if err != nil {
+		http.Error(w, "Failed to store map", http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("map version added", "mapId", id, "version", storedMap.Version)
+	writeJSON(w, storedMap)
+}
+
+func (s *MarketService) RateHandler(w http.ResponseWriter, r *http.Request) {
+	var req RateMapRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Score < 1 || req.Score > 5 {
+		http.Error(w, "score must be between 1 and 5", http.StatusBadRequest)
+		return
+	}
+
+	storedMap, err := s.repo.RateMap(r.PathValue("id"), r.Header.Get("X-User-ID"), req.Score)
+	if err != nil {
+		http.Error(w, "Map not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, storedMap)
+}
+
+func (s *MarketService) FavoriteHandler(w http.ResponseWriter, r *http.Request) {
+	favorite := r.Method != http.MethodDelete
+
+	if err := s.repo.SetFavorite(r.PathValue("id"), r.Header.Get("X-User-ID"), favorite); err != nil {
+		http.Error(w, "Map not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *MarketService) FavoritesHandler(w http.ResponseWriter, r *http.Request) {
+	maps, err := s.repo.ListFavorites(r.Header.Get("X-User-ID"))
+	if err != nil {
+		http.Error(w, "Failed to list favorites", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, maps)
+}
+
+func decodeUpload(w http.ResponseWriter, r *http.Request) (*UploadMapRequest, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes)
+
+	var req UploadMapRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil, false
+	}
+
+	if err := validateMap(&req.Map); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	return &req, true
+}
+
+func validateMap(m *gamemap.FileMap) error {
+	m.Name = strings.TrimSpace(m.Name)
+	if m.Name == "" || len(m.Name) > maxNameLength {
+		return fmt.Errorf("map name must be between 1 and %d characters", maxNameLength)
+	}
+	if len(m.Desc) > maxDescLength {
+		return fmt.Errorf("map description must be at most %d characters", maxDescLength)
+	}
+
+	size := m.Size()
+	if size.Width > maxMapSide || size.Height > maxMapSide {
+		return fmt.Errorf("map must be at most %dx%d", maxMapSide, maxMapSide)
+	}
+
+	return m.Validate()
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+type storedEntry struct {
+	meta      StoredMap
+	versions  []MapVersion
+	ratings   map[string]int
+	favorites map[string]bool
+}
+
+type InMemoryMapRepository struct {
+	maps map[string]*storedEntry
+	mu   sync.RWMutex
+}
+
+func NewInMemoryMapRepository() *InMemoryMapRepository {
+	return &InMemoryMapRepository{
+		maps: make(map[string]*storedEntry),
+	}
+}
+
+func (r *InMemoryMapRepository) CreateMap(authorId, authorName string, data gamemap.FileMap, comment string) (*StoredMap, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	entry := &storedEntry{
+		meta: StoredMap{
+			Id:         generateMapID(),
+			AuthorId:   authorId,
+			AuthorName: authorName,
+			CreatedAt:  now,
+		},
+		ratings:   make(map[string]int),
+		favorites: make(map[string]bool),
+	}
+	entry.addVersion(data, comment, now)
+
+	r.maps[entry.meta.Id] = entry
+	return entry.snapshot(), nil
+}
+
+func (r *InMemoryMapRepository) GetMap(id string) (*StoredMap, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, exists := r.maps[id]
+	if !exists {
+		return nil, fmt.Errorf("map not found")
+	}
+	return entry.snapshot(), nil
+}
+
+func (r *InMemoryMapRepository) ListMaps(query MapQuery) ([]*StoredMap, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	search := strings.ToLower(query.Search)
+	result := make([]*StoredMap, 0, len(r.maps))
+	for _, entry := range r.maps {
+		if search != "" &&
+			!strings.Contains(strings.ToLower(entry.meta.Name), search) &&
+			!strings.Contains(strings.ToLower(entry.meta.AuthorName), search) {
+			continue
+		}
+		result = append(result, entry.snapshot())
+	}
+
+	sortMaps(result, query.Sort)
+
+	if query.Offset >= len(result) {
+		return []*StoredMap{}, nil
+	}
+	result = result[query.Offset:]
+	if query.Limit > 0 && len(result) > query.Limit {
+		result = result[:query.Limit]
+	}
+	return result, nil
+}
+
+func (r *InMemoryMapRepository) AddVersion(id string, data gamemap.FileMap, comment string) (*StoredMap, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, exists := r.maps[id]
+	if !exists {
+		return nil, fmt.Errorf("map not found")
+	}
+
+	entry.addVersion(data, comment, time.Now())
+	return entry.snapshot(), nil
+}
+
+func (r *InMemoryMapRepository) GetVersions(id string) ([]MapVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, exists := r.maps[id]
+	if !exists {
+		return nil, fmt.Errorf("map not found")
+	}
+
+	versions := make([]MapVersion, len(entry.versions))
+	copy(versions, entry.versions)
+	return versions, nil
+}
+
+func (r *InMemoryMapRepository) IncrementPlayCount(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, exists := r.maps[id]
+	if !exists {
+		return fmt.Errorf("map not found")
+	}
+
+	entry.meta.PlayCount++
+	return nil
+}
+
+func (r *InMemoryMapRepository) RateMap(id, userId string, score int) (*StoredMap, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, exists := r.maps[id]
+	if !exists {
+		return nil, fmt.Errorf("map not found")
+	}
+
+	entry.ratings[userId] = score
+
+	total := 0
+	for _, s := range entry.ratings {
+		total += s
+	}
+	entry.meta.RatingCount = len(entry.ratings)
+	entry.meta.Rating = float64(total) / float64(len(entry.ratings))
+
+	return entry.snapshot(), nil
+}
+
+func (r *InMemoryMapRepository) SetFavorite(id, userId string, favorite bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, exists := r.maps[id]
+	if !exists {
+		return fmt.Errorf("map not found")
+	}
+
+	if favorite {
+		entry.favorites[userId] = true
+	} else {
+		delete(entry.favorites, userId)
+	}
+	return nil
+}
+
+func (r *InMemoryMapRepository) ListFavorites(userId string) ([]*StoredMap, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*StoredMap, 0)
+	for _, entry := range r.maps {
+		if entry.favorites[userId] {
+			result = append(result, entry.snapshot())
+		}
+	}
+
+	sortMaps(result, SortNewest)
+	return result, nil
+}
+
+func (e *storedEntry) addVersion(data gamemap.FileMap, comment string, at time.Time) {
+	e.versions = append(e.versions, MapVersion{
+		Version:   len(e.versions) + 1,
+		Map:       data,
+		Comment:   comment,
+		CreatedAt: at,
+	})
+
+	size := data.Size()
+	e.meta.Name = data.Name
+	e.meta.Desc = data.Desc
+	e.meta.Version = len(e.versions)
+	e.meta.Width = size.Width
+	e.meta.Height = size.Height
+	e.meta.MinPlayers = data.MinPlayers
+	e.meta.MaxPlayers = data.MaxPlayers
+	e.meta.UpdatedAt = at
+}
+
+func (e *storedEntry) snapshot() *StoredMap {
+	meta := e.meta
+	return &meta
+}
+
+func sortMaps(maps []*StoredMap, sortBy string) {
+	slices.SortFunc(maps, func(a, b *StoredMap) int {
+		switch sortBy {
+		case SortRating:
+			if a.Rating != b.Rating {
+				if a.Rating > b.Rating {
+					return -1
+				}
+				return 1
+			}
+		case SortPlays:
+			if a.PlayCount != b.PlayCount {
+				if a.PlayCount > b.PlayCount {
+					return -1
+				}
+				return 1
+			}
+		}
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	})
+}
+
+func generateMapID() string {
+	return fmt.Sprintf("map_%d", time.Now().UnixNano())
+}
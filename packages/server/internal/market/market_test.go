@@ -0,0 +1,186 @@
+package market
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"server/internal/game/block"
+	gamemap "server/internal/game/map"
+	"testing"
+)
+
+const testMapBody = `{
+	"map": {
+		"name": "Duel",
+		"desc": "Two kings",
+		"terrain": [".....", ".#.#.", ".....", ".#.#.", "....."],
+		"castles": [{"x": 3, "y": 3, "num": 20}],
+		"spawns": [{"x": 1, "y": 1}, {"x": 5, "y": 5}]
+	},
+	"comment": "initial"
+}`
+
+func newTestServer() (*MarketService, *http.ServeMux) {
+	service := NewMarketService(NewInMemoryMapRepository())
+
+	withUser := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Set("X-User-ID", r.URL.Query().Get("user"))
+			r.Header.Set("X-Username", r.URL.Query().Get("user"))
+			next(w, r)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /library", service.ListHandler)
+	mux.HandleFunc("POST /library", withUser(service.UploadHandler))
+	mux.HandleFunc("GET /library/{id}", service.DetailHandler)
+	mux.HandleFunc("POST /library/{id}/versions", withUser(service.UpdateHandler))
+	mux.HandleFunc("PUT /library/{id}/rating", withUser(service.RateHandler))
+	mux.HandleFunc("PUT /library/{id}/favorite", withUser(service.FavoriteHandler))
+	mux.HandleFunc("DELETE /library/{id}/favorite", withUser(service.FavoriteHandler))
+	mux.HandleFunc("GET /favorites", withUser(service.FavoritesHandler))
+	return service, mux
+}
+
+func doRequest(mux *http.ServeMux, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func uploadTestMap(t *testing.T, mux *http.ServeMux) *StoredMap {
+	t.Helper()
+	rec := doRequest(mux, http.MethodPost, "/library?user=alice", testMapBody)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 on upload, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var stored StoredMap
+	if err := json.NewDecoder(rec.Body).Decode(&stored); err != nil {
+		t.Fatalf("Failed to decode upload response: %v", err)
+	}
+	return &stored
+}
+
+func TestMarket_Upload(t *testing.T) {
+	_, mux := newTestServer()
+
+	t.Run("valid_map", func(t *testing.T) {
+		stored := uploadTestMap(t, mux)
+		if stored.AuthorId != "alice" || stored.Version != 1 || stored.Name != "Duel" {
+			t.Errorf("Unexpected stored map: %+v", stored)
+		}
+	})
+
+	t.Run("invalid_map", func(t *testing.T) {
+		body := `{"map": {"name": "Broken", "terrain": ["..#..", "..#.."], "spawns": [{"x": 1, "y": 1}, {"x": 5, "y": 1}]}}`
+		rec := doRequest(mux, http.MethodPost, "/library?user=alice", body)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for unreachable spawn, got %d", rec.Code)
+		}
+	})
+
+	t.Run("missing_name", func(t *testing.T) {
+		body := `{"map": {"terrain": ["...."], "spawns": [{"x": 1, "y": 1}, {"x": 4, "y": 1}]}}`
+		rec := doRequest(mux, http.MethodPost, "/library?user=alice", body)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for missing name, got %d", rec.Code)
+		}
+	})
+}
+
+func TestMarket_Versions(t *testing.T) {
+	_, mux := newTestServer()
+	stored := uploadTestMap(t, mux)
+
+	rec := doRequest(mux, http.MethodPost, "/library/"+stored.Id+"/versions?user=bob", testMapBody)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for non-author update, got %d", rec.Code)
+	}
+
+	rec = doRequest(mux, http.MethodPost, "/library/"+stored.Id+"/versions?user=alice", testMapBody)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for author update, got %d", rec.Code)
+	}
+
+	rec = doRequest(mux, http.MethodGet, "/library/"+stored.Id, "")
+	var detail MapDetailResponse
+	if err := json.NewDecoder(rec.Body).Decode(&detail); err != nil {
+		t.Fatalf("Failed to decode detail: %v", err)
+	}
+	if detail.Map.Version != 2 || len(detail.Versions) != 2 {
+		t.Errorf("Expected 2 versions, got version=%d history=%d", detail.Map.Version, len(detail.Versions))
+	}
+}
+
+func TestMarket_RatingAndFavorites(t *testing.T) {
+	_, mux := newTestServer()
+	stored := uploadTestMap(t, mux)
+
+	doRequest(mux, http.MethodPut, "/library/"+stored.Id+"/rating?user=bob", `{"score": 5}`)
+	doRequest(mux, http.MethodPut, "/library/"+stored.Id+"/rating?user=carol", `{"score": 2}`)
+	rec := doRequest(mux, http.MethodPut, "/library/"+stored.Id+"/rating?user=carol", `{"score": 4}`)
+
+	var rated StoredMap
+	json.NewDecoder(rec.Body).Decode(&rated)
+	if rated.RatingCount != 2 || rated.Rating != 4.5 {
+		t.Errorf("Expected rating 4.5 from 2 users, got %.2f from %d", rated.Rating, rated.RatingCount)
+	}
+
+	rec = doRequest(mux, http.MethodPut, "/library/"+stored.Id+"/rating?user=bob", `{"score": 9}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for out of range score, got %d", rec.Code)
+	}
+
+	doRequest(mux, http.MethodPut, "/library/"+stored.Id+"/favorite?user=bob", "")
+	rec = doRequest(mux, http.MethodGet, "/favorites?user=bob", "")
+	var favorites []*StoredMap
+	json.NewDecoder(rec.Body).Decode(&favorites)
+	if len(favorites) != 1 {
+		t.Errorf("Expected 1 favorite, got %d", len(favorites))
+	}
+
+	doRequest(mux, http.MethodDelete, "/library/"+stored.Id+"/favorite?user=bob", "")
+	rec = doRequest(mux, http.MethodGet, "/favorites?user=bob", "")
+	favorites = nil
+	json.NewDecoder(rec.Body).Decode(&favorites)
+	if len(favorites) != 0 {
+		t.Errorf("Expected no favorites after removal, got %d", len(favorites))
+	}
+}
+
+func TestMarket_DatabaseProvider(t *testing.T) {
+	service, mux := newTestServer()
+	stored := uploadTestMap(t, mux)
+
+	manager := gamemap.NewMapManager()
+	manager.RegisterProvider(gamemap.NewDatabaseProvider(service))
+
+	players := []gamemap.Player{
+		{Index: 0, Owner: block.Owner(0), IsActive: true},
+		{Index: 1, Owner: block.Owner(1), IsActive: true},
+	}
+
+	for i := 0; i < 2; i++ {
+		gameMap, err := manager.GetMap("db-"+stored.Id, players)
+		if err != nil {
+			t.Fatalf("Failed to load stored map: %v", err)
+		}
+		king, _ := gameMap.Block(gamemap.Pos{X: 5, Y: 5})
+		if king.Meta().Name != block.KingName {
+			t.Errorf("Expected king at second spawn, got %s", king.Meta().Name)
+		}
+	}
+
+	stored, _ = service.repo.GetMap(stored.Id)
+	if stored.PlayCount != 2 {
+		t.Errorf("Expected play count 2, got %d", stored.PlayCount)
+	}
+
+	if _, err := manager.GetMap("db-missing", players); err == nil {
+		t.Error("Expected error for unknown map")
+	}
+}
@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"net/http"
+
+	"server/internal/cache"
+	"server/internal/queue"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "slareneg"
+
+// 由各业务包直接更新的指标
+var (
+	GameCommands = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "game_commands_total",
+		Help:      "Game commands handled by the event loop, by command type.",
+	}, []string{"type"})
+
+	MovesRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "game_moves_rejected_total",
+		Help:      "Move commands rejected by the game core, by reason.",
+	}, []string{"reason"})
+
+	TurnTimerLag = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "game_turn_timer_lag_seconds",
+		Help:      "Delay between the scheduled and actual firing of the turn timer.",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1},
+	})
+
+	WebSocketConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "websocket_connections",
+		Help:      "Open WebSocket connections.",
+	})
+
+	AuthFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_failures_total",
+		Help:      "Rejected authentication attempts, by reason.",
+	}, []string{"reason"})
+)
+
+// GameInfo 采集时的对局快照
+type GameInfo struct {
+	Status  string
+	Mode    string
+	Players int
+}
+
+// Sources 采集时读取的状态，为 nil 的来源不输出对应指标
+type Sources struct {
+	Games func() []GameInfo
+	Queue queue.StatsReporter
+	Cache func() cache.CacheStats
+}
+
+// NewHandler 返回 /metrics 处理器，每次调用使用独立的注册表
+func NewHandler(sources Sources) http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		GameCommands,
+		MovesRejected,
+		TurnTimerLag,
+		WebSocketConnections,
+		AuthFailures,
+		&stateCollector{sources: sources},
+	)
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+var (
+	activeGamesDesc = prometheus.NewDesc(namespace+"_games",
+		"Games hosted by this instance, by status and mode.", []string{"status", "mode"}, nil)
+	gamePlayersDesc = prometheus.NewDesc(namespace+"_game_players",
+		"Players in games hosted by this instance.", nil, nil)
+	queueDeliveredDesc = prometheus.NewDesc(namespace+"_queue_delivered_total",
+		"Messages delivered to subscribers, by topic pattern.", []string{"topic"}, nil)
+	queueDroppedDesc = prometheus.NewDesc(namespace+"_queue_dropped_total",
+		"Messages dropped because a subscriber buffer was full, by topic pattern.", []string{"topic"}, nil)
+	queueDisconnectedDesc = prometheus.NewDesc(namespace+"_queue_disconnected_total",
+		"Slow subscribers disconnected, by topic pattern.", []string{"topic"}, nil)
+	cacheHitsDesc = prometheus.NewDesc(namespace+"_cache_hits_total",
+		"Cache hits.", nil, nil)
+	cacheMissesDesc = prometheus.NewDesc(namespace+"_cache_misses_total",
+		"Cache misses.", nil, nil)
+	cacheHitRateDesc = prometheus.NewDesc(namespace+"_cache_hit_rate",
+		"Cache hit rate since start.", nil, nil)
+	cacheEvictionsDesc = prometheus.NewDesc(namespace+"_cache_evictions_total",
+		"Cache items evicted to stay within the memory budget.", nil, nil)
+	cacheMemoryDesc = prometheus.NewDesc(namespace+"_cache_memory_bytes",
+		"Estimated cache memory usage.", nil, nil)
+)
+
+// stateCollector 采集时从大厅、队列与缓存读取状态
+type stateCollector struct {
+	sources Sources
+}
+
+func (c *stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeGamesDesc
+	ch <- gamePlayersDesc
+	ch <- queueDeliveredDesc
+	ch <- queueDroppedDesc
+	ch <- queueDisconnectedDesc
+	ch <- cacheHitsDesc
+	ch <- cacheMissesDesc
+	ch <- cacheHitRateDesc
+	ch <- cacheEvictionsDesc
+	ch <- cacheMemoryDesc
+}
+
+func (c *stateCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.sources.Games != nil {
+		c.collectGames(ch)
+	}
+	if c.sources.Queue != nil {
+		c.collectQueue(ch)
+	}
+	if c.sources.Cache != nil {
+		c.collectCache(ch)
+	}
+}
+
+func (c *stateCollector) collectGames(ch chan<- prometheus.Metric) {
+	type key struct{ status, mode string }
+	counts := make(map[key]int)
+	players := 0
+	for _, game := range c.sources.Games() {
+		counts[key{game.Status, game.Mode}]++
+		players += game.Players
+	}
+
+	for k, count := range counts {
+		ch <- prometheus.MustNewConstMetric(activeGamesDesc, prometheus.GaugeValue, float64(count), k.status, k.mode)
+	}
+	ch <- prometheus.MustNewConstMetric(gamePlayersDesc, prometheus.GaugeValue, float64(players))
+}
+
+func (c *stateCollector) collectQueue(ch chan<- prometheus.Metric) {
+	totals := make(map[string]queue.TopicStats)
+	for topic, stats := range c.sources.Queue.Stats() {
+		pattern := queue.TopicPattern(topic)
+		total := totals[pattern]
+		total.Delivered += stats.Delivered
+		total.Dropped += stats.Dropped
+		total.Disconnected += stats.Disconnected
+		totals[pattern] = total
+	}
+
+	for pattern, stats := range totals {
+		ch <- prometheus.MustNewConstMetric(queueDeliveredDesc, prometheus.CounterValue, float64(stats.Delivered), pattern)
+		ch <- prometheus.MustNewConstMetric(queueDroppedDesc, prometheus.CounterValue, float64(stats.Dropped), pattern)
+		ch <- prometheus.MustNewConstMetric(queueDisconnectedDesc, prometheus.CounterValue, float64(stats.Disconnected), pattern)
+	}
+}
+
+func (c *stateCollector) collectCache(ch chan<- prometheus.Metric) {
+	stats := c.sources.Cache()
+	ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(cacheHitRateDesc, prometheus.GaugeValue, stats.HitRate)
+	ch <- prometheus.MustNewConstMetric(cacheEvictionsDesc, prometheus.CounterValue, float64(stats.Evictions))
+	ch <- prometheus.MustNewConstMetric(cacheMemoryDesc, prometheus.GaugeValue, float64(stats.MemoryBytes))
+}
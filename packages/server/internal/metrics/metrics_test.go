@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"server/internal/cache"
+	"server/internal/queue"
+)
+
+type fakeQueueStats map[string]queue.TopicStats
+
+func (f fakeQueueStats) Stats() map[string]queue.TopicStats { return f }
+
+func scrape(t *testing.T, sources Sources) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	NewHandler(sources).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != 200 {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	body, _ := io.ReadAll(rec.Body)
+	return string(body)
+}
+
+func TestHandler_ExposesState(t *testing.T) {
+	body := scrape(t, Sources{
+		Games: func() []GameInfo {
+			return []GameInfo{
+				{Status: "waiting", Mode: "classic_1v1", Players: 1},
+				{Status: "in_progress", Mode: "classic_1v1", Players: 2},
+				{Status: "in_progress", Mode: "classic_1v1", Players: 2},
+			}
+		},
+		Queue: fakeQueueStats{
+			"g1/commands":    {Delivered: 10},
+			"g2/commands":    {Delivered: 5},
+			"g1/player/p1":   {Delivered: 3, Dropped: 2},
+			"g2/player/p2":   {Dropped: 1, Disconnected: 1},
+			"lobby/commands": {Delivered: 4},
+		},
+		Cache: func() cache.CacheStats {
+			return cache.CacheStats{Hits: 3, Misses: 1, HitRate: 0.75, Evictions: 2}
+		},
+	})
+
+	for _, want := range []string{
+		`slareneg_games{mode="classic_1v1",status="in_progress"} 2`,
+		`slareneg_games{mode="classic_1v1",status="waiting"} 1`,
+		`slareneg_game_players 5`,
+		`slareneg_queue_delivered_total{topic="{game}/commands"} 15`,
+		`slareneg_queue_dropped_total{topic="{game}/player/{player}"} 3`,
+		`slareneg_queue_disconnected_total{topic="{game}/player/{player}"} 1`,
+		`slareneg_queue_delivered_total{topic="lobby/commands"} 4`,
+		`slareneg_cache_hit_rate 0.75`,
+		`slareneg_cache_evictions_total 2`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %q", want)
+		}
+	}
+}
+
+func TestHandler_ExposesCounters(t *testing.T) {
+	AuthFailures.WithLabelValues("test_reason").Inc()
+	MovesRejected.WithLabelValues("test_reason").Inc()
+	GameCommands.WithLabelValues("test_type").Inc()
+
+	body := scrape(t, Sources{})
+
+	for _, want := range []string{
+		`slareneg_auth_failures_total{reason="test_reason"}`,
+		`slareneg_game_moves_rejected_total{reason="test_reason"}`,
+		`slareneg_game_commands_total{type="test_type"}`,
+		`slareneg_game_turn_timer_lag_seconds_bucket`,
+		`slareneg_websocket_connections`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %q", want)
+		}
+	}
+	if strings.Contains(body, "slareneg_games{") {
+		t.Error("Expected no game metrics without a game source")
+	}
+}
@@ -0,0 +1,168 @@
+package queue
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Policy 订阅缓冲区已满时的处理策略
+type Policy string
+
+const (
+	// PolicyDropNewest 丢弃新消息
+	PolicyDropNewest Policy = "dropNewest"
+	// PolicyDropOldest 丢弃缓冲区中最旧的消息，为新消息腾出空间
+	PolicyDropOldest Policy = "dropOldest"
+	// PolicyBlock 阻塞发布方直到送达、超时或订阅取消，BlockTimeout 为 0 时不超时（无损）
+	PolicyBlock Policy = "block"
+	// PolicyDisconnect 取消慢消费者的订阅并关闭其通道
+	PolicyDisconnect Policy = "disconnect"
+)
+
+type SubscribeOptions struct {
+	BufferSize   int
+	Policy       Policy
+	BlockTimeout time.Duration
+}
+
+// Options 内存队列的默认订阅选项，指令主题（见 IsCommandTopic）单独配置
+type Options struct {
+	Default SubscribeOptions
+	Command SubscribeOptions
+}
+
+// DefaultOptions 普通主题缓冲区满时丢弃新消息，指令主题无损投递
+func DefaultOptions() Options {
+	return Options{
+		Default: SubscribeOptions{BufferSize: 50, Policy: PolicyDropNewest},
+		Command: SubscribeOptions{BufferSize: 50, Policy: PolicyBlock},
+	}
+}
+
+// IsCommandTopic 判断是否为指令主题：lobby/commands 与 <gameId>/commands
+func IsCommandTopic(topic string) bool {
+	return strings.HasSuffix(topic, "/commands")
+}
+
+// TopicStats 主题的投递统计
+type TopicStats struct {
+	Delivered    uint64 `json:"delivered"`
+	Dropped      uint64 `json:"dropped"`
+	Disconnected uint64 `json:"disconnected"`
+}
+
+// StatsReporter 由提供投递统计的队列实现。
+// 已没有订阅者的对局与玩家主题的统计并入 TopicPattern 返回的模式，统计项数不随对局数增长
+type StatsReporter interface {
+	Stats() map[string]TopicStats
+}
+
+// TopicPattern 将主题中的对局与玩家 ID 替换为占位符。
+// 例如 g1/player/p1 归为 {game}/player/{player}，lobby/player/u1 归为 lobby/player/{player}，其他 lobby/* 保持不变。
+func TopicPattern(topic string) string {
+	parts := strings.Split(topic, "/")
+	if len(parts) < 2 {
+		return topic
+	}
+
+	if parts[0] != "lobby" {
+		parts[0] = "{game}"
+	}
+	if len(parts) >= 3 && parts[1] == "player" {
+		parts[2] = "{player}"
+	}
+	return strings.Join(parts, "/")
+}
+
+type deliveryResult int
+
+const (
+	resultDelivered deliveryResult = iota
+	resultDropped
+	resultDisconnect
+	resultClosed
+)
+
+type subscription struct {
+	ch   chan Event
+	opts SubscribeOptions
+	// done 在取消订阅时关闭，唤醒阻塞中的发布方
+	done chan struct{}
+
+	// 发送方持有读锁，关闭通道前取写锁，避免向已关闭的通道发送
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newSubscription(opts SubscribeOptions) *subscription {
+	return &subscription{
+		ch:   make(chan Event, opts.BufferSize),
+		opts: opts,
+		done: make(chan struct{}),
+	}
+}
+
+func (s *subscription) close() {
+	close(s.done)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	close(s.ch)
+}
+
+// send 按策略投递，evicted 为 dropOldest 挤出的消息数
+func (s *subscription) send(message Event) (result deliveryResult, evicted uint64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return resultClosed, 0
+	}
+
+	select {
+	case s.ch <- message:
+		return resultDelivered, 0
+	default:
+	}
+
+	switch s.opts.Policy {
+	case PolicyBlock:
+		var timeout <-chan time.Time
+		if s.opts.BlockTimeout > 0 {
+			timer := time.NewTimer(s.opts.BlockTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+
+		select {
+		case s.ch <- message:
+			return resultDelivered, 0
+		case <-s.done:
+			return resultClosed, 0
+		case <-timeout:
+			return resultDropped, 0
+		}
+
+	case PolicyDropOldest:
+		for {
+			select {
+			case s.ch <- message:
+				return resultDelivered, evicted
+			default:
+			}
+			select {
+			case <-s.ch:
+				evicted++
+			default:
+			}
+		}
+
+	case PolicyDisconnect:
+		return resultDisconnect, 0
+
+	default:
+		return resultDropped, 0
+	}
+}
@@ -0,0 +1,198 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInMemoryQueue_DropNewest(t *testing.T) {
+	q := NewInMemoryQueue()
+	ch := q.SubscribeWithOptions("events", SubscribeOptions{BufferSize: 2, Policy: PolicyDropNewest})
+
+	for i := 0; i < 3; i++ {
+		q.Publish("events", i)
+	}
+
+	if first, second := <-ch, <-ch; first != 0 || second != 1 {
+		t.Errorf("Expected buffered messages 0 and 1, got %v and %v", first, second)
+	}
+
+	stats := q.Stats()["events"]
+	if stats.Delivered != 2 || stats.Dropped != 1 {
+		t.Errorf("Expected 2 delivered and 1 dropped, got %+v", stats)
+	}
+}
+
+func TestInMemoryQueue_DropOldest(t *testing.T) {
+	q := NewInMemoryQueue()
+	ch := q.SubscribeWithOptions("events", SubscribeOptions{BufferSize: 2, Policy: PolicyDropOldest})
+
+	for i := 0; i < 4; i++ {
+		q.Publish("events", i)
+	}
+
+	if first, second := <-ch, <-ch; first != 2 || second != 3 {
+		t.Errorf("Expected newest messages 2 and 3, got %v and %v", first, second)
+	}
+
+	stats := q.Stats()["events"]
+	if stats.Delivered != 4 || stats.Dropped != 2 {
+		t.Errorf("Expected 4 delivered and 2 dropped, got %+v", stats)
+	}
+}
+
+func TestInMemoryQueue_BlockWithTimeout(t *testing.T) {
+	q := NewInMemoryQueue()
+	ch := q.SubscribeWithOptions("events", SubscribeOptions{
+		BufferSize:   1,
+		Policy:       PolicyBlock,
+		BlockTimeout: 20 * time.Millisecond,
+	})
+
+	q.Publish("events", 1)
+
+	start := time.Now()
+	q.Publish("events", 2)
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Expected publish to block for the timeout, returned after %v", elapsed)
+	}
+
+	if stats := q.Stats()["events"]; stats.Dropped != 1 {
+		t.Errorf("Expected message to be dropped after timeout, got %+v", stats)
+	}
+
+	// 消费者腾出空间后阻塞的发布方应继续投递
+	done := make(chan struct{})
+	go func() {
+		q.Publish("events", 3)
+		close(done)
+	}()
+	<-ch
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected blocked publish to complete once buffer drained")
+	}
+	if msg := <-ch; msg != 3 {
+		t.Errorf("Expected 3, got %v", msg)
+	}
+}
+
+func TestInMemoryQueue_CommandTopicsAreLossless(t *testing.T) {
+	q := NewInMemoryQueue()
+	topic := "game-1/commands"
+	ch := q.Subscribe(topic)
+
+	const total = 200
+	go func() {
+		for i := 0; i < total; i++ {
+			q.Publish(topic, i)
+		}
+	}()
+
+	for i := 0; i < total; i++ {
+		select {
+		case msg := <-ch:
+			if msg != i {
+				t.Fatalf("Expected message %d, got %v", i, msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected message %d, timed out", i)
+		}
+	}
+
+	if stats := q.Stats()[topic]; stats.Delivered != total || stats.Dropped != 0 {
+		t.Errorf("Expected lossless delivery, got %+v", stats)
+	}
+}
+
+func TestInMemoryQueue_UnsubscribeReleasesBlockedPublisher(t *testing.T) {
+	q := NewInMemoryQueue()
+	topic := "game-1/commands"
+	ch := q.SubscribeWithOptions(topic, SubscribeOptions{BufferSize: 1, Policy: PolicyBlock})
+
+	q.Publish(topic, 1)
+
+	done := make(chan struct{})
+	go func() {
+		q.Publish(topic, 2)
+		close(done)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	q.Unsubscribe(topic, ch)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected blocked publish to return after unsubscribe")
+	}
+}
+
+func TestInMemoryQueue_DisconnectSlowConsumer(t *testing.T) {
+	q := NewInMemoryQueue()
+	slow := q.SubscribeWithOptions("events", SubscribeOptions{BufferSize: 1, Policy: PolicyDisconnect})
+	fast := q.Subscribe("events")
+
+	q.Publish("events", 1)
+	q.Publish("events", 2)
+
+	if msg, ok := <-slow; !ok || msg != 1 {
+		t.Errorf("Expected buffered message before close, got %v (%v)", msg, ok)
+	}
+	if _, ok := <-slow; ok {
+		t.Error("Expected slow consumer channel to be closed")
+	}
+
+	if first, second := <-fast, <-fast; first != 1 || second != 2 {
+		t.Errorf("Expected other subscriber to be unaffected, got %v and %v", first, second)
+	}
+
+	stats := q.Stats()["events"]
+	if stats.Disconnected != 1 || stats.Dropped != 1 {
+		t.Errorf("Expected 1 disconnect, got %+v", stats)
+	}
+}
+
+func TestTopicPattern(t *testing.T) {
+	cases := map[string]string{
+		"lobby/commands":         "lobby/commands",
+		"lobby/events":           "lobby/events",
+		"lobby/player/u1":        "lobby/player/{player}",
+		"g1/commands":            "{game}/commands",
+		"g1/broadcast":           "{game}/broadcast",
+		"g1/player/p1":           "{game}/player/{player}",
+		"{game}/player/{player}": "{game}/player/{player}",
+		"standalone":             "standalone",
+	}
+	for topic, want := range cases {
+		if got := TopicPattern(topic); got != want {
+			t.Errorf("TopicPattern(%q) = %q, want %q", topic, got, want)
+		}
+	}
+}
+
+func TestInMemoryQueue_StatsPruneTopicsWithoutSubscribers(t *testing.T) {
+	q := NewInMemoryQueue()
+	for _, topic := range []string{"g1/player/p1", "g2/player/p2"} {
+		ch := q.Subscribe(topic)
+		q.Publish(topic, 1)
+		<-ch
+		q.Unsubscribe(topic, ch)
+	}
+
+	active := q.Subscribe("g3/player/p3")
+	q.Publish("g3/player/p3", 1)
+	<-active
+
+	stats := q.Stats()
+	if _, exists := stats["g1/player/p1"]; exists {
+		t.Error("Expected stats for unsubscribed topic to be pruned")
+	}
+	if got := stats["{game}/player/{player}"].Delivered; got != 2 {
+		t.Errorf("Expected pruned topics to be merged into their pattern, got %d", got)
+	}
+	if got := stats["g3/player/p3"].Delivered; got != 1 {
+		t.Errorf("Expected stats for active topic to be kept, got %d", got)
+	}
+}
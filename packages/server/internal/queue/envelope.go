@@ -0,0 +1,137 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Envelope 可序列化的事件信封，用于跨进程传输、日志与回放
+type Envelope struct {
+	// Type 为 RegisterEvent 注册的类型名
+	Type string `json:"type"`
+	// Version 为载荷结构的版本，结构不兼容变更时递增
+	Version   int       `json:"version"`
+	Timestamp time.Time `json:"timestamp"`
+	// Source 发布方标识，如实例 ID
+	Source  string          `json:"source,omitempty"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type registeredEvent struct {
+	name    string
+	version int
+	typ     reflect.Type
+}
+
+var registry = struct {
+	sync.RWMutex
+	byName map[string]registeredEvent
+	byType map[reflect.Type]registeredEvent
+}{
+	byName: make(map[string]registeredEvent),
+	byType: make(map[reflect.Type]registeredEvent),
+}
+
+// RegisterEvent 注册可序列化的事件类型，sample 为该类型的零值（非指针）。
+// 解码时还原为同一个 Go 类型，各包在 init 中注册自己的事件。
+func RegisterEvent(name string, version int, sample Event) {
+	t := reflect.TypeOf(sample)
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if existing, exists := registry.byName[name]; exists && existing.typ != t {
+		panic(fmt.Sprintf("queue: event name %q already registered for %s", name, existing.typ))
+	}
+	if existing, exists := registry.byType[t]; exists && existing.name != name {
+		panic(fmt.Sprintf("queue: %s already registered as %q", t, existing.name))
+	}
+
+	event := registeredEvent{name: name, version: version, typ: t}
+	registry.byName[name] = event
+	registry.byType[t] = event
+}
+
+// RegisteredEvents 返回所有已注册的类型名，按字典序排列
+func RegisteredEvents() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	names := make([]string, 0, len(registry.byName))
+	for name := range registry.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewEnvelope 将已注册的事件装入信封
+func NewEnvelope(event Event, source string) (*Envelope, error) {
+	registry.RLock()
+	registered, exists := registry.byType[reflect.TypeOf(event)]
+	registry.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("unregistered event type: %T", event)
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", registered.name, err)
+	}
+
+	return &Envelope{
+		Type:      registered.name,
+		Version:   registered.version,
+		Timestamp: time.Now().UTC(),
+		Source:    source,
+		Payload:   payload,
+	}, nil
+}
+
+// Event 按注册的类型解出载荷。高于本地注册版本的载荷视为无法识别。
+func (e *Envelope) Event() (Event, error) {
+	registry.RLock()
+	registered, exists := registry.byName[e.Type]
+	registry.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("unknown event type: %s", e.Type)
+	}
+	if e.Version > registered.version {
+		return nil, fmt.Errorf("unsupported %s version %d (max %d)", e.Type, e.Version, registered.version)
+	}
+
+	value := reflect.New(registered.typ)
+	if err := json.Unmarshal(e.Payload, value.Interface()); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", e.Type, err)
+	}
+	return value.Elem().Interface(), nil
+}
+
+// Encode 将事件编码为 JSON 信封
+func Encode(event Event, source string) ([]byte, error) {
+	envelope, err := NewEnvelope(event, source)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(envelope)
+}
+
+// Decode 解析 Encode 的输出，同时返回信封以便记录来源与时间
+func Decode(data []byte) (Event, *Envelope, error) {
+	var envelope Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, nil, fmt.Errorf("invalid event envelope: %w", err)
+	}
+
+	event, err := envelope.Event()
+	if err != nil {
+		return nil, &envelope, err
+	}
+	return event, &envelope, nil
+}
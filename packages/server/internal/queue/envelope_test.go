@@ -0,0 +1,81 @@
+package queue
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvelope_RoundTrip(t *testing.T) {
+	event := testCommand{PlayerId: "p1", Troops: 7}
+
+	data, err := Encode(event, "game-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	decoded, envelope, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !reflect.DeepEqual(decoded, event) {
+		t.Errorf("Expected %#v, got %#v", event, decoded)
+	}
+	if envelope.Type != "test.command" || envelope.Version != 1 {
+		t.Errorf("Expected test.command v1, got %s v%d", envelope.Type, envelope.Version)
+	}
+	if envelope.Source != "game-1" {
+		t.Errorf("Expected source game-1, got %q", envelope.Source)
+	}
+	if envelope.Timestamp.IsZero() {
+		t.Error("Expected timestamp to be set")
+	}
+}
+
+func TestEnvelope_RejectsUnknownTypeAndNewerVersion(t *testing.T) {
+	envelope, err := NewEnvelope(testCommand{PlayerId: "p1"}, "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	envelope.Version = 2
+	if _, err := envelope.Event(); err == nil || !strings.Contains(err.Error(), "version") {
+		t.Errorf("Expected version error, got %v", err)
+	}
+
+	envelope.Type = "test.unknown"
+	data, _ := json.Marshal(envelope)
+	if _, _, err := Decode(data); err == nil {
+		t.Error("Expected error for unknown event type")
+	}
+
+	if _, _, err := Decode([]byte("not json")); err == nil {
+		t.Error("Expected error for malformed envelope")
+	}
+}
+
+func TestRegisterEvent_ConflictingNamePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic when registering a name twice with different types")
+		}
+	}()
+
+	type otherCommand struct{ X int }
+	RegisterEvent("test.command", 1, otherCommand{})
+}
+
+func TestRegisteredEvents(t *testing.T) {
+	names := RegisteredEvents()
+	found := false
+	for _, name := range names {
+		if name == "test.command" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected test.command in %v", names)
+	}
+}
@@ -5,45 +5,67 @@ import (
 	"sync"
 )
 
+// Event 队列中的事件。需要跨进程传输的事件类型须通过 RegisterEvent 注册，见 Envelope。
 type Event interface{}
 
-type BaseEvent struct {
-	EventData interface{} // The actual event data
-}
-
-func (e BaseEvent) Event() interface{} {
-	return e.EventData
-}
-
-func NewEvent(eventData interface{}) Event {
-	return BaseEvent{EventData: eventData}
-}
-
 type Queue interface {
 	Subscribe(topic string) <-chan Event
 	Unsubscribe(topic string, ch <-chan Event)
 	Publish(topic string, message Event)
 }
 
+// Router 多实例部署时由队列实现，决定对局由哪个实例承载。
+// 只有持有归属的实例才订阅该对局的主题，因此发往对局的消息只会被承载实例处理。
+type Router interface {
+	InstanceID() string
+	// Claim 尝试取得归属，已由当前实例持有时同样返回 true
+	Claim(name string) (bool, error)
+	// Owner 返回当前持有者，无人持有时返回空字符串
+	Owner(name string) (string, error)
+	Release(name string)
+}
+
+// InMemoryQueue 进程内队列，订阅缓冲区满时按订阅的 Policy 处理
 type InMemoryQueue struct {
-	subscribers map[string][]chan Event
+	subscribers map[string][]*subscription
 	mu          sync.RWMutex
+	options     Options
+
+	// stats 各主题的投递统计，主题没有订阅者后并入其模式，见 pruneStats
+	stats   map[string]TopicStats
+	statsMu sync.Mutex
 }
 
+var _ StatsReporter = (*InMemoryQueue)(nil)
+
 func NewInMemoryQueue() *InMemoryQueue {
+	return NewInMemoryQueueWithOptions(DefaultOptions())
+}
+
+func NewInMemoryQueueWithOptions(options Options) *InMemoryQueue {
 	return &InMemoryQueue{
-		subscribers: make(map[string][]chan Event),
+		subscribers: make(map[string][]*subscription),
+		options:     options,
+		stats:       make(map[string]TopicStats),
 	}
 }
 
+// Subscribe 使用队列的默认选项订阅，指令主题默认无损
 func (q *InMemoryQueue) Subscribe(topic string) <-chan Event {
-	ch := make(chan Event, 50) // Buffered channel to avoid blocking
+	if IsCommandTopic(topic) {
+		return q.SubscribeWithOptions(topic, q.options.Command)
+	}
+	return q.SubscribeWithOptions(topic, q.options.Default)
+}
+
+func (q *InMemoryQueue) SubscribeWithOptions(topic string, opts SubscribeOptions) <-chan Event {
+	sub := newSubscription(opts)
 
 	q.mu.Lock()
-	q.subscribers[topic] = append(q.subscribers[topic], ch)
+	q.subscribers[topic] = append(q.subscribers[topic], sub)
 	q.mu.Unlock()
 
-	return ch
+	return sub.ch
 }
 
 func (q *InMemoryQueue) Publish(topic string, message Event) {
@@ -54,30 +76,43 @@ func (q *InMemoryQueue) Publish(topic string, message Event) {
 		return
 	}
 
-	// 创建 subs 的副本，避免在读锁期间长时间持有
-	subsCopy := make([]chan Event, len(subs))
+	// 创建 subs 的副本，阻塞投递时不持有队列锁
+	subsCopy := make([]*subscription, len(subs))
 	copy(subsCopy, subs)
 	q.mu.RUnlock()
 
+	var delta TopicStats
 	for _, sub := range subsCopy {
-		select {
-		case sub <- message:
-		default:
-			// If the channel is full, we skip sending to avoid blocking
+		result, evicted := sub.send(message)
+		if evicted > 0 {
+			delta.Dropped += evicted
+			slog.Warn("Queue dropped oldest messages", "topic", topic, "count", evicted)
+		}
+
+		switch result {
+		case resultDelivered:
+			delta.Delivered++
+		case resultDropped:
+			delta.Dropped++
 			slog.Warn("Queue publish skipped", "topic", topic, "message", message)
+		case resultDisconnect:
+			delta.Dropped++
+			delta.Disconnected++
+			slog.Warn("Queue disconnected slow subscriber", "topic", topic)
+			q.Unsubscribe(topic, sub.ch)
 		}
 	}
+	q.record(topic, delta)
 }
 
 func (q *InMemoryQueue) Unsubscribe(topic string, ch <-chan Event) {
 	q.mu.Lock()
-	defer q.mu.Unlock()
-
+	var removed *subscription
 	if subs, ok := q.subscribers[topic]; ok {
 		for i, sub := range subs {
-			if sub == ch {
+			if sub.ch == ch {
 				q.subscribers[topic] = append(subs[:i], subs[i+1:]...)
-				close(sub) // Close the channel to avoid memory leaks
+				removed = sub
 				break
 			}
 		}
@@ -87,4 +122,66 @@ func (q *InMemoryQueue) Unsubscribe(topic string, ch <-chan Event) {
 			delete(q.subscribers, topic)
 		}
 	}
+	q.mu.Unlock()
+
+	if removed != nil {
+		removed.close() // Close the channel to avoid memory leaks
+	}
+
+	q.statsMu.Lock()
+	q.pruneStats(topic)
+	q.statsMu.Unlock()
+}
+
+// Stats 返回各主题的投递统计快照，已没有订阅者的主题按模式合并
+func (q *InMemoryQueue) Stats() map[string]TopicStats {
+	q.statsMu.Lock()
+	defer q.statsMu.Unlock()
+
+	for topic := range q.stats {
+		q.pruneStats(topic)
+	}
+	stats := make(map[string]TopicStats, len(q.stats))
+	for topic, topicStats := range q.stats {
+		stats[topic] = topicStats
+	}
+	return stats
+}
+
+// record 在一次发布结束后累加统计，与 pruneStats 共用 statsMu，合并时不会丢失计数
+func (q *InMemoryQueue) record(topic string, delta TopicStats) {
+	q.statsMu.Lock()
+	defer q.statsMu.Unlock()
+
+	q.stats[topic] = addStats(q.stats[topic], delta)
+}
+
+// pruneStats 主题已没有订阅者时将其统计并入模式，计数保持单调递增。调用方需持有 statsMu
+func (q *InMemoryQueue) pruneStats(topic string) {
+	pattern := TopicPattern(topic)
+	if pattern == topic {
+		return
+	}
+	stats, exists := q.stats[topic]
+	if !exists {
+		return
+	}
+
+	q.mu.RLock()
+	_, active := q.subscribers[topic]
+	q.mu.RUnlock()
+	if active {
+		return
+	}
+
+	q.stats[pattern] = addStats(q.stats[pattern], stats)
+	delete(q.stats, topic)
+}
+
+func addStats(a, b TopicStats) TopicStats {
+	return TopicStats{
+		Delivered:    a.Delivered + b.Delivered,
+		Dropped:      a.Dropped + b.Dropped,
+		Disconnected: a.Disconnected + b.Disconnected,
+	}
 }
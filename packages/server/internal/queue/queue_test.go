@@ -194,20 +194,6 @@ receiveLoop:
 	q.Unsubscribe(topic, ch)
 }
 
-func TestInMemoryQueue_BaseEvent(t *testing.T) {
-	testData := "test-string-data"
-
-	event := NewEvent(testData)
-	baseEvent, ok := event.(BaseEvent)
-	if !ok {
-		t.Fatal("Expected BaseEvent type")
-	}
-
-	if baseEvent.Event() != testData {
-		t.Errorf("Expected %v, got %v", testData, baseEvent.Event())
-	}
-}
-
 func BenchmarkInMemoryQueue_Subscribe(b *testing.B) {
 	q := NewInMemoryQueue()
 	topic := "bench-subscribe"
@@ -0,0 +1,273 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ Queue = (*RedisQueue)(nil)
+var _ Router = (*RedisQueue)(nil)
+
+// 仅当归属仍属于当前实例时续期/删除，避免误操作其他实例接管后的归属
+var (
+	renewClaimScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	releaseClaimScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// RedisQueue 基于 Redis Pub/Sub 的队列，允许 WebSocket 层与对局层运行在不同进程。
+//
+// 所有本地订阅共用一条 Pub/Sub 连接，事件以 Envelope 传输（Source 为实例 ID），
+// 因此发布的事件类型必须先通过 RegisterEvent 注册。
+// 对局归属通过 Redis 键 <prefix>owner:<name> 记录，持有期间按 claimTTL/3 续期。
+type RedisQueue struct {
+	client     *redis.Client
+	pubsub     *redis.PubSub
+	prefix     string
+	instanceID string
+	claimTTL   time.Duration
+
+	mu          sync.RWMutex
+	subscribers map[string][]chan Event
+	claims      map[string]struct{}
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func NewRedisQueue(client *redis.Client, prefix, instanceID string, claimTTL time.Duration) *RedisQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &RedisQueue{
+		client:      client,
+		pubsub:      client.Subscribe(ctx),
+		prefix:      prefix,
+		instanceID:  instanceID,
+		claimTTL:    claimTTL,
+		subscribers: make(map[string][]chan Event),
+		claims:      make(map[string]struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+
+	q.wg.Add(2)
+	go q.receiveLoop()
+	go q.renewLoop()
+	return q
+}
+
+func (q *RedisQueue) Subscribe(topic string) <-chan Event {
+	ch := make(chan Event, 50)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.subscribers[topic] = append(q.subscribers[topic], ch)
+	if len(q.subscribers[topic]) == 1 {
+		if err := q.pubsub.Subscribe(q.ctx, q.prefix+topic); err != nil {
+			slog.Error("redis subscribe failed", "error", err, "topic", topic)
+		}
+	}
+	return ch
+}
+
+func (q *RedisQueue) Unsubscribe(topic string, ch <-chan Event) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	subs := q.subscribers[topic]
+	for i, sub := range subs {
+		if sub == ch {
+			q.subscribers[topic] = append(subs[:i], subs[i+1:]...)
+			close(sub)
+			break
+		}
+	}
+
+	if len(q.subscribers[topic]) == 0 {
+		delete(q.subscribers, topic)
+		if err := q.pubsub.Unsubscribe(q.ctx, q.prefix+topic); err != nil {
+			slog.Error("redis unsubscribe failed", "error", err, "topic", topic)
+		}
+	}
+}
+
+func (q *RedisQueue) Publish(topic string, message Event) {
+	payload, err := Encode(message, q.instanceID)
+	if err != nil {
+		slog.Error("failed to encode queue event", "error", err, "topic", topic)
+		return
+	}
+
+	if err := q.client.Publish(q.ctx, q.prefix+topic, payload).Err(); err != nil {
+		slog.Error("redis publish failed", "error", err, "topic", topic)
+	}
+}
+
+func (q *RedisQueue) InstanceID() string {
+	return q.instanceID
+}
+
+func (q *RedisQueue) Claim(name string) (bool, error) {
+	key := q.ownerKey(name)
+	claimed, err := q.client.SetNX(q.ctx, key, q.instanceID, q.claimTTL).Result()
+	if err != nil {
+		return false, err
+	}
+
+	if !claimed {
+		owner, err := q.client.Get(q.ctx, key).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return false, err
+		}
+		claimed = owner == q.instanceID
+	}
+
+	if claimed {
+		q.mu.Lock()
+		q.claims[name] = struct{}{}
+		q.mu.Unlock()
+	}
+	return claimed, nil
+}
+
+func (q *RedisQueue) Owner(name string) (string, error) {
+	owner, err := q.client.Get(q.ctx, q.ownerKey(name)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return owner, err
+}
+
+func (q *RedisQueue) Release(name string) {
+	q.mu.Lock()
+	delete(q.claims, name)
+	q.mu.Unlock()
+
+	if err := releaseClaimScript.Run(q.ctx, q.client, []string{q.ownerKey(name)}, q.instanceID).Err(); err != nil {
+		slog.Error("failed to release claim", "error", err, "name", name)
+	}
+}
+
+// Close 释放当前实例持有的归属并关闭连接，可重复调用
+func (q *RedisQueue) Close() error {
+	q.closeOnce.Do(q.close)
+	return q.closeErr
+}
+
+func (q *RedisQueue) close() {
+	q.mu.RLock()
+	names := make([]string, 0, len(q.claims))
+	for name := range q.claims {
+		names = append(names, name)
+	}
+	q.mu.RUnlock()
+
+	for _, name := range names {
+		q.Release(name)
+	}
+
+	q.cancel()
+	q.closeErr = q.pubsub.Close()
+	q.wg.Wait()
+
+	q.mu.Lock()
+	for topic, subs := range q.subscribers {
+		for _, sub := range subs {
+			close(sub)
+		}
+		delete(q.subscribers, topic)
+	}
+	q.mu.Unlock()
+}
+
+func (q *RedisQueue) receiveLoop() {
+	defer q.wg.Done()
+
+	for msg := range q.pubsub.Channel() {
+		event, envelope, err := Decode([]byte(msg.Payload))
+		if err != nil {
+			source := ""
+			if envelope != nil {
+				source = envelope.Source
+			}
+			slog.Error("failed to decode queue event", "error", err, "channel", msg.Channel, "source", source)
+			continue
+		}
+		q.dispatch(strings.TrimPrefix(msg.Channel, q.prefix), event)
+	}
+}
+
+func (q *RedisQueue) dispatch(topic string, event Event) {
+	// 持有读锁发送，避免与 Unsubscribe 关闭通道并发
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	for _, sub := range q.subscribers[topic] {
+		select {
+		case sub <- event:
+		default:
+			slog.Warn("Queue publish skipped", "topic", topic, "message", event)
+		}
+	}
+}
+
+func (q *RedisQueue) renewLoop() {
+	defer q.wg.Done()
+
+	interval := max(q.claimTTL/3, 10*time.Millisecond)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-ticker.C:
+			q.renewClaims()
+		}
+	}
+}
+
+func (q *RedisQueue) renewClaims() {
+	q.mu.RLock()
+	names := make([]string, 0, len(q.claims))
+	for name := range q.claims {
+		names = append(names, name)
+	}
+	q.mu.RUnlock()
+
+	for _, name := range names {
+		renewed, err := renewClaimScript.Run(q.ctx, q.client, []string{q.ownerKey(name)},
+			q.instanceID, q.claimTTL.Milliseconds()).Int()
+		if err != nil {
+			slog.Error("failed to renew claim", "error", err, "name", name)
+			continue
+		}
+		if renewed == 0 {
+			slog.Warn("lost claim to another instance", "name", name, "instance", q.instanceID)
+			q.mu.Lock()
+			delete(q.claims, name)
+			q.mu.Unlock()
+		}
+	}
+}
+
+func (q *RedisQueue) ownerKey(name string) string {
+	return q.prefix + "owner:" + name
+}
@@ -0,0 +1,133 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type testCommand struct {
+	PlayerId string
+	Troops   int
+}
+
+func init() {
+	RegisterEvent("test.command", 1, testCommand{})
+}
+
+func newTestRedisQueue(t *testing.T, mr *miniredis.Miniredis, instanceID string) *RedisQueue {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	q := NewRedisQueue(client, "test:", instanceID, time.Second)
+	t.Cleanup(func() {
+		q.Close()
+		client.Close()
+	})
+	return q
+}
+
+// publishUntilReceived 订阅在 Redis 端生效前发布的消息会丢失，因此重复发布直到收到
+func publishUntilReceived(t *testing.T, q Queue, topic string, event Event, ch <-chan Event) Event {
+	t.Helper()
+	deadline := time.After(2 * time.Second)
+	for {
+		q.Publish(topic, event)
+		select {
+		case received := <-ch:
+			return received
+		case <-time.After(20 * time.Millisecond):
+		case <-deadline:
+			t.Fatalf("Expected to receive event on %s", topic)
+			return nil
+		}
+	}
+}
+
+func TestRedisQueue_CrossInstanceDelivery(t *testing.T) {
+	mr := miniredis.RunT(t)
+	wsTier := newTestRedisQueue(t, mr, "ws-1")
+	gameTier := newTestRedisQueue(t, mr, "game-1")
+
+	ch := gameTier.Subscribe("g1/commands")
+	received := publishUntilReceived(t, wsTier, "g1/commands", testCommand{PlayerId: "p1", Troops: 3}, ch)
+
+	cmd, ok := received.(testCommand)
+	if !ok || cmd.PlayerId != "p1" || cmd.Troops != 3 {
+		t.Fatalf("Expected typed testCommand, got %#v", received)
+	}
+
+	gameTier.Unsubscribe("g1/commands", ch)
+	if _, ok := <-ch; ok {
+		t.Error("Expected channel to be closed after unsubscribe")
+	}
+}
+
+func TestRedisQueue_UnregisteredEventIsNotPublished(t *testing.T) {
+	mr := miniredis.RunT(t)
+	q := newTestRedisQueue(t, mr, "ws-1")
+
+	type unregistered struct{ X int }
+	if _, err := Encode(unregistered{X: 1}, "ws-1"); err == nil {
+		t.Fatal("Expected encode error for unregistered type")
+	}
+
+	ch := q.Subscribe("topic")
+	publishUntilReceived(t, q, "topic", testCommand{PlayerId: "ready"}, ch)
+
+	q.Publish("topic", unregistered{X: 1})
+	select {
+	case event := <-ch:
+		t.Errorf("Expected unregistered event to be dropped, got %#v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestRedisQueue_Claim(t *testing.T) {
+	mr := miniredis.RunT(t)
+	first := newTestRedisQueue(t, mr, "game-1")
+	second := newTestRedisQueue(t, mr, "game-2")
+
+	if claimed, err := first.Claim("g1"); err != nil || !claimed {
+		t.Fatalf("Expected first instance to claim g1, got %v (%v)", claimed, err)
+	}
+	if claimed, _ := first.Claim("g1"); !claimed {
+		t.Error("Expected claim to be idempotent for the owner")
+	}
+	if claimed, _ := second.Claim("g1"); claimed {
+		t.Error("Expected second instance not to claim g1")
+	}
+	if owner, _ := second.Owner("g1"); owner != "game-1" {
+		t.Errorf("Expected owner game-1, got %q", owner)
+	}
+
+	// 非持有者释放不影响归属
+	second.Release("g1")
+	if owner, _ := first.Owner("g1"); owner != "game-1" {
+		t.Errorf("Expected owner to survive foreign release, got %q", owner)
+	}
+
+	first.Close()
+	if owner, _ := second.Owner("g1"); owner != "" {
+		t.Errorf("Expected claim to be released on close, got %q", owner)
+	}
+	if claimed, _ := second.Claim("g1"); !claimed {
+		t.Error("Expected second instance to take over g1")
+	}
+}
+
+func TestRedisQueue_ClaimExpiresWithoutRenewal(t *testing.T) {
+	mr := miniredis.RunT(t)
+	q := newTestRedisQueue(t, mr, "game-1")
+
+	q.client.Set(q.ctx, q.ownerKey("g1"), "crashed-instance", time.Second)
+	if claimed, _ := q.Claim("g1"); claimed {
+		t.Fatal("Expected claim held by another instance to be refused")
+	}
+
+	mr.FastForward(2 * time.Second)
+	if claimed, _ := q.Claim("g1"); !claimed {
+		t.Error("Expected expired claim to be taken over")
+	}
+}
@@ -0,0 +1,149 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"server/internal/game"
+	"server/internal/queue"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// lobbyChatTopic 大厅频道的主题，所有会话都会订阅
+const lobbyChatTopic = "lobby/chat"
+
+// 聊天消息的拒绝原因
+var (
+	ErrChatEmpty       = errors.New("chat message is empty")
+	ErrChatTooLong     = errors.New("chat message too long")
+	ErrChatRateLimited = errors.New("chat rate limit exceeded")
+)
+
+// ChatFilter 聊天内容审核，返回替换后的文本，返回错误时拒绝整条消息
+type ChatFilter interface {
+	Filter(userId, text string) (string, error)
+}
+
+// ChatPermissions 检查用户能否发言，用于拒绝被禁言或封禁的用户
+type ChatPermissions interface {
+	CanChat(userId string) error
+}
+
+// Moderation 聊天审核的扩展点，字段为 nil 时跳过对应检查
+type Moderation struct {
+	Filter      ChatFilter
+	Permissions ChatPermissions
+}
+
+type ChatPayload struct {
+	Channel string `json:"channel"`
+	Text    string `json:"text"`
+}
+
+// WordFilter 将屏蔽词替换为等长的星号，不区分大小写
+type WordFilter struct {
+	pattern *regexp.Regexp
+}
+
+func NewWordFilter(words []string) *WordFilter {
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+	if len(quoted) == 0 {
+		return &WordFilter{}
+	}
+	return &WordFilter{pattern: regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))}
+}
+
+func (f *WordFilter) Filter(_, text string) (string, error) {
+	if f.pattern == nil {
+		return text, nil
+	}
+	return f.pattern.ReplaceAllStringFunc(text, func(word string) string {
+		return strings.Repeat("*", utf8.RuneCountInString(word))
+	}), nil
+}
+
+// handleChatMessage 审核聊天消息后发布：大厅频道直接发布到 lobby/chat，对局频道交由对局确定接收者。
+// 发送者为连接认证的用户，而不是负载中的 playerId。
+func (ws *WebSocketServer) handleChatMessage(c *client, msg ClientMessage) error {
+	var payload ChatPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return fmt.Errorf("invalid chat payload: %w", err)
+	}
+	if c.playerId == "" {
+		return fmt.Errorf("authentication required to chat")
+	}
+
+	text, err := ws.moderateChat(c.playerId, payload.Text, time.Now())
+	if err != nil {
+		return err
+	}
+
+	channel := game.ChatChannel(payload.Channel)
+	if channel == game.ChatChannelLobby {
+		ws.queue.Publish(lobbyChatTopic, game.ChatMessageEvent{
+			Channel:    channel,
+			SenderId:   c.playerId,
+			SenderName: c.username,
+			Text:       text,
+			SentAt:     time.Now().UnixMilli(),
+		})
+		return nil
+	}
+
+	if msg.GameId == "" {
+		return fmt.Errorf("gameId is required for %s chat", payload.Channel)
+	}
+	ws.queue.Publish(fmt.Sprintf("%s/commands", msg.GameId), game.ChatCommand{
+		CommandEvent: game.CommandEvent{PlayerId: c.playerId},
+		Channel:      channel,
+		Text:         text,
+	})
+	return nil
+}
+
+// moderateChat 依次检查长度、发言权限、频率与内容，返回审核后的文本
+func (ws *WebSocketServer) moderateChat(userId, text string, now time.Time) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", ErrChatEmpty
+	}
+	if ws.limits.ChatMaxLength > 0 && utf8.RuneCountInString(text) > ws.limits.ChatMaxLength {
+		return "", fmt.Errorf("%w: max %d characters", ErrChatTooLong, ws.limits.ChatMaxLength)
+	}
+	if ws.moderation.Permissions != nil {
+		if err := ws.moderation.Permissions.CanChat(userId); err != nil {
+			return "", err
+		}
+	}
+	if !ws.chatLimiter.allow(userId, now) {
+		return "", ErrChatRateLimited
+	}
+	if ws.moderation.Filter != nil {
+		return ws.moderation.Filter.Filter(userId, text)
+	}
+	return text, nil
+}
+
+// chatRecipient 只转发接收者包含会话用户的消息，Recipients 为空表示所有人。
+// 观战者从延迟的观战主题收到玩家的公共消息，聊天主题只转发明确发给观战者的消息
+func chatRecipient(userId string, spectator bool) func(queue.Event) bool {
+	return func(event queue.Event) bool {
+		message, ok := event.(game.ChatMessageEvent)
+		if !ok {
+			return true
+		}
+		if len(message.Recipients) == 0 {
+			return !spectator
+		}
+		return slices.Contains(message.Recipients, userId)
+	}
+}
@@ -0,0 +1,152 @@
+package websocket
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"server/internal/game"
+	"server/internal/queue"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+type fakePermissions map[string]error
+
+func (p fakePermissions) CanChat(userId string) error {
+	return p[userId]
+}
+
+func startChatServer(t *testing.T, q queue.Queue, limits Limits, moderation Moderation) (*WebSocketServer, string) {
+	t.Helper()
+	server := NewWebSocketServerWithModeration(q, limits, moderation)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set("X-User-ID", r.URL.Query().Get("user"))
+		r.Header.Set("X-Username", "Name "+r.URL.Query().Get("user"))
+		server.HandleWebSocket(w, r)
+	}))
+	t.Cleanup(httpServer.Close)
+	return server, "ws" + strings.TrimPrefix(httpServer.URL, "http")
+}
+
+func sendChat(conn *websocket.Conn, gameId, channel, text string) {
+	conn.WriteJSON(map[string]interface{}{
+		"type":    "chat",
+		"gameId":  gameId,
+		"payload": map[string]interface{}{"channel": channel, "text": text},
+	})
+}
+
+func TestWordFilter(t *testing.T) {
+	filter := NewWordFilter([]string{"darn", " ", "heck"})
+	text, err := filter.Filter("u1", "Darn it, what the HECK")
+	if err != nil || text != "**** it, what the ****" {
+		t.Errorf("Expected masked text, got %q (%v)", text, err)
+	}
+	if text, _ := NewWordFilter(nil).Filter("u1", "darn"); text != "darn" {
+		t.Errorf("Expected empty filter to keep text, got %q", text)
+	}
+}
+
+func TestWebSocket_ChatModeration(t *testing.T) {
+	q := queue.NewInMemoryQueue()
+	commands := q.Subscribe("game-1/commands")
+	_, url := startChatServer(t, q, Limits{ChatMaxLength: 10, ChatRPS: 0.001, ChatBurst: 2}, Moderation{
+		Filter:      NewWordFilter([]string{"darn"}),
+		Permissions: fakePermissions{"muted": errors.New("user is muted")},
+	})
+
+	conn := dialURL(t, url+"?user=u1")
+	sendChat(conn, "game-1", "team", " darn ")
+	cmd := expectCommand[game.ChatCommand](t, commands)
+	if cmd.PlayerId != "u1" || cmd.Channel != game.ChatChannelTeam || cmd.Text != "****" {
+		t.Errorf("Expected filtered team chat from u1, got %+v", cmd)
+	}
+
+	sendChat(conn, "game-1", "all", "this message is too long")
+	if msg := readUntilType(t, conn, "error"); !strings.Contains(msg["error"].(string), ErrChatTooLong.Error()) {
+		t.Errorf("Expected too long error, got %v", msg)
+	}
+	sendChat(conn, "game-1", "all", "   ")
+	if msg := readUntilType(t, conn, "error"); msg["error"] != ErrChatEmpty.Error() {
+		t.Errorf("Expected empty message error, got %v", msg)
+	}
+
+	// 第二条消息用完令牌桶，第三条被限流
+	sendChat(conn, "game-1", "all", "gl")
+	expectCommand[game.ChatCommand](t, commands)
+	sendChat(conn, "game-1", "all", "hf")
+	if msg := readUntilType(t, conn, "error"); msg["error"] != ErrChatRateLimited.Error() {
+		t.Errorf("Expected rate limit error, got %v", msg)
+	}
+
+	muted := dialURL(t, url+"?user=muted")
+	sendChat(muted, "game-1", "all", "hello")
+	if msg := readUntilType(t, muted, "error"); msg["error"] != "user is muted" {
+		t.Errorf("Expected muted error, got %v", msg)
+	}
+}
+
+func TestWebSocket_ChatForwarding(t *testing.T) {
+	q := queue.NewInMemoryQueue()
+	commands := q.Subscribe("game-1/commands")
+	server, url := startChatServer(t, q, Limits{ChatMaxLength: 100}, Moderation{})
+
+	conn := dialURL(t, url+"?user=u1")
+	other := dialURL(t, url+"?user=u2")
+	waitForConns(t, server, "u1", 1)
+	waitForConns(t, server, "u2", 1)
+
+	// 大厅频道直接发布，所有会话都会收到
+	sendChat(other, "", "lobby", "anyone up for 1v1?")
+	msg := readUntilType(t, conn, "game.chatMessage")
+	if data := msg["data"].(map[string]interface{}); msg["gameId"] != "lobby" || data["SenderName"] != "Name u2" {
+		t.Errorf("Expected lobby chat from u2, got %v", msg)
+	}
+
+	conn.WriteJSON(map[string]interface{}{
+		"type":    "join",
+		"gameId":  "game-1",
+		"payload": map[string]interface{}{"playerId": "u1"},
+	})
+	expectCommand[game.JoinCommand](t, commands)
+	q.Publish("game-1/player/u1", game.JoinAckEvent{PlayerId: "u1"})
+
+	// 只转发接收者包含本玩家的消息
+	q.Publish("game-1/chat", game.ChatMessageEvent{Channel: game.ChatChannelTeam, SenderId: "u3", Text: "secret", Recipients: []string{"u3", "u4"}})
+	q.Publish("game-1/chat", game.ChatMessageEvent{Channel: game.ChatChannelTeam, SenderId: "u2", Text: "for u1", Recipients: []string{"u1", "u2"}})
+
+	msg = readUntilType(t, conn, "game.chatMessage")
+	if data := msg["data"].(map[string]interface{}); data["Text"] != "for u1" {
+		t.Errorf("Expected only the message addressed to u1, got %v", data)
+	}
+
+	// 不能以其他玩家的身份加入并收到发给他的消息
+	other.WriteJSON(map[string]interface{}{
+		"type":    "join",
+		"gameId":  "game-1",
+		"payload": map[string]interface{}{"playerId": "u1"},
+	})
+	if msg := readUntilType(t, other, "error"); !strings.Contains(msg["error"].(string), ErrPlayerMismatch.Error()) {
+		t.Errorf("Expected player mismatch error, got %v", msg)
+	}
+
+	// 观战者只从聊天主题收到发给观战者的消息，玩家的公共消息经由观战主题延迟送达
+	watcher := dialURL(t, url+"?user=w1")
+	waitForConns(t, server, "w1", 1)
+	watcher.WriteJSON(map[string]interface{}{
+		"type":    "spectate",
+		"gameId":  "game-1",
+		"payload": map[string]interface{}{},
+	})
+	expectCommand[game.SpectateCommand](t, commands)
+	q.Publish("game-1/player/w1", game.JoinAckEvent{PlayerId: "w1"})
+	q.Publish("game-1/chat", game.ChatMessageEvent{Channel: game.ChatChannelAll, SenderId: "u2", Text: "live"})
+	q.Publish("game-1/chat", game.ChatMessageEvent{Channel: game.ChatChannelAll, SenderId: "w2", Text: "to watchers", Recipients: []string{"w1", "w2"}})
+
+	msg = readUntilType(t, watcher, "game.chatMessage")
+	if data := msg["data"].(map[string]interface{}); data["Text"] != "to watchers" {
+		t.Errorf("Expected only the spectator message, got %v", data)
+	}
+}
@@ -0,0 +1,195 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"server/internal/game"
+	"server/internal/queue"
+	"slices"
+)
+
+// maxPendingEvents 对局确认加入之前每个主题最多缓存的事件数，超出的事件被丢弃，客户端可以通过 resume 补齐
+const maxPendingEvents = 256
+
+// ServerMessage 转发给客户端的对局事件，Type 为事件注册的类型名（如 game.playerJoined）
+type ServerMessage struct {
+	Type   string          `json:"type"`
+	GameId string          `json:"gameId"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// gameSubscription 会话在某个对局中订阅的广播与玩家主题，会话断线保留期间通道为空。
+// 观战者订阅延迟的观战主题代替广播主题。
+type gameSubscription struct {
+	gameId    string
+	playerId  string
+	spectator bool
+	// name 与 password 为观战者名称与房间密码，重连时重新加入观战
+	name      string
+	password  string
+	broadcast <-chan queue.Event
+	player    <-chan queue.Event
+	chat      <-chan queue.Event
+
+	// accepted 对局确认加入后关闭，此前广播与聊天事件只缓存不转发，确认被拒绝时移除订阅。
+	// isAccepted 由 sessionsMu 保护
+	accepted   chan struct{}
+	isAccepted bool
+}
+
+func (sub *gameSubscription) broadcastTopic() string {
+	if sub.spectator {
+		return fmt.Sprintf("%s/spectate", sub.gameId)
+	}
+	return fmt.Sprintf("%s/broadcast", sub.gameId)
+}
+
+// joinCommand 会话重连时重新加入对局的指令
+func (sub *gameSubscription) joinCommand() queue.Event {
+	if sub.spectator {
+		return game.SpectateCommand{
+			CommandEvent:  game.CommandEvent{PlayerId: sub.playerId},
+			SpectatorName: sub.name,
+			Password:      sub.password,
+		}
+	}
+	return game.ReconnectCommand{
+		CommandEvent: game.CommandEvent{PlayerId: sub.playerId},
+	}
+}
+
+// leaveCommand 会话断线时离开对局的指令，观战者直接退出观战
+func (sub *gameSubscription) leaveCommand() queue.Event {
+	if sub.spectator {
+		return game.StopSpectatingCommand{
+			CommandEvent: game.CommandEvent{PlayerId: sub.playerId},
+		}
+	}
+	return game.DisconnectCommand{
+		CommandEvent: game.CommandEvent{PlayerId: sub.playerId},
+	}
+}
+
+// startForwarding 订阅对局主题并转发给会话的所有连接，调用方需持有 sessionsMu
+func (ws *WebSocketServer) startForwarding(s *session, sub *gameSubscription) {
+	sub.broadcast = ws.queue.Subscribe(sub.broadcastTopic())
+	sub.player = ws.queue.Subscribe(fmt.Sprintf("%s/player/%s", sub.gameId, sub.playerId))
+	sub.chat = ws.queue.Subscribe(fmt.Sprintf("%s/chat", sub.gameId))
+
+	go ws.forwardAccepted(s, sub, sub.broadcast, nil)
+	go ws.forwardPlayer(s, sub, sub.player)
+	go ws.forwardAccepted(s, sub, sub.chat, chatRecipient(s.key, sub.spectator))
+}
+
+// stopForwarding 取消订阅，转发协程在通道关闭后退出。调用方需持有 sessionsMu
+func (ws *WebSocketServer) stopForwarding(sub *gameSubscription) {
+	if sub.broadcast == nil {
+		return
+	}
+	ws.queue.Unsubscribe(sub.broadcastTopic(), sub.broadcast)
+	ws.queue.Unsubscribe(fmt.Sprintf("%s/player/%s", sub.gameId, sub.playerId), sub.player)
+	ws.queue.Unsubscribe(fmt.Sprintf("%s/chat", sub.gameId), sub.chat)
+	sub.broadcast, sub.player, sub.chat = nil, nil, nil
+}
+
+// forward 将事件转发给会话的所有连接，accept 不为 nil 时只转发其接受的事件
+func (ws *WebSocketServer) forward(s *session, gameId string, events <-chan queue.Event, accept func(queue.Event) bool) {
+	for event := range events {
+		if accept != nil && !accept(event) {
+			continue
+		}
+		ws.send(s, gameId, event)
+	}
+}
+
+// forwardAccepted 与 forward 相同，但在对局确认加入之前只缓存事件，确认后按原顺序补发
+func (ws *WebSocketServer) forwardAccepted(s *session, sub *gameSubscription, events <-chan queue.Event, accept func(queue.Event) bool) {
+	accepted := sub.accepted
+	var pending []queue.Event
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if accept != nil && !accept(event) {
+				continue
+			}
+			if accepted == nil {
+				ws.send(s, sub.gameId, event)
+			} else if len(pending) < maxPendingEvents {
+				pending = append(pending, event)
+			} else {
+				slog.Warn("dropping event before join was accepted", "gameId", sub.gameId, "user", s.key)
+			}
+		case <-accepted:
+			accepted = nil
+			for _, event := range pending {
+				ws.send(s, sub.gameId, event)
+			}
+			pending = nil
+		}
+	}
+}
+
+// forwardPlayer 转发玩家主题，并根据对局的加入确认开始转发或移除订阅。确认本身不转发给客户端
+func (ws *WebSocketServer) forwardPlayer(s *session, sub *gameSubscription, events <-chan queue.Event) {
+	for event := range events {
+		if ack, ok := event.(game.JoinAckEvent); ok {
+			if ack.Error == "" {
+				ws.acceptGame(sub)
+			} else {
+				// 移除订阅会关闭本协程读取的通道，不能在此同步执行
+				go ws.rejectGame(s, sub)
+			}
+			continue
+		}
+		ws.send(s, sub.gameId, event)
+	}
+}
+
+func (ws *WebSocketServer) acceptGame(sub *gameSubscription) {
+	ws.sessionsMu.Lock()
+	defer ws.sessionsMu.Unlock()
+
+	if !sub.isAccepted {
+		sub.isAccepted = true
+		close(sub.accepted)
+	}
+}
+
+// rejectGame 移除尚未被接受的订阅，已接受的订阅不受之后失败的加入指令影响
+func (ws *WebSocketServer) rejectGame(s *session, sub *gameSubscription) {
+	ws.sessionsMu.Lock()
+	defer ws.sessionsMu.Unlock()
+
+	if sub.isAccepted || s.games[sub.gameId] != sub {
+		return
+	}
+	ws.stopForwarding(sub)
+	delete(s.games, sub.gameId)
+}
+
+// send 将单个事件发送给会话的所有连接
+func (ws *WebSocketServer) send(s *session, gameId string, event queue.Event) {
+	envelope, err := queue.NewEnvelope(event, "")
+	if err != nil {
+		slog.Error("failed to encode event for client", "error", err, "gameId", gameId)
+		return
+	}
+	msg := ServerMessage{
+		Type:   envelope.Type,
+		GameId: gameId,
+		Data:   envelope.Payload,
+	}
+
+	ws.sessionsMu.Lock()
+	conns := slices.Clone(s.conns)
+	ws.sessionsMu.Unlock()
+
+	// 写入失败说明连接正在关闭，由读循环负责清理
+	for _, c := range conns {
+		c.writeJSON(msg)
+	}
+}
@@ -0,0 +1,79 @@
+package websocket
+
+import (
+	"encoding/json"
+	"server/internal/game"
+	"server/internal/queue"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWebSocket_ResumeForwardsEvents(t *testing.T) {
+	q := queue.NewInMemoryQueue()
+	commands := q.Subscribe("game-1/commands")
+	_, url := startHeartbeatServer(t, q, Limits{})
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	conn.WriteJSON(map[string]interface{}{
+		"type":    "resume",
+		"gameId":  "game-1",
+		"payload": map[string]interface{}{"lastSeq": 12},
+	})
+	cmd := expectCommand[game.ResumeCommand](t, commands)
+	if cmd.PlayerId != "user_1" || cmd.LastSeq != 12 {
+		t.Errorf("Expected resume for user_1 after seq 12, got %+v", cmd)
+	}
+
+	// 订阅在发布指令之前建立，对局的回复与后续广播都会转发给客户端
+	q.Publish("game-1/player/user_1", game.ResumeEvent{PlayerId: "user_1", Seq: 14, Complete: true})
+	q.Publish("game-1/player/user_1", game.JoinAckEvent{PlayerId: "user_1"})
+	q.Publish("game-1/broadcast", game.TurnStartedEvent{BroadcastEvent: game.BroadcastEvent{Seq: 15}, TurnNumber: 3})
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	received := make(map[string]json.RawMessage)
+	for len(received) < 2 {
+		var msg ServerMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("Expected forwarded events, got %v", err)
+		}
+		if msg.GameId != "game-1" {
+			t.Errorf("Expected gameId game-1, got %s", msg.GameId)
+		}
+		received[msg.Type] = msg.Data
+	}
+
+	var resumed game.ResumeEvent
+	if err := json.Unmarshal(received["game.resumed"], &resumed); err != nil || resumed.Seq != 14 {
+		t.Errorf("Expected game.resumed with seq 14, got %s", received["game.resumed"])
+	}
+	var turn game.TurnStartedEvent
+	if err := json.Unmarshal(received["game.turnStarted"], &turn); err != nil || turn.Seq != 15 {
+		t.Errorf("Expected game.turnStarted with seq 15, got %s", received["game.turnStarted"])
+	}
+}
+
+// 不能以其他玩家的身份恢复，否则可以读取对方的视野并接管其断线的位置
+func TestWebSocket_ResumeRejectsOtherPlayer(t *testing.T) {
+	_, url := startHeartbeatServer(t, queue.NewInMemoryQueue(), Limits{})
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	conn.WriteJSON(map[string]interface{}{"type": "resume", "gameId": "game-1", "payload": map[string]interface{}{"playerId": "p2"}})
+	var msg map[string]string
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := conn.ReadJSON(&msg); err != nil || msg["type"] != "error" || !strings.Contains(msg["error"], ErrPlayerMismatch.Error()) {
+		t.Errorf("Expected error message, got %v (%v)", msg, err)
+	}
+}
@@ -0,0 +1,44 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// pingWriteWait 发送 ping 的写超时
+const pingWriteWait = 5 * time.Second
+
+// keepAlive 按心跳间隔发送 ping，done 关闭或写入失败时返回
+func (ws *WebSocketServer) keepAlive(c *client, done <-chan struct{}) {
+	ticker := time.NewTicker(ws.limits.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, now.Add(pingWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// updateReadDeadline 读超时取 readTimeout 内无消息与两个心跳间隔内无 pong 中较早的一个
+func (ws *WebSocketServer) updateReadDeadline(c *client) {
+	var deadline time.Time
+	if ws.limits.ReadTimeout > 0 {
+		deadline = c.lastMessage.Add(ws.limits.ReadTimeout)
+	}
+	if ws.limits.PingInterval > 0 {
+		pongDeadline := c.lastPong.Add(2 * ws.limits.PingInterval)
+		if deadline.IsZero() || pongDeadline.Before(deadline) {
+			deadline = pongDeadline
+		}
+	}
+	if !deadline.IsZero() {
+		c.conn.SetReadDeadline(deadline)
+	}
+}
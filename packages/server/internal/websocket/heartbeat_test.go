@@ -0,0 +1,148 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"server/internal/game"
+	"server/internal/queue"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func startHeartbeatServer(t *testing.T, q queue.Queue, limits Limits) (*WebSocketServer, string) {
+	t.Helper()
+	server := NewWebSocketServer(q, limits)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set("X-User-ID", "user_1")
+		server.HandleWebSocket(w, r)
+	}))
+	t.Cleanup(httpServer.Close)
+	return server, "ws" + strings.TrimPrefix(httpServer.URL, "http")
+}
+
+func expectCommand[T any](t *testing.T, ch <-chan queue.Event) T {
+	t.Helper()
+	select {
+	case event := <-ch:
+		cmd, ok := event.(T)
+		if !ok {
+			t.Fatalf("Expected %T, got %T", cmd, event)
+		}
+		return cmd
+	case <-time.After(2 * time.Second):
+		var zero T
+		t.Fatalf("Expected %T, got nothing", zero)
+		return zero
+	}
+}
+
+func TestWebSocket_Ping(t *testing.T) {
+	_, url := startHeartbeatServer(t, queue.NewInMemoryQueue(), Limits{PingInterval: 20 * time.Millisecond})
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	pinged := make(chan struct{}, 1)
+	conn.SetPingHandler(func(data string) error {
+		select {
+		case pinged <- struct{}{}:
+		default:
+		}
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-pinged:
+	case <-time.After(time.Second):
+		t.Fatal("Expected ping from server")
+	}
+}
+
+func TestWebSocket_PongTimeout(t *testing.T) {
+	server, url := startHeartbeatServer(t, queue.NewInMemoryQueue(), Limits{PingInterval: 20 * time.Millisecond})
+
+	// 不读取的客户端不会回应 pong
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	waitForClients(t, server, 1)
+	waitForClients(t, server, 0)
+}
+
+func TestWebSocket_DisconnectAndReconnect(t *testing.T) {
+	q := queue.NewInMemoryQueue()
+	commands := q.Subscribe("game-1/commands")
+	server, url := startHeartbeatServer(t, q, Limits{ReconnectWindow: time.Minute})
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	conn.WriteJSON(map[string]interface{}{
+		"type":    "join",
+		"gameId":  "game-1",
+		"payload": map[string]string{"playerId": "user_1", "playerName": "Alice"},
+	})
+	expectCommand[game.JoinCommand](t, commands)
+
+	conn.Close()
+	if cmd := expectCommand[game.DisconnectCommand](t, commands); cmd.PlayerId != "user_1" {
+		t.Errorf("Expected disconnect for p1, got %s", cmd.PlayerId)
+	}
+	waitForClients(t, server, 0)
+
+	conn, _, err = websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	if cmd := expectCommand[game.ReconnectCommand](t, commands); cmd.PlayerId != "user_1" {
+		t.Errorf("Expected reconnect for p1, got %s", cmd.PlayerId)
+	}
+}
+
+func TestWebSocket_LeaveSkipsDisconnect(t *testing.T) {
+	q := queue.NewInMemoryQueue()
+	commands := q.Subscribe("game-1/commands")
+	server, url := startHeartbeatServer(t, q, Limits{ReconnectWindow: time.Minute})
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	conn.WriteJSON(map[string]interface{}{
+		"type":    "join",
+		"gameId":  "game-1",
+		"payload": map[string]string{"playerId": "user_1"},
+	})
+	conn.WriteJSON(map[string]interface{}{
+		"type":    "leave",
+		"gameId":  "game-1",
+		"payload": map[string]string{"playerId": "user_1"},
+	})
+	expectCommand[game.JoinCommand](t, commands)
+	expectCommand[game.LeaveCommand](t, commands)
+
+	conn.Close()
+	waitForClients(t, server, 0)
+	select {
+	case event := <-commands:
+		t.Errorf("Expected no command after leave, got %T", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,101 @@
+package websocket
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// 超过该数量的玩家令牌桶时清理已回满的桶
+const playerBucketPruneSize = 10000
+
+// Limits WebSocket 连接的消息限制，0 值表示不限制
+type Limits struct {
+	MaxMessageSize int64
+	ReadTimeout    time.Duration
+	MessageRPS     float64
+	MessageBurst   int
+	PlayerRPS      float64
+	PlayerBurst    int
+	// MaxViolations 单个连接累计被限流的消息数，超过后断开连接
+	MaxViolations int
+	// PingInterval 心跳间隔，连续两个间隔内没有收到 pong 则断开连接
+	PingInterval time.Duration
+	// ReconnectWindow 断线后保留用户所在对局的时长，期间同一用户的新连接会自动重连这些对局
+	ReconnectWindow time.Duration
+	// ChatMaxLength 单条聊天消息的最大字符数
+	ChatMaxLength int
+	// ChatRPS 与 ChatBurst 为每个用户的聊天令牌桶，与消息限流相互独立
+	ChatRPS   float64
+	ChatBurst int
+}
+
+type tokenBucket struct {
+	rate     float64
+	burst    float64
+	tokens   float64
+	lastSeen time.Time
+}
+
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	b := float64(max(burst, 1))
+	return &tokenBucket{rate: rate, burst: b, tokens: b, lastSeen: now}
+}
+
+func (b *tokenBucket) allow(now time.Time) bool {
+	if b.rate <= 0 {
+		return true
+	}
+
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.lastSeen).Seconds()*b.rate)
+	b.lastSeen = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *tokenBucket) full(now time.Time) bool {
+	return b.rate <= 0 || now.Sub(b.lastSeen).Seconds()*b.rate >= b.burst
+}
+
+// playerLimiter 按玩家共享的令牌桶，防止同一玩家通过多个连接绕过单连接限制
+type playerLimiter struct {
+	mutex   sync.Mutex
+	rate    float64
+	burst   int
+	buckets map[string]*tokenBucket
+}
+
+func newPlayerLimiter(rate float64, burst int) *playerLimiter {
+	return &playerLimiter{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+func (l *playerLimiter) allow(playerId string, now time.Time) bool {
+	if l.rate <= 0 || playerId == "" {
+		return true
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if len(l.buckets) > playerBucketPruneSize {
+		for id, bucket := range l.buckets {
+			if bucket.full(now) {
+				delete(l.buckets, id)
+			}
+		}
+	}
+
+	bucket, exists := l.buckets[playerId]
+	if !exists {
+		bucket = newTokenBucket(l.rate, l.burst, now)
+		l.buckets[playerId] = bucket
+	}
+	return bucket.allow(now)
+}
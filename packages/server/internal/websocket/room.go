@@ -0,0 +1,167 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"server/internal/game"
+	"server/internal/lobby"
+	"server/internal/queue"
+	"time"
+)
+
+const (
+	// inviteCodeAlphabet 去掉了易混淆的 0/O、1/I，长度为 32 以便均匀取样
+	inviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	inviteCodeLength   = 8
+	// roomCreateTimeout 等待大厅确认创建房间的时长
+	roomCreateTimeout = 5 * time.Second
+)
+
+// CreateRoomPayload 创建私人房间，Password 为空时无需密码
+type CreateRoomPayload struct {
+	GameMode string `json:"gameMode"`
+	MapId    string `json:"mapId"`
+	Password string `json:"password"`
+}
+
+// RoomTargetPayload kick 与 transferHost 的目标玩家
+type RoomTargetPayload struct {
+	TargetId string `json:"targetId"`
+}
+
+// RoomSettingsPayload 字段为空时保持不变
+type RoomSettingsPayload struct {
+	GameMode string `json:"gameMode"`
+	MapId    string `json:"mapId"`
+}
+
+// newInviteCode 生成私人房间的邀请码，同时作为房间的 gameId
+func newInviteCode() (string, error) {
+	b := make([]byte, inviteCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = inviteCodeAlphabet[int(b[i])%len(inviteCodeAlphabet)]
+	}
+	return string(b), nil
+}
+
+// handleCreateRoomMessage 生成邀请码并请求大厅创建房间，创建者为房主，随后需以邀请码 join。
+// 大厅确认房间已创建后才回复 roomCreated
+func (ws *WebSocketServer) handleCreateRoomMessage(c *client, msg ClientMessage) error {
+	if c.playerId == "" {
+		return fmt.Errorf("authentication required to create a room")
+	}
+
+	var payload CreateRoomPayload
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return fmt.Errorf("invalid create room payload: %w", err)
+		}
+	}
+
+	gameMode := game.Classic1v1
+	if payload.GameMode != "" {
+		mode, exists := game.GetGameMode(payload.GameMode)
+		if !exists {
+			return fmt.Errorf("unknown game mode: %s", payload.GameMode)
+		}
+		gameMode = mode
+	}
+
+	code, err := newInviteCode()
+	if err != nil {
+		return fmt.Errorf("failed to generate invite code: %w", err)
+	}
+
+	// 先订阅房主的大厅主题，避免错过大厅的确认
+	topic := lobby.PlayerTopic(c.playerId)
+	results := ws.queue.Subscribe(topic)
+	go ws.awaitRoomCreated(c, code, topic, results)
+
+	ws.queue.Publish("lobby/commands", lobby.LobbyCommand{
+		Type:   "createRoom",
+		GameId: code,
+		Payload: lobby.CreateRoomPayload{
+			Game:     lobby.CreateGamePayload{GameMode: gameMode, MapId: payload.MapId},
+			Host:     c.playerId,
+			Password: payload.Password,
+		},
+	})
+
+	return nil
+}
+
+// awaitRoomCreated 等待大厅对邀请码 code 的创建结果并回复客户端，超时视为失败
+func (ws *WebSocketServer) awaitRoomCreated(c *client, code, topic string, results <-chan queue.Event) {
+	defer ws.queue.Unsubscribe(topic, results)
+
+	timeout := time.NewTimer(roomCreateTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case event, ok := <-results:
+			if !ok {
+				return
+			}
+			result, ok := event.(lobby.RoomCreatedEvent)
+			if !ok || result.GameId != code {
+				continue
+			}
+			if result.Error != "" {
+				c.writeJSON(map[string]string{"type": "error", "error": result.Error})
+				return
+			}
+			c.writeJSON(map[string]interface{}{
+				"type":       "roomCreated",
+				"gameId":     code,
+				"inviteCode": code,
+			})
+			return
+		case <-timeout.C:
+			c.writeJSON(map[string]string{"type": "error", "error": "timed out creating room"})
+			return
+		}
+	}
+}
+
+// handleHostMessage 将房主操作转为对局指令，操作者为连接认证的用户，是否为房主由对局检查
+func (ws *WebSocketServer) handleHostMessage(c *client, msg ClientMessage) error {
+	if c.playerId == "" {
+		return fmt.Errorf("authentication required for %s", msg.Type)
+	}
+	if msg.GameId == "" {
+		return fmt.Errorf("gameId is required for %s", msg.Type)
+	}
+
+	host := game.CommandEvent{PlayerId: c.playerId}
+	var cmd queue.Event
+	switch msg.Type {
+	case "kick", "transferHost":
+		var payload RoomTargetPayload
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return fmt.Errorf("invalid %s payload: %w", msg.Type, err)
+		}
+		if payload.TargetId == "" {
+			return fmt.Errorf("targetId is required")
+		}
+		if msg.Type == "kick" {
+			cmd = game.KickCommand{CommandEvent: host, TargetId: payload.TargetId}
+		} else {
+			cmd = game.TransferHostCommand{CommandEvent: host, TargetId: payload.TargetId}
+		}
+	case "roomSettings":
+		var payload RoomSettingsPayload
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return fmt.Errorf("invalid room settings payload: %w", err)
+		}
+		cmd = game.RoomSettingsCommand{CommandEvent: host, GameMode: payload.GameMode, MapId: payload.MapId}
+	case "start":
+		cmd = game.HostStartCommand{CommandEvent: host}
+	}
+
+	ws.queue.Publish(fmt.Sprintf("%s/commands", msg.GameId), cmd)
+	return nil
+}
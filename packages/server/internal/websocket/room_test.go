@@ -0,0 +1,124 @@
+package websocket
+
+import (
+	"server/internal/game"
+	"server/internal/lobby"
+	"server/internal/queue"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewInviteCode(t *testing.T) {
+	code, err := newInviteCode()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(code) != inviteCodeLength {
+		t.Errorf("Expected %d characters, got %q", inviteCodeLength, code)
+	}
+	for _, r := range code {
+		if !strings.ContainsRune(inviteCodeAlphabet, r) {
+			t.Errorf("Unexpected character %q in invite code %s", r, code)
+		}
+	}
+}
+
+func TestWebSocket_CreateRoom(t *testing.T) {
+	q := queue.NewInMemoryQueue()
+	lobbyCommands := q.Subscribe("lobby/commands")
+	_, url := startChatServer(t, q, Limits{}, Moderation{})
+
+	conn := dialURL(t, url+"?user=u1")
+	conn.WriteJSON(map[string]interface{}{
+		"type":    "createRoom",
+		"payload": map[string]interface{}{"gameMode": game.Classic1v1.Name, "password": "secret"},
+	})
+
+	cmd := expectCommand[lobby.LobbyCommand](t, lobbyCommands)
+	payload, ok := cmd.Payload.(lobby.CreateRoomPayload)
+	if cmd.Type != "createRoom" || cmd.GameId == "" || !ok ||
+		payload.Host != "u1" || payload.Password != "secret" {
+		t.Errorf("Expected createRoom hosted by u1, got %+v", cmd)
+	}
+
+	// 大厅确认之前不回复邀请码
+	conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	var early map[string]interface{}
+	if err := conn.ReadJSON(&early); err == nil {
+		t.Fatalf("Expected no reply before the lobby confirms, got %v", early)
+	}
+	conn.Close()
+	conn = dialURL(t, url+"?user=u1")
+	defer conn.Close()
+
+	conn.WriteJSON(map[string]interface{}{"type": "createRoom"})
+	cmd = expectCommand[lobby.LobbyCommand](t, lobbyCommands)
+	q.Publish(lobby.PlayerTopic("u1"), lobby.RoomCreatedEvent{GameId: cmd.GameId, Host: "u1"})
+
+	reply := readUntilType(t, conn, "roomCreated")
+	if reply["inviteCode"] != cmd.GameId || reply["gameId"] != cmd.GameId {
+		t.Fatalf("Expected invite code %s as gameId, got %v", cmd.GameId, reply)
+	}
+
+	conn.WriteJSON(map[string]interface{}{"type": "createRoom"})
+	cmd = expectCommand[lobby.LobbyCommand](t, lobbyCommands)
+	q.Publish(lobby.PlayerTopic("u1"), lobby.RoomCreatedEvent{GameId: cmd.GameId, Host: "u1", Error: "failed to create room"})
+	if msg := readUntilType(t, conn, "error"); msg["error"] != "failed to create room" {
+		t.Errorf("Expected lobby error, got %v", msg)
+	}
+}
+
+func TestWebSocket_HostMessages(t *testing.T) {
+	q := queue.NewInMemoryQueue()
+	commands := q.Subscribe("ROOM1/commands")
+	_, url := startChatServer(t, q, Limits{}, Moderation{})
+
+	conn := dialURL(t, url+"?user=u1")
+	conn.WriteJSON(map[string]interface{}{
+		"type":    "kick",
+		"gameId":  "ROOM1",
+		"payload": map[string]interface{}{"targetId": "u2"},
+	})
+	// 操作者为连接认证的用户
+	if kick := expectCommand[game.KickCommand](t, commands); kick.PlayerId != "u1" || kick.TargetId != "u2" {
+		t.Errorf("Expected u1 to kick u2, got %+v", kick)
+	}
+
+	conn.WriteJSON(map[string]interface{}{"type": "start", "gameId": "ROOM1"})
+	if start := expectCommand[game.HostStartCommand](t, commands); start.PlayerId != "u1" {
+		t.Errorf("Expected host start from u1, got %+v", start)
+	}
+
+	conn.WriteJSON(map[string]interface{}{"type": "transferHost", "gameId": "ROOM1", "payload": map[string]interface{}{}})
+	if msg := readUntilType(t, conn, "error"); msg["error"] != "targetId is required" {
+		t.Errorf("Expected missing target error, got %v", msg)
+	}
+}
+
+// 房间中的玩家 id 即认证用户，被移出的用户不能换一个 playerId 重新加入
+func TestWebSocket_RoomJoinUsesAuthenticatedUser(t *testing.T) {
+	q := queue.NewInMemoryQueue()
+	commands := q.Subscribe("ROOM1/commands")
+	_, url := startChatServer(t, q, Limits{}, Moderation{})
+
+	conn := dialURL(t, url+"?user=u2")
+	defer conn.Close()
+	conn.WriteJSON(map[string]interface{}{
+		"type":    "join",
+		"gameId":  "ROOM1",
+		"payload": map[string]interface{}{"playerId": "u2-alt", "password": "secret"},
+	})
+	if msg := readUntilType(t, conn, "error"); !strings.Contains(msg["error"].(string), ErrPlayerMismatch.Error()) {
+		t.Errorf("Expected player mismatch error, got %v", msg)
+	}
+
+	conn.WriteJSON(map[string]interface{}{
+		"type":    "join",
+		"gameId":  "ROOM1",
+		"payload": map[string]interface{}{"password": "secret"},
+	})
+	if join := expectCommand[game.JoinCommand](t, commands); join.PlayerId != "u2" || join.Password != "secret" {
+		t.Errorf("Expected join as u2, got %+v", join)
+	}
+}
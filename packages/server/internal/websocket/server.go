@@ -2,13 +2,18 @@ package websocket
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"server/internal/game"
 	"server/internal/game/block"
 	gamemap "server/internal/game/map"
+	"server/internal/lobby"
+	"server/internal/metrics"
 	"server/internal/queue"
+	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -17,10 +22,34 @@ var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true // 允许所有来源
 	},
+	// 浏览器以子协议传递令牌时，服务端必须回应其中一个子协议，否则连接会被浏览器拒绝
+	Subprotocols: []string{"bearer"},
 }
 
+// 指令身份的拒绝原因
+var (
+	ErrNotAuthenticated = errors.New("authentication required")
+	ErrPlayerMismatch   = errors.New("playerId does not match authenticated user")
+)
+
 type WebSocketServer struct {
-	queue queue.Queue
+	queue   queue.Queue
+	limits  Limits
+	players *playerLimiter
+
+	// chatLimiter 每个用户的聊天频率，moderation 为可替换的审核实现
+	chatLimiter *playerLimiter
+	moderation  Moderation
+
+	// clients 当前连接，停机时用于通知客户端并关闭连接
+	clients   map[*client]struct{}
+	clientsMu sync.Mutex
+	draining  bool
+	handlers  sync.WaitGroup
+
+	// sessions 按用户组织的连接与对局订阅
+	sessions   map[string]*session
+	sessionsMu sync.Mutex
 }
 
 type ClientMessage struct {
@@ -29,9 +58,18 @@ type ClientMessage struct {
 	Payload json.RawMessage `json:"payload"`
 }
 
+// JoinPayload Password 为私人房间的密码。各指令负载中的 PlayerId 均可省略，玩家总是连接认证的用户
 type JoinPayload struct {
 	PlayerId   string `json:"playerId"`
 	PlayerName string `json:"playerName"`
+	Password   string `json:"password,omitempty"`
+}
+
+// ResumePayload LastSeq 为客户端收到的最后一个广播事件的序号，从未收到时为 0。
+// PlayerId 可以省略，恢复的总是连接认证的用户
+type ResumePayload struct {
+	PlayerId string `json:"playerId"`
+	LastSeq  uint64 `json:"lastSeq"`
 }
 
 type MovePayload struct {
@@ -41,18 +79,50 @@ type MovePayload struct {
 	Troops    uint16      `json:"troops"`
 }
 
+type CreateGamePayload struct {
+	GameMode string `json:"gameMode"`
+	MapId    string `json:"mapId"`
+}
+
 type ForceStartPayload struct {
 	PlayerId string `json:"playerId"`
 	IsVote   bool   `json:"isVote"`
 }
 
-func NewWebSocketServer(q queue.Queue) *WebSocketServer {
+// commandPlayer 返回指令的玩家 ID，即连接认证的用户。负载中的 playerId 可以省略，与认证用户不一致时拒绝
+func (c *client) commandPlayer(payloadPlayerId string) (string, error) {
+	if c.playerId == "" {
+		return "", ErrNotAuthenticated
+	}
+	if payloadPlayerId != "" && payloadPlayerId != c.playerId {
+		return "", fmt.Errorf("%w: %s", ErrPlayerMismatch, payloadPlayerId)
+	}
+	return c.playerId, nil
+}
+
+// NewWebSocketServer 创建不带聊天审核扩展的服务器，聊天仍受长度与频率限制
+func NewWebSocketServer(q queue.Queue, limits Limits) *WebSocketServer {
+	return NewWebSocketServerWithModeration(q, limits, Moderation{})
+}
+
+func NewWebSocketServerWithModeration(q queue.Queue, limits Limits, moderation Moderation) *WebSocketServer {
 	return &WebSocketServer{
-		queue: q,
+		queue:       q,
+		limits:      limits,
+		players:     newPlayerLimiter(limits.PlayerRPS, limits.PlayerBurst),
+		chatLimiter: newPlayerLimiter(limits.ChatRPS, limits.ChatBurst),
+		moderation:  moderation,
+		clients:     make(map[*client]struct{}),
+		sessions:    make(map[string]*session),
 	}
 }
 
 func (ws *WebSocketServer) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if ws.isDraining() {
+		http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		slog.Error("websocket upgrade failed", "error", err)
@@ -60,7 +130,48 @@ func (ws *WebSocketServer) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 	}
 	defer conn.Close()
 
-	slog.Info("websocket client connected", "remote", conn.RemoteAddr())
+	playerId := r.Header.Get("X-User-ID")
+	now := time.Now()
+	c := &client{
+		conn:        conn,
+		playerId:    playerId,
+		username:    r.Header.Get("X-Username"),
+		lastMessage: now,
+		lastPong:    now,
+	}
+	if !ws.register(c) {
+		c.notifyRestart()
+		return
+	}
+	defer ws.unregister(c)
+
+	metrics.WebSocketConnections.Inc()
+	defer metrics.WebSocketConnections.Dec()
+	slog.Info("websocket client connected", "remote", conn.RemoteAddr(), "user", playerId)
+
+	ws.connect(c)
+	defer ws.disconnect(c)
+
+	// 超出大小的消息由 gorilla 直接以 1009 (CloseMessageTooBig) 关闭连接
+	if ws.limits.MaxMessageSize > 0 {
+		conn.SetReadLimit(ws.limits.MaxMessageSize)
+	}
+	ws.updateReadDeadline(c)
+
+	if ws.limits.PingInterval > 0 {
+		conn.SetPongHandler(func(string) error {
+			c.lastPong = time.Now()
+			ws.updateReadDeadline(c)
+			return nil
+		})
+		done := make(chan struct{})
+		defer close(done)
+		go ws.keepAlive(c, done)
+	}
+
+	connBucket := newTokenBucket(ws.limits.MessageRPS, ws.limits.MessageBurst, time.Now())
+	violations := 0
+	throttled := false
 
 	for {
 		var msg ClientMessage
@@ -71,78 +182,182 @@ func (ws *WebSocketServer) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 			}
 			break
 		}
+		now := time.Now()
+		c.lastMessage = now
+		ws.updateReadDeadline(c)
+
+		if !connBucket.allow(now) || !ws.players.allow(playerId, now) {
+			violations++
+			if ws.limits.MaxViolations > 0 && violations >= ws.limits.MaxViolations {
+				slog.Warn("disconnecting flooding websocket client", "remote", conn.RemoteAddr(), "user", playerId)
+				closeMsg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "rate limit exceeded")
+				conn.WriteControl(websocket.CloseMessage, closeMsg, now.Add(time.Second))
+				break
+			}
+
+			// 连续被限流时只提示一次，避免向刷屏的客户端放大写入
+			if !throttled {
+				throttled = true
+				c.writeJSON(map[string]string{
+					"type":  "error",
+					"error": "rate limit exceeded",
+				})
+			}
+			continue
+		}
+		throttled = false
 
-		if err := ws.handleMessage(msg); err != nil {
+		if !ws.isActive(c) {
+			c.writeJSON(map[string]string{
+				"type":  "error",
+				"error": "session taken over by another connection",
+			})
+			continue
+		}
+
+		if err := ws.handleMessage(c, msg); err != nil {
 			slog.Error("message handling failed", "error", err, "type", msg.Type)
 			errorMsg := map[string]string{
 				"type":  "error",
 				"error": err.Error(),
 			}
-			conn.WriteJSON(errorMsg)
+			c.writeJSON(errorMsg)
 		}
 	}
 
 	slog.Info("websocket client disconnected", "remote", conn.RemoteAddr())
 }
 
-func (ws *WebSocketServer) handleMessage(msg ClientMessage) error {
+func (ws *WebSocketServer) handleMessage(c *client, msg ClientMessage) error {
 	switch msg.Type {
 	case "join":
-		return ws.handleJoinMessage(msg)
+		return ws.handleJoinMessage(c, msg)
 	case "leave":
-		return ws.handleLeaveMessage(msg)
+		return ws.handleLeaveMessage(c, msg)
+	case "spectate":
+		return ws.handleSpectateMessage(c, msg)
+	case "resume":
+		return ws.handleResumeMessage(c, msg)
+	case "chat":
+		return ws.handleChatMessage(c, msg)
 	case "move":
-		return ws.handleMoveMessage(msg)
+		return ws.handleMoveMessage(c, msg)
 	case "forceStart":
-		return ws.handleForceStartMessage(msg)
+		return ws.handleForceStartMessage(c, msg)
 	case "surrender":
-		return ws.handleSurrenderMessage(msg)
+		return ws.handleSurrenderMessage(c, msg)
 	case "createGame":
 		return ws.handleCreateGameMessage(msg)
+	case "createRoom":
+		return ws.handleCreateRoomMessage(c, msg)
+	case "kick", "transferHost", "roomSettings", "start":
+		return ws.handleHostMessage(c, msg)
 	default:
 		return fmt.Errorf("unknown message type: %s", msg.Type)
 	}
 }
 
-func (ws *WebSocketServer) handleJoinMessage(msg ClientMessage) error {
+func (ws *WebSocketServer) handleJoinMessage(c *client, msg ClientMessage) error {
 	var payload JoinPayload
 	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
 		return fmt.Errorf("invalid join payload: %w", err)
 	}
+	playerId, err := c.commandPlayer(payload.PlayerId)
+	if err != nil {
+		return err
+	}
 
 	joinCmd := game.JoinCommand{
-		CommandEvent: game.CommandEvent{PlayerId: payload.PlayerId},
+		CommandEvent: game.CommandEvent{PlayerId: playerId},
 		PlayerName:   payload.PlayerName,
+		Password:     payload.Password,
 	}
 
+	ws.joinGame(c, &gameSubscription{gameId: msg.GameId, playerId: playerId})
 	ws.queue.Publish(fmt.Sprintf("%s/commands", msg.GameId), joinCmd)
 	return nil
 }
 
-func (ws *WebSocketServer) handleLeaveMessage(msg ClientMessage) error {
-	var payload map[string]string
+// handleSpectateMessage 以观战者身份订阅对局的延迟事件流，负载与 join 相同
+func (ws *WebSocketServer) handleSpectateMessage(c *client, msg ClientMessage) error {
+	var payload JoinPayload
 	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
-		return fmt.Errorf("invalid leave payload: %w", err)
+		return fmt.Errorf("invalid spectate payload: %w", err)
+	}
+	playerId, err := c.commandPlayer(payload.PlayerId)
+	if err != nil {
+		return err
 	}
 
-	playerId := payload["playerId"]
-	if playerId == "" {
-		return fmt.Errorf("playerId is required")
+	ws.joinGame(c, &gameSubscription{
+		gameId:    msg.GameId,
+		playerId:  playerId,
+		spectator: true,
+		name:      payload.PlayerName,
+		password:  payload.Password,
+	})
+	ws.queue.Publish(fmt.Sprintf("%s/commands", msg.GameId), game.SpectateCommand{
+		CommandEvent:  game.CommandEvent{PlayerId: playerId},
+		SpectatorName: payload.PlayerName,
+		Password:      payload.Password,
+	})
+	return nil
+}
+
+func (ws *WebSocketServer) handleLeaveMessage(c *client, msg ClientMessage) error {
+	var payload map[string]string
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return fmt.Errorf("invalid leave payload: %w", err)
+		}
+	}
+	playerId, err := c.commandPlayer(payload["playerId"])
+	if err != nil {
+		return err
 	}
 
-	leaveCmd := game.LeaveCommand{
+	var leaveCmd queue.Event = game.LeaveCommand{
 		CommandEvent: game.CommandEvent{PlayerId: playerId},
 	}
+	if ws.isSpectating(c, msg.GameId) {
+		leaveCmd = game.StopSpectatingCommand{
+			CommandEvent: game.CommandEvent{PlayerId: playerId},
+		}
+	}
 
 	ws.queue.Publish(fmt.Sprintf("%s/commands", msg.GameId), leaveCmd)
+	ws.leaveGame(c, msg.GameId)
+	return nil
+}
+
+// handleResumeMessage 重新订阅对局并请求状态快照与 lastSeq 之后错过的事件
+func (ws *WebSocketServer) handleResumeMessage(c *client, msg ClientMessage) error {
+	var payload ResumePayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return fmt.Errorf("invalid resume payload: %w", err)
+	}
+	playerId, err := c.commandPlayer(payload.PlayerId)
+	if err != nil {
+		return err
+	}
+
+	ws.joinGame(c, &gameSubscription{gameId: msg.GameId, playerId: playerId})
+	ws.queue.Publish(fmt.Sprintf("%s/commands", msg.GameId), game.ResumeCommand{
+		CommandEvent: game.CommandEvent{PlayerId: playerId},
+		LastSeq:      payload.LastSeq,
+	})
 	return nil
 }
 
-func (ws *WebSocketServer) handleMoveMessage(msg ClientMessage) error {
+func (ws *WebSocketServer) handleMoveMessage(c *client, msg ClientMessage) error {
 	var payload MovePayload
 	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
 		return fmt.Errorf("invalid move payload: %w", err)
 	}
+	playerId, err := c.commandPlayer(payload.PlayerId)
+	if err != nil {
+		return err
+	}
 
 	var direction game.MoveTowards
 	switch payload.Direction {
@@ -159,7 +374,7 @@ func (ws *WebSocketServer) handleMoveMessage(msg ClientMessage) error {
 	}
 
 	moveCmd := game.MoveCommand{
-		CommandEvent: game.CommandEvent{PlayerId: payload.PlayerId},
+		CommandEvent: game.CommandEvent{PlayerId: playerId},
 		From:         payload.From,
 		Direction:    direction,
 		Troops:       block.Num(payload.Troops),
@@ -169,14 +384,18 @@ func (ws *WebSocketServer) handleMoveMessage(msg ClientMessage) error {
 	return nil
 }
 
-func (ws *WebSocketServer) handleForceStartMessage(msg ClientMessage) error {
+func (ws *WebSocketServer) handleForceStartMessage(c *client, msg ClientMessage) error {
 	var payload ForceStartPayload
 	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
 		return fmt.Errorf("invalid force start payload: %w", err)
 	}
+	playerId, err := c.commandPlayer(payload.PlayerId)
+	if err != nil {
+		return err
+	}
 
 	forceStartCmd := game.ForceStartCommand{
-		CommandEvent: game.CommandEvent{PlayerId: payload.PlayerId},
+		CommandEvent: game.CommandEvent{PlayerId: playerId},
 		IsVote:       payload.IsVote,
 	}
 
@@ -184,15 +403,16 @@ func (ws *WebSocketServer) handleForceStartMessage(msg ClientMessage) error {
 	return nil
 }
 
-func (ws *WebSocketServer) handleSurrenderMessage(msg ClientMessage) error {
+func (ws *WebSocketServer) handleSurrenderMessage(c *client, msg ClientMessage) error {
 	var payload map[string]string
-	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
-		return fmt.Errorf("invalid surrender payload: %w", err)
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return fmt.Errorf("invalid surrender payload: %w", err)
+		}
 	}
-
-	playerId := payload["playerId"]
-	if playerId == "" {
-		return fmt.Errorf("playerId is required")
+	playerId, err := c.commandPlayer(payload["playerId"])
+	if err != nil {
+		return err
 	}
 
 	surrenderCmd := game.SurrenderCommand{
@@ -204,11 +424,28 @@ func (ws *WebSocketServer) handleSurrenderMessage(msg ClientMessage) error {
 }
 
 func (ws *WebSocketServer) handleCreateGameMessage(msg ClientMessage) error {
-	createGameCmd := map[string]interface{}{
-		"type":   "createGame",
-		"gameId": msg.GameId,
-		"payload": map[string]interface{}{
-			"gameMode": "classic_1v1", // 默认模式，可以从payload中解析
+	var payload CreateGamePayload
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return fmt.Errorf("invalid create game payload: %w", err)
+		}
+	}
+
+	gameMode := game.Classic1v1
+	if payload.GameMode != "" {
+		mode, exists := game.GetGameMode(payload.GameMode)
+		if !exists {
+			return fmt.Errorf("unknown game mode: %s", payload.GameMode)
+		}
+		gameMode = mode
+	}
+
+	createGameCmd := lobby.LobbyCommand{
+		Type:   "createGame",
+		GameId: msg.GameId,
+		Payload: lobby.CreateGamePayload{
+			GameMode: gameMode,
+			MapId:    payload.MapId,
 		},
 	}
 
@@ -224,7 +461,3 @@ func (ws *WebSocketServer) StartServer(addr string) error {
 	slog.Info("websocket server starting", "addr", addr)
 	return http.ListenAndServe(addr, nil)
 }
-func (ws *WebSocketServer) StopServer() error {
-	slog.Info("websocket server stopped")
-	return nil
-}
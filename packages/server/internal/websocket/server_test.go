@@ -0,0 +1,93 @@
+package websocket
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"server/internal/queue"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func dialTestServer(t *testing.T, limits Limits) *websocket.Conn {
+	t.Helper()
+	server := NewWebSocketServer(queue.NewInMemoryQueue(), limits)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set("X-User-ID", "user_1")
+		server.HandleWebSocket(w, r)
+	}))
+	t.Cleanup(httpServer.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readUntilClose 读取直到连接关闭，返回关闭码
+func readUntilClose(t *testing.T, conn *websocket.Conn) int {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) {
+				return closeErr.Code
+			}
+			t.Fatalf("Expected close frame, got %v", err)
+		}
+	}
+}
+
+func TestWebSocket_FloodDisconnect(t *testing.T) {
+	conn := dialTestServer(t, Limits{
+		MessageRPS:    1,
+		MessageBurst:  2,
+		MaxViolations: 5,
+	})
+
+	for i := 0; i < 10; i++ {
+		msg := `{"type": "move", "gameId": "g1", "payload": {"playerId": "user_1", "direction": "up"}}`
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			break
+		}
+	}
+
+	if code := readUntilClose(t, conn); code != websocket.ClosePolicyViolation {
+		t.Errorf("Expected close code %d, got %d", websocket.ClosePolicyViolation, code)
+	}
+}
+
+func TestWebSocket_MaxMessageSize(t *testing.T) {
+	conn := dialTestServer(t, Limits{MaxMessageSize: 64})
+
+	msg := `{"type": "createGame", "gameId": "` + strings.Repeat("x", 128) + `"}`
+	conn.WriteMessage(websocket.TextMessage, []byte(msg))
+
+	if code := readUntilClose(t, conn); code != websocket.CloseMessageTooBig {
+		t.Errorf("Expected close code %d, got %d", websocket.CloseMessageTooBig, code)
+	}
+}
+
+func TestPlayerLimiter_SharedAcrossConnections(t *testing.T) {
+	limiter := newPlayerLimiter(1, 2)
+	now := time.Unix(0, 0)
+
+	if !limiter.allow("user_1", now) || !limiter.allow("user_1", now) {
+		t.Fatal("Expected burst to be allowed")
+	}
+	if limiter.allow("user_1", now) {
+		t.Error("Expected player bucket to be exhausted")
+	}
+	if !limiter.allow("user_2", now) {
+		t.Error("Expected other players to be unaffected")
+	}
+	if !limiter.allow("user_1", now.Add(time.Second)) {
+		t.Error("Expected player bucket to refill")
+	}
+}
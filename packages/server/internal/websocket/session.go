@@ -0,0 +1,166 @@
+package websocket
+
+import (
+	"fmt"
+	"log/slog"
+	"server/internal/queue"
+	"slices"
+	"time"
+)
+
+// session 同一用户的所有连接。最近建立的连接负责发送指令，所有连接都会收到对局事件；
+// 最后一个连接关闭后会话保留 ReconnectWindow，期间同一用户的新连接自动重连会话中的对局。
+type session struct {
+	key   string
+	conns []*client
+	// games 会话加入的对局
+	games map[string]*gameSubscription
+	// lobbyChat 大厅频道的订阅，会话有连接时存在
+	lobbyChat <-chan queue.Event
+	// until 没有连接时会话的保留期限
+	until time.Time
+}
+
+// sessionKey 未认证的连接各自使用独立的会话
+func sessionKey(c *client) string {
+	if c.playerId == "" {
+		return fmt.Sprintf("conn-%p", c)
+	}
+	return c.playerId
+}
+
+// connect 将连接加入用户会话，通知被接管的旧连接，并在会话断线保留期内向其对局发布重连指令
+func (ws *WebSocketServer) connect(c *client) {
+	now := time.Now()
+	key := sessionKey(c)
+
+	ws.sessionsMu.Lock()
+	ws.pruneSessions(now)
+	s, exists := ws.sessions[key]
+	if !exists {
+		s = &session{key: key, games: make(map[string]*gameSubscription)}
+		ws.sessions[key] = s
+	}
+
+	var previous *client
+	if len(s.conns) > 0 {
+		previous = s.conns[len(s.conns)-1]
+	}
+	var reconnect []*gameSubscription
+	if len(s.conns) == 0 {
+		s.lobbyChat = ws.queue.Subscribe(lobbyChatTopic)
+		go ws.forward(s, "lobby", s.lobbyChat, nil)
+		for _, sub := range s.games {
+			ws.startForwarding(s, sub)
+			reconnect = append(reconnect, sub)
+		}
+	}
+	s.conns = append(s.conns, c)
+	c.session = s
+	ws.sessionsMu.Unlock()
+
+	if previous != nil {
+		previous.writeJSON(map[string]string{
+			"type":    "takenOver",
+			"message": "another connection took over this session",
+		})
+		slog.Info("websocket session taken over", "user", c.playerId, "connections", len(s.conns))
+	}
+
+	for _, sub := range reconnect {
+		ws.queue.Publish(fmt.Sprintf("%s/commands", sub.gameId), sub.joinCommand())
+	}
+	if len(reconnect) > 0 {
+		slog.Info("player reconnected to games", "user", c.playerId, "games", len(reconnect))
+	}
+}
+
+// disconnect 将连接移出会话。最后一个连接关闭时向会话的对局发布断线指令，并按 ReconnectWindow 保留会话
+func (ws *WebSocketServer) disconnect(c *client) {
+	s := c.session
+	if s == nil {
+		return
+	}
+
+	ws.sessionsMu.Lock()
+	s.conns = slices.DeleteFunc(s.conns, func(conn *client) bool { return conn == c })
+	if len(s.conns) > 0 {
+		ws.sessionsMu.Unlock()
+		return
+	}
+
+	ws.queue.Unsubscribe(lobbyChatTopic, s.lobbyChat)
+	s.lobbyChat = nil
+	disconnected := make([]*gameSubscription, 0, len(s.games))
+	for _, sub := range s.games {
+		ws.stopForwarding(sub)
+		disconnected = append(disconnected, sub)
+	}
+	if c.playerId != "" && ws.limits.ReconnectWindow > 0 && len(s.games) > 0 {
+		s.until = time.Now().Add(ws.limits.ReconnectWindow)
+	} else {
+		delete(ws.sessions, s.key)
+	}
+	ws.sessionsMu.Unlock()
+
+	for _, sub := range disconnected {
+		ws.queue.Publish(fmt.Sprintf("%s/commands", sub.gameId), sub.leaveCommand())
+	}
+}
+
+// isActive 只有会话中最近建立的连接可以发送指令
+func (ws *WebSocketServer) isActive(c *client) bool {
+	ws.sessionsMu.Lock()
+	defer ws.sessionsMu.Unlock()
+	return c.session != nil && c.session.conns[len(c.session.conns)-1] == c
+}
+
+// joinGame 会话订阅对局事件，应在向对局发布指令之前调用，避免错过回复。
+// 广播与聊天在对局确认加入后才转发，见 forwardAccepted
+func (ws *WebSocketServer) joinGame(c *client, sub *gameSubscription) {
+	ws.sessionsMu.Lock()
+	defer ws.sessionsMu.Unlock()
+
+	s := c.session
+	if existing, exists := s.games[sub.gameId]; exists {
+		if existing.playerId == sub.playerId && existing.spectator == sub.spectator {
+			return
+		}
+		ws.stopForwarding(existing)
+	}
+
+	if sub.accepted == nil {
+		sub.accepted = make(chan struct{})
+	}
+	s.games[sub.gameId] = sub
+	ws.startForwarding(s, sub)
+}
+
+func (ws *WebSocketServer) leaveGame(c *client, gameId string) {
+	ws.sessionsMu.Lock()
+	defer ws.sessionsMu.Unlock()
+
+	s := c.session
+	if sub, exists := s.games[gameId]; exists {
+		ws.stopForwarding(sub)
+		delete(s.games, gameId)
+	}
+}
+
+// isSpectating 会话是否以观战者身份订阅了对局
+func (ws *WebSocketServer) isSpectating(c *client, gameId string) bool {
+	ws.sessionsMu.Lock()
+	defer ws.sessionsMu.Unlock()
+
+	sub, exists := c.session.games[gameId]
+	return exists && sub.spectator
+}
+
+// pruneSessions 清理超过保留期限且没有连接的会话，调用方需持有 sessionsMu
+func (ws *WebSocketServer) pruneSessions(now time.Time) {
+	for key, s := range ws.sessions {
+		if len(s.conns) == 0 && now.After(s.until) {
+			delete(ws.sessions, key)
+		}
+	}
+}
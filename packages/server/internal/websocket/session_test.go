@@ -0,0 +1,167 @@
+package websocket
+
+import (
+	"server/internal/game"
+	"server/internal/queue"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// readUntilType 读取消息直到出现指定类型
+func readUntilType(t *testing.T, conn *websocket.Conn, msgType string) map[string]interface{} {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var msg map[string]interface{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("Expected %s message, got %v", msgType, err)
+		}
+		if msg["type"] == msgType {
+			return msg
+		}
+	}
+}
+
+func waitForConns(t *testing.T, server *WebSocketServer, user string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		server.sessionsMu.Lock()
+		count := 0
+		if s, ok := server.sessions[user]; ok {
+			count = len(s.conns)
+		}
+		server.sessionsMu.Unlock()
+		if count == n {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Expected %d connections for %s", n, user)
+}
+
+func dialURL(t *testing.T, url string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	return conn
+}
+
+func TestWebSocket_TabTakeover(t *testing.T) {
+	q := queue.NewInMemoryQueue()
+	commands := q.Subscribe("game-1/commands")
+	server, url := startHeartbeatServer(t, q, Limits{ReconnectWindow: time.Minute})
+
+	older := dialURL(t, url)
+	defer older.Close()
+	older.WriteJSON(map[string]interface{}{
+		"type":    "join",
+		"gameId":  "game-1",
+		"payload": map[string]string{"playerId": "user_1"},
+	})
+	expectCommand[game.JoinCommand](t, commands)
+	q.Publish("game-1/player/user_1", game.JoinAckEvent{PlayerId: "user_1"})
+
+	newer := dialURL(t, url)
+	defer newer.Close()
+	readUntilType(t, older, "takenOver")
+
+	t.Run("only_newest_sends_commands", func(t *testing.T) {
+		older.WriteJSON(map[string]interface{}{
+			"type":    "surrender",
+			"gameId":  "game-1",
+			"payload": map[string]string{"playerId": "user_1"},
+		})
+		msg := readUntilType(t, older, "error")
+		if msg["error"] != "session taken over by another connection" {
+			t.Errorf("Expected takeover error, got %v", msg["error"])
+		}
+
+		newer.WriteJSON(map[string]interface{}{
+			"type":    "surrender",
+			"gameId":  "game-1",
+			"payload": map[string]string{"playerId": "user_1"},
+		})
+		expectCommand[game.SurrenderCommand](t, commands)
+	})
+
+	t.Run("all_tabs_receive_events", func(t *testing.T) {
+		q.Publish("game-1/broadcast", game.TurnStartedEvent{TurnNumber: 2})
+		readUntilType(t, older, "game.turnStarted")
+		readUntilType(t, newer, "game.turnStarted")
+	})
+
+	t.Run("last_close_disconnects", func(t *testing.T) {
+		newer.Close()
+		waitForConns(t, server, "user_1", 1)
+		select {
+		case event := <-commands:
+			t.Fatalf("Expected no command while a tab is open, got %T", event)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		older.Close()
+		if cmd := expectCommand[game.DisconnectCommand](t, commands); cmd.PlayerId != "user_1" {
+			t.Errorf("Expected disconnect for p1, got %s", cmd.PlayerId)
+		}
+	})
+}
+
+func TestWebSocket_NewestCloseRestoresOlder(t *testing.T) {
+	q := queue.NewInMemoryQueue()
+	commands := q.Subscribe("game-1/commands")
+	server, url := startHeartbeatServer(t, q, Limits{})
+
+	older := dialURL(t, url)
+	defer older.Close()
+	waitForConns(t, server, "user_1", 1)
+	newer := dialURL(t, url)
+	readUntilType(t, older, "takenOver")
+	newer.Close()
+	waitForConns(t, server, "user_1", 1)
+
+	older.WriteJSON(map[string]interface{}{
+		"type":    "join",
+		"gameId":  "game-1",
+		"payload": map[string]string{"playerId": "user_1"},
+	})
+	expectCommand[game.JoinCommand](t, commands)
+}
+
+// 指令的玩家总是连接认证的用户，不能替其他玩家移动或投降
+func TestWebSocket_CommandsUseAuthenticatedUser(t *testing.T) {
+	q := queue.NewInMemoryQueue()
+	commands := q.Subscribe("game-1/commands")
+	_, url := startHeartbeatServer(t, q, Limits{})
+
+	conn := dialURL(t, url)
+	defer conn.Close()
+
+	conn.WriteJSON(map[string]interface{}{
+		"type":    "move",
+		"gameId":  "game-1",
+		"payload": map[string]interface{}{"from": map[string]int{"x": 1, "y": 1}, "direction": "up", "troops": 1},
+	})
+	if cmd := expectCommand[game.MoveCommand](t, commands); cmd.PlayerId != "user_1" {
+		t.Errorf("Expected move from user_1, got %s", cmd.PlayerId)
+	}
+
+	conn.WriteJSON(map[string]interface{}{
+		"type":    "surrender",
+		"gameId":  "game-1",
+		"payload": map[string]string{"playerId": "p2"},
+	})
+	if msg := readUntilType(t, conn, "error"); !strings.Contains(msg["error"].(string), ErrPlayerMismatch.Error()) {
+		t.Errorf("Expected player mismatch error, got %v", msg)
+	}
+	select {
+	case event := <-commands:
+		t.Errorf("Expected no command for another player, got %T", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
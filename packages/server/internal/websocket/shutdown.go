@@ -0,0 +1,105 @@
+package websocket
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// client 单个 WebSocket 连接。gorilla 连接不支持并发写，写入需持有 writeMu；
+// WriteControl 与 Close 可以并发调用。
+type client struct {
+	conn     *websocket.Conn
+	playerId string
+	// username 大厅聊天中显示的发送者名称
+	username string
+	writeMu  sync.Mutex
+
+	// session 连接所属的用户会话，由 sessionsMu 保护
+	session *session
+
+	// 以下字段只由读循环访问
+	lastMessage time.Time
+	lastPong    time.Time
+}
+
+func (c *client) writeJSON(v interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteJSON(v)
+}
+
+// notifyRestart 发送 serverRestart 消息，并以 1012 (Service Restart) 关闭连接，客户端应稍后重连
+func (c *client) notifyRestart() {
+	c.writeJSON(map[string]string{
+		"type":    "serverRestart",
+		"message": "server is restarting",
+	})
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server restarting")
+	c.conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+}
+
+// register 停机开始后返回 false，此时连接应立即关闭
+func (ws *WebSocketServer) register(c *client) bool {
+	ws.clientsMu.Lock()
+	defer ws.clientsMu.Unlock()
+
+	if ws.draining {
+		return false
+	}
+	ws.clients[c] = struct{}{}
+	ws.handlers.Add(1)
+	return true
+}
+
+func (ws *WebSocketServer) unregister(c *client) {
+	ws.clientsMu.Lock()
+	delete(ws.clients, c)
+	ws.clientsMu.Unlock()
+	ws.handlers.Done()
+}
+
+func (ws *WebSocketServer) isDraining() bool {
+	ws.clientsMu.Lock()
+	defer ws.clientsMu.Unlock()
+	return ws.draining
+}
+
+// Shutdown 拒绝新连接，通知现有客户端服务器重启并等待连接关闭。
+// ctx 到期时强制关闭剩余连接并返回 ctx 的错误。
+func (ws *WebSocketServer) Shutdown(ctx context.Context) error {
+	ws.clientsMu.Lock()
+	ws.draining = true
+	clients := make([]*client, 0, len(ws.clients))
+	for c := range ws.clients {
+		clients = append(clients, c)
+	}
+	ws.clientsMu.Unlock()
+
+	slog.Info("closing websocket connections", "count", len(clients))
+	for _, c := range clients {
+		c.notifyRestart()
+	}
+
+	// 客户端回应关闭帧后读循环退出，处理器随之返回
+	done := make(chan struct{})
+	go func() {
+		ws.handlers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		slog.Info("websocket server stopped")
+		return nil
+	case <-ctx.Done():
+		for _, c := range clients {
+			c.conn.Close()
+		}
+		slog.Warn("websocket connections force closed", "error", ctx.Err())
+		return ctx.Err()
+	}
+}
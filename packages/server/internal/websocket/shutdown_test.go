@@ -0,0 +1,94 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"server/internal/queue"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func waitForClients(t *testing.T, server *WebSocketServer, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		server.clientsMu.Lock()
+		count := len(server.clients)
+		server.clientsMu.Unlock()
+		if count == n {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Expected %d registered clients", n)
+}
+
+func TestWebSocket_Shutdown(t *testing.T) {
+	server := NewWebSocketServer(queue.NewInMemoryQueue(), Limits{})
+	httpServer := httptest.NewServer(http.HandlerFunc(server.HandleWebSocket))
+	defer httpServer.Close()
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	waitForClients(t, server, 1)
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		shutdownErr <- server.Shutdown(ctx)
+	}()
+
+	var msg map[string]string
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("Expected serverRestart message, got %v", err)
+	}
+	if msg["type"] != "serverRestart" {
+		t.Errorf("Expected serverRestart message, got %v", msg)
+	}
+	if code := readUntilClose(t, conn); code != websocket.CloseServiceRestart {
+		t.Errorf("Expected close code %d, got %d", websocket.CloseServiceRestart, code)
+	}
+
+	if err := <-shutdownErr; err != nil {
+		t.Errorf("Expected clean shutdown, got %v", err)
+	}
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil {
+		t.Fatal("Expected dial to fail after shutdown")
+	}
+	if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 after shutdown, got %v", resp)
+	}
+}
+
+func TestWebSocket_ShutdownTimeout(t *testing.T) {
+	server := NewWebSocketServer(queue.NewInMemoryQueue(), Limits{})
+	httpServer := httptest.NewServer(http.HandlerFunc(server.HandleWebSocket))
+	defer httpServer.Close()
+
+	// 不读取消息的客户端不会回应关闭帧，需要在期限到达后强制关闭
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	waitForClients(t, server, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	waitForClients(t, server, 0)
+}
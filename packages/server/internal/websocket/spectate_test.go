@@ -0,0 +1,126 @@
+package websocket
+
+import (
+	"encoding/json"
+	"server/internal/game"
+	"server/internal/queue"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWebSocket_Spectate(t *testing.T) {
+	q := queue.NewInMemoryQueue()
+	commands := q.Subscribe("game-1/commands")
+	_, url := startHeartbeatServer(t, q, Limits{})
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	conn.WriteJSON(map[string]interface{}{
+		"type":    "spectate",
+		"gameId":  "game-1",
+		"payload": map[string]interface{}{"playerId": "user_1", "playerName": "Watcher"},
+	})
+	cmd := expectCommand[game.SpectateCommand](t, commands)
+	if cmd.PlayerId != "user_1" || cmd.SpectatorName != "Watcher" {
+		t.Errorf("Expected spectate for user_1, got %+v", cmd)
+	}
+	q.Publish("game-1/player/user_1", game.JoinAckEvent{PlayerId: "user_1"})
+
+	// 观战者只收到延迟的观战主题，不收到实时广播
+	q.Publish("game-1/broadcast", game.TurnStartedEvent{BroadcastEvent: game.BroadcastEvent{Seq: 3}, TurnNumber: 3})
+	q.Publish("game-1/spectate", game.TurnStartedEvent{BroadcastEvent: game.BroadcastEvent{Seq: 1}, TurnNumber: 1})
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg ServerMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("Expected forwarded spectator event, got %v", err)
+	}
+	if msg.Type != "game.turnStarted" {
+		t.Fatalf("Expected game.turnStarted, got %+v", msg)
+	}
+	var turn game.TurnStartedEvent
+	if err := json.Unmarshal(msg.Data, &turn); err != nil || turn.Seq != 1 {
+		t.Errorf("Expected spectator event with seq 1, got %s", msg.Data)
+	}
+
+	conn.WriteJSON(map[string]interface{}{
+		"type":    "leave",
+		"gameId":  "game-1",
+		"payload": map[string]interface{}{"playerId": "user_1"},
+	})
+	expectCommand[game.StopSpectatingCommand](t, commands)
+}
+
+func TestWebSocket_SpectatorDisconnectStopsSpectating(t *testing.T) {
+	q := queue.NewInMemoryQueue()
+	commands := q.Subscribe("game-1/commands")
+	_, url := startHeartbeatServer(t, q, Limits{ReconnectWindow: time.Minute})
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	conn.WriteJSON(map[string]interface{}{
+		"type":    "spectate",
+		"gameId":  "game-1",
+		"payload": map[string]interface{}{"playerId": "user_1"},
+	})
+	expectCommand[game.SpectateCommand](t, commands)
+
+	// 观战者断线时直接退出观战，重连后重新加入
+	conn.Close()
+	expectCommand[game.StopSpectatingCommand](t, commands)
+
+	conn, _, err = websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to redial: %v", err)
+	}
+	defer conn.Close()
+	expectCommand[game.SpectateCommand](t, commands)
+}
+
+// 对局确认之前不转发实时广播，加入被拒绝时丢弃缓存并移除订阅，不能借 join 绕过观战延迟
+func TestWebSocket_JoinForwardsOnlyAfterAck(t *testing.T) {
+	q := queue.NewInMemoryQueue()
+	commands := q.Subscribe("game-1/commands")
+	server, url := startHeartbeatServer(t, q, Limits{})
+
+	conn := dialURL(t, url)
+	defer conn.Close()
+	conn.WriteJSON(map[string]interface{}{"type": "join", "gameId": "game-1", "payload": map[string]interface{}{}})
+	expectCommand[game.JoinCommand](t, commands)
+
+	q.Publish("game-1/broadcast", game.TurnStartedEvent{TurnNumber: 7})
+	q.Publish("game-1/player/user_1", game.PlayerErrorEvent{PlayerId: "user_1", Error: "game already started"})
+	q.Publish("game-1/player/user_1", game.JoinAckEvent{PlayerId: "user_1", Error: "game already started"})
+
+	if msg := readUntilType(t, conn, "game.playerError"); msg["gameId"] != "game-1" {
+		t.Errorf("Expected join error, got %v", msg)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		server.sessionsMu.Lock()
+		_, subscribed := server.sessions["user_1"].games["game-1"]
+		server.sessionsMu.Unlock()
+		if !subscribed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected rejected join to remove the subscription")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	q.Publish("game-1/broadcast", game.TurnStartedEvent{TurnNumber: 8})
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	var msg ServerMessage
+	if err := conn.ReadJSON(&msg); err == nil {
+		t.Errorf("Expected no broadcast after rejected join, got %+v", msg)
+	}
+}
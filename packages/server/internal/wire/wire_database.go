@@ -8,34 +8,44 @@ package wire
 import (
 	"server/internal/auth"
 	"server/internal/config"
+	gamemap "server/internal/game/map"
 	"server/internal/lobby"
+	"server/internal/market"
 	"server/internal/queue"
-	"server/internal/websocket"
 
 	"github.com/google/wire"
 )
 
 func InitializeApplicationWithDatabase(cfg *config.Config) (*Application, error) {
 	wire.Build(
-		queue.NewInMemoryQueue,
-		wire.Bind(new(queue.Queue), new(*queue.InMemoryQueue)),
+		provideQueue,
 
 		auth.NewDatabaseUserRepository,
 		wire.Bind(new(auth.UserRepository), new(*auth.DatabaseUserRepository)),
 
+		auth.NewDatabaseTokenRepository,
+		wire.Bind(new(auth.TokenRepository), new(*auth.DatabaseTokenRepository)),
+
 		provideJWTTokenService,
 		wire.Bind(new(auth.TokenService), new(*auth.JWTTokenService)),
 
 		auth.NewArgon2PasswordService,
 		wire.Bind(new(auth.PasswordService), new(*auth.Argon2PasswordService)),
 
-		auth.NewAuthService,
+		provideAuthService,
 
 		provideCacheService,
 
-		lobby.NewLobby,
+		market.NewDatabaseMapRepository,
+		wire.Bind(new(market.MapRepository), new(*market.DatabaseMapRepository)),
+		market.NewMarketService,
+
+		provideMapManager,
+		wire.Bind(new(gamemap.MapManager), new(*gamemap.DefaultMapManager)),
+
+		provideLobby,
 
-		websocket.NewWebSocketServer,
+		provideWebSocketServer,
 
 		wire.Struct(new(Application), "*"),
 	)
@@ -7,11 +7,20 @@
 package wire
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"log/slog"
+	"os"
 	"server/internal/auth"
 	"server/internal/cache"
 	"server/internal/config"
+	"server/internal/game"
 	"server/internal/game/map"
 	"server/internal/lobby"
+	"server/internal/market"
 	"server/internal/queue"
 	"server/internal/websocket"
 	"time"
@@ -21,14 +30,23 @@ import (
 
 func InitializeApplication(cfg *config.Config) (*Application, error) {
 	inMemoryUserRepository := auth.NewInMemoryUserRepository()
-	jwtTokenService := provideJWTTokenService(cfg)
+	cacheService, err := provideCacheService(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tokenRepository := provideTokenRepository(cfg, cacheService)
+	jwtTokenService := provideJWTTokenService(cfg, tokenRepository)
 	argon2PasswordService := auth.NewArgon2PasswordService()
-	authService := auth.NewAuthService(inMemoryUserRepository, jwtTokenService, argon2PasswordService)
-	inMemoryQueue := queue.NewInMemoryQueue()
-	defaultMapManager := provideMapManager()
-	lobbyLobby := lobby.NewLobby(inMemoryQueue, defaultMapManager)
-	webSocketServer := websocket.NewWebSocketServer(inMemoryQueue)
-	cacheService := provideCacheService(cfg)
+	authService := provideAuthService(cfg, inMemoryUserRepository, jwtTokenService, argon2PasswordService, tokenRepository, cacheService)
+	queueQueue, err := provideQueue(cfg)
+	if err != nil {
+		return nil, err
+	}
+	inMemoryMapRepository := market.NewInMemoryMapRepository()
+	marketService := market.NewMarketService(inMemoryMapRepository)
+	defaultMapManager := provideMapManager(cfg, marketService)
+	lobbyLobby := provideLobby(cfg, queueQueue, defaultMapManager)
+	webSocketServer := provideWebSocketServer(cfg, queueQueue, authService)
 	application := &Application{
 		Config:      cfg,
 		AuthService: authService,
@@ -36,6 +54,8 @@ func InitializeApplication(cfg *config.Config) (*Application, error) {
 		WSServer:    webSocketServer,
 		Cache:       cacheService,
 		MapManager:  defaultMapManager,
+		Market:      marketService,
+		Queue:       queueQueue,
 	}
 	return application, nil
 }
@@ -49,17 +69,146 @@ type Application struct {
 	WSServer    *websocket.WebSocketServer
 	Cache       *cache.CacheService
 	MapManager  gamemap.MapManager
+	Market      *market.MarketService
+	Queue       queue.Queue
+}
+
+// provideTokenRepository 缓存使用 Redis 时令牌保存在 Redis 中，重启后刷新令牌与吊销记录仍然有效；
+// 进程内缓存会按内存预算淘汰数据，不适合保存吊销记录，此时使用内存仓库
+func provideTokenRepository(cfg *config.Config, cacheService *cache.CacheService) auth.TokenRepository {
+	if cfg.Cache.Type == "redis" {
+		return auth.NewCacheTokenRepository(cacheService, time.Duration(cfg.Auth.RefreshExpiry))
+	}
+	slog.Warn("token revocation is kept in memory and will not survive restarts, set cache.type to redis to persist it")
+	return auth.NewInMemoryTokenRepository()
+}
+
+func provideJWTTokenService(cfg *config.Config, tokenRepo auth.TokenRepository) *auth.JWTTokenService {
+	return auth.NewJWTTokenService(cfg.Auth.JWTSecret, time.Duration(cfg.Auth.TokenExpiry), tokenRepo)
+}
+
+func provideAuthService(cfg *config.Config, userRepo auth.UserRepository, tokenSvc auth.TokenService, passwordSvc auth.PasswordService, tokenRepo auth.TokenRepository, cacheService *cache.CacheService) *auth.AuthService {
+	limiter := auth.NewLoginLimiter(cfg.Auth.RateLimitRPS, cfg.Auth.RateLimitBurst, cfg.Auth.TrustedProxies)
+	return auth.NewAuthService(userRepo, tokenSvc, passwordSvc, tokenRepo, limiter, cacheService,
+		time.Duration(cfg.Auth.RefreshExpiry), time.Duration(cfg.Auth.WSTicketTTL), cfg.Auth.AdminUsers)
+}
+
+func provideCacheService(cfg *config.Config) (*cache.CacheService, error) {
+	var backend cache.Cache
+	if cfg.Cache.Type == "redis" {
+		client, err := newRedisClient(cfg.Redis)
+		if err != nil {
+			return nil, err
+		}
+		backend = cache.NewRedisCache(client, cfg.Cache.Prefix)
+	} else {
+		inMemoryCache, err := cache.NewInMemoryCacheWithOptions(cache.Options{
+			CleanupInterval: time.Duration(cfg.Cache.CleanupInterval),
+			MaxMemoryBytes:  int64(cfg.Cache.MaxMemoryMB) << 20,
+			EvictionPolicy:  cache.EvictionPolicy(cfg.Cache.EvictionPolicy),
+		})
+		if err != nil {
+			return nil, err
+		}
+		backend = inMemoryCache
+	}
+
+	return cache.NewCacheService(backend, cache.TTLs{
+		Default:    time.Duration(cfg.Cache.DefaultTTL),
+		Session:    time.Duration(cfg.Cache.SessionTTL),
+		GameState:  time.Duration(cfg.Cache.GameStateTTL),
+		Connection: time.Duration(cfg.Cache.ConnectionTTL),
+	}), nil
+}
+
+func provideLobby(cfg *config.Config, q queue.Queue, mapManager gamemap.MapManager) *lobby.Lobby {
+	return lobby.NewLobbyWithOptions(q, mapManager, game.Options{
+		ReconnectTimeout:        time.Duration(cfg.Game.ReconnectTimeout),
+		DisconnectCheckInterval: time.Second,
+		HistorySize:             cfg.Game.EventHistorySize,
+		MaxSpectators:           cfg.Game.MaxSpectators,
+		SpectatorDelay:          time.Duration(cfg.Game.SpectatorDelay),
+	})
+}
+
+func provideWebSocketServer(cfg *config.Config, q queue.Queue, authService *auth.AuthService) *websocket.WebSocketServer {
+	return websocket.NewWebSocketServerWithModeration(q, websocket.Limits{
+		MaxMessageSize:  cfg.WebSocket.MaxMessageSize,
+		ReadTimeout:     time.Duration(cfg.WebSocket.ReadTimeout),
+		MessageRPS:      cfg.WebSocket.MessageRPS,
+		MessageBurst:    cfg.WebSocket.MessageBurst,
+		PlayerRPS:       cfg.WebSocket.PlayerRPS,
+		PlayerBurst:     cfg.WebSocket.PlayerBurst,
+		MaxViolations:   cfg.WebSocket.MaxViolations,
+		PingInterval:    time.Duration(cfg.Game.HeartbeatInterval),
+		ReconnectWindow: time.Duration(cfg.Game.ReconnectTimeout),
+		ChatMaxLength:   cfg.WebSocket.ChatMaxLength,
+		ChatRPS:         cfg.WebSocket.ChatRPS,
+		ChatBurst:       cfg.WebSocket.ChatBurst,
+	}, websocket.Moderation{
+		Filter:      websocket.NewWordFilter(cfg.WebSocket.ChatBlockedWords),
+		Permissions: authService,
+	})
+}
+
+func provideMapManager(cfg *config.Config, marketService *market.MarketService) *gamemap.DefaultMapManager {
+	manager := gamemap.NewMapManager()
+	manager.RegisterProvider(gamemap.NewFileProvider(cfg.Game.MapsDir))
+	manager.RegisterProvider(gamemap.NewDatabaseProvider(marketService))
+	return manager
+}
+
+func provideQueue(cfg *config.Config) (queue.Queue, error) {
+	if cfg.Queue.Type != "redis" {
+		return newInMemoryQueue(cfg.Queue.Backpressure), nil
+	}
+
+	client, err := newRedisClient(cfg.Redis)
+	if err != nil {
+		return nil, err
+	}
+
+	instanceID := cfg.Queue.InstanceID
+	if instanceID == "" {
+		instanceID = generateInstanceID()
+	}
+	return queue.NewRedisQueue(client, cfg.Queue.Prefix, instanceID, time.Duration(cfg.Queue.ClaimTTL)), nil
 }
 
-func provideJWTTokenService(cfg *config.Config) *auth.JWTTokenService {
-	return auth.NewJWTTokenService(cfg.Auth.JWTSecret)
+func newRedisClient(cfg config.RedisConfig) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	return client, nil
 }
 
-func provideCacheService(cfg *config.Config) *cache.CacheService {
-	inMemoryCache := cache.NewInMemoryCache(time.Duration(cfg.Cache.CleanupInterval))
-	return cache.NewCacheService(inMemoryCache)
+func newInMemoryQueue(cfg config.BackpressureConfig) *queue.InMemoryQueue {
+	return queue.NewInMemoryQueueWithOptions(queue.Options{
+		Default: queue.SubscribeOptions{
+			BufferSize:   cfg.BufferSize,
+			Policy:       queue.Policy(cfg.Policy),
+			BlockTimeout: time.Duration(cfg.BlockTimeout),
+		},
+		Command: queue.SubscribeOptions{
+			BufferSize:   cfg.BufferSize,
+			Policy:       queue.Policy(cfg.CommandPolicy),
+			BlockTimeout: time.Duration(cfg.CommandBlockTimeout),
+		},
+	})
 }
 
-func provideMapManager() *gamemap.DefaultMapManager {
-	return gamemap.NewMapManager()
+func generateInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "instance"
+	}
+	buf := make([]byte, 4)
+	rand.Read(buf)
+	return hostname + "-" + hex.EncodeToString(buf)
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
@@ -11,6 +12,8 @@ import (
 	"time"
 
 	"server/internal/config"
+	"server/internal/metrics"
+	"server/internal/queue"
 	"server/internal/wire"
 )
 
@@ -41,7 +44,8 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	if err := startServices(app, cfg); err != nil {
+	server, err := startServices(app, cfg)
+	if err != nil {
 		slog.Error("failed to start services", "error", err)
 		os.Exit(1)
 	}
@@ -54,41 +58,79 @@ func main() {
 	}
 
 	slog.Info("shutting down server")
-	stopServices(app)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout))
+	defer shutdownCancel()
+	stopServices(shutdownCtx, app, server)
 	slog.Info("server shutdown complete")
 }
 
-func startServices(app *wire.Application, cfg *config.Config) error {
-	go func() {
-		if err := app.Lobby.Start(); err != nil {
-			slog.Error("lobby service failed to start", "error", err)
-		}
-	}()
+func startServices(app *wire.Application, cfg *config.Config) (*http.Server, error) {
+	slog.Info("server role", "role", cfg.Server.Role, "queue", cfg.Queue.Type)
+
+	if cfg.RunsGames() {
+		go func() {
+			if err := app.Lobby.Start(); err != nil {
+				slog.Error("lobby service failed to start", "error", err)
+			}
+		}()
+	}
 
 	setupHTTPRoutes(app)
 
+	server := &http.Server{
+		Addr:         cfg.GetServerAddr(),
+		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout),
+		WriteTimeout: time.Duration(cfg.Server.WriteTimeout),
+	}
+
 	go func() {
 		slog.Info("starting HTTP server", "addr", cfg.GetServerAddr())
-
-		server := &http.Server{
-			Addr:         cfg.GetServerAddr(),
-			ReadTimeout:  time.Duration(cfg.Server.ReadTimeout),
-			WriteTimeout: time.Duration(cfg.Server.WriteTimeout),
-		}
-
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			slog.Error("HTTP server failed", "error", err)
 		}
 	}()
 
-	return nil
+	return server, nil
 }
 
 func setupHTTPRoutes(app *wire.Application) {
 	http.HandleFunc("/api/auth/register", app.AuthService.RegisterHandler)
 	http.HandleFunc("/api/auth/login", app.AuthService.LoginHandler)
-	http.HandleFunc("/api/game/ws", app.AuthService.AuthMiddleware(app.WSServer.HandleWebSocket))
+	http.HandleFunc("/api/auth/guest", app.AuthService.GuestHandler)
+	http.HandleFunc("/api/auth/upgrade", app.AuthService.UpgradeHandler)
+	http.HandleFunc("/api/auth/refresh", app.AuthService.RefreshHandler)
+	http.HandleFunc("/api/auth/logout", app.AuthService.LogoutHandler)
+	http.HandleFunc("GET /api/users/me", app.AuthService.AuthMiddleware(app.AuthService.MeHandler))
+	http.HandleFunc("PATCH /api/users/me", app.AuthService.AuthMiddleware(app.AuthService.MeHandler))
+	http.HandleFunc("DELETE /api/users/me", app.AuthService.DeleteAccountHandler)
+	http.HandleFunc("POST /api/users/me/password", app.AuthService.ChangePasswordHandler)
+	http.HandleFunc("GET /api/users/{id}", app.AuthService.PublicProfileHandler)
+	http.HandleFunc("/api/auth/ws-ticket", app.AuthService.AuthMiddleware(app.AuthService.WSTicketHandler))
+	if app.Config.ServesWebSocket() {
+		http.HandleFunc("/api/game/ws", app.AuthService.WSAuthMiddleware(app.WSServer.HandleWebSocket))
+	}
+	http.HandleFunc("/api/maps", mapListHandler(app))
+	http.HandleFunc("GET /api/maps/library", app.Market.ListHandler)
+	http.HandleFunc("POST /api/maps/library", app.AuthService.AuthMiddleware(app.AuthService.RequireFullAccount(app.Market.UploadHandler)))
+	http.HandleFunc("GET /api/maps/library/{id}", app.Market.DetailHandler)
+	http.HandleFunc("POST /api/maps/library/{id}/versions", app.AuthService.AuthMiddleware(app.AuthService.RequireFullAccount(app.Market.UpdateHandler)))
+	http.HandleFunc("PUT /api/maps/library/{id}/rating", app.AuthService.AuthMiddleware(app.AuthService.RequireFullAccount(app.Market.RateHandler)))
+	http.HandleFunc("PUT /api/maps/library/{id}/favorite", app.AuthService.AuthMiddleware(app.Market.FavoriteHandler))
+	http.HandleFunc("DELETE /api/maps/library/{id}/favorite", app.AuthService.AuthMiddleware(app.Market.FavoriteHandler))
+	http.HandleFunc("GET /api/maps/favorites", app.AuthService.AuthMiddleware(app.Market.FavoritesHandler))
+	if app.Config.RunsGames() {
+		http.HandleFunc("GET /api/admin/games", adminOnly(app, app.Lobby.ListGamesHandler))
+		http.HandleFunc("DELETE /api/admin/games/{id}", adminOnly(app, app.Lobby.StopGameHandler))
+		http.HandleFunc("DELETE /api/admin/games/{id}/players/{playerId}", adminOnly(app, app.Lobby.KickPlayerHandler))
+	}
+	http.HandleFunc("PUT /api/admin/users/{id}/ban", adminOnly(app, app.AuthService.BanHandler))
+	http.HandleFunc("DELETE /api/admin/users/{id}/ban", adminOnly(app, app.AuthService.BanHandler))
+	http.HandleFunc("PUT /api/admin/users/{id}/mute", adminOnly(app, app.AuthService.MuteHandler))
+	http.HandleFunc("DELETE /api/admin/users/{id}/mute", adminOnly(app, app.AuthService.MuteHandler))
 	http.HandleFunc("/health", healthCheckHandler(app))
+	if app.Config.Metrics.Enabled {
+		http.Handle("GET "+app.Config.Metrics.Path, metricsHandler(app))
+	}
 	http.HandleFunc("/api/cache/stats", app.AuthService.AuthMiddleware(cacheStatsHandler(app)))
 
 	staticDir := app.Config.Server.StaticDir
@@ -98,6 +140,10 @@ func setupHTTPRoutes(app *wire.Application) {
 	}
 }
 
+func adminOnly(app *wire.Application, next http.HandlerFunc) http.HandlerFunc {
+	return app.AuthService.AuthMiddleware(app.AuthService.RequireAdmin(next))
+}
+
 func healthCheckHandler(app *wire.Application) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -111,6 +157,9 @@ func healthCheckHandler(app *wire.Application) http.HandlerFunc {
 			"timestamp": time.Now().Unix(),
 			"cache":     stats,
 		}
+		if reporter, ok := app.Queue.(queue.StatsReporter); ok {
+			health["queue"] = reporter.Stats()
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(health); err != nil {
@@ -120,6 +169,28 @@ func healthCheckHandler(app *wire.Application) http.HandlerFunc {
 	}
 }
 
+func metricsHandler(app *wire.Application) http.Handler {
+	sources := metrics.Sources{Cache: app.Cache.GetCacheStats}
+	if reporter, ok := app.Queue.(queue.StatsReporter); ok {
+		sources.Queue = reporter
+	}
+	if app.Config.RunsGames() {
+		sources.Games = func() []metrics.GameInfo {
+			games := app.Lobby.ListGames()
+			infos := make([]metrics.GameInfo, 0, len(games))
+			for _, game := range games {
+				infos = append(infos, metrics.GameInfo{
+					Status:  string(game.Status),
+					Mode:    game.Mode,
+					Players: len(game.Players),
+				})
+			}
+			return infos
+		}
+	}
+	return metrics.NewHandler(sources)
+}
+
 func cacheStatsHandler(app *wire.Application) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -136,13 +207,43 @@ func cacheStatsHandler(app *wire.Application) http.HandlerFunc {
 	}
 }
 
-func stopServices(app *wire.Application) {
-	if err := app.Lobby.Stop(); err != nil {
-		slog.Error("error stopping lobby service", "error", err)
+func mapListHandler(app *wire.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		maps := app.MapManager.ListMaps()
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(maps); err != nil {
+			slog.Error("failed to encode map list", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// stopServices 按顺序停机：先结束对局让客户端收到最终结果，再拒绝新连接并通知客户端重启、
+// 关闭 HTTP 服务，最后关闭队列。整个过程受 ctx 的期限约束。
+func stopServices(ctx context.Context, app *wire.Application, server *http.Server) {
+	if app.Config.RunsGames() {
+		app.Lobby.Shutdown()
+	}
+
+	if app.Config.ServesWebSocket() {
+		if err := app.WSServer.Shutdown(ctx); err != nil {
+			slog.Error("error stopping websocket server", "error", err)
+		}
 	}
 
-	if err := app.WSServer.StopServer(); err != nil {
-		slog.Error("error stopping websocket server", "error", err)
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("error shutting down HTTP server", "error", err)
+	}
+
+	if closer, ok := app.Queue.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			slog.Error("error closing queue", "error", err)
+		}
 	}
 }
 